@@ -1,37 +1,55 @@
 package aggregator
 
 import (
-	"math"
+	"sync"
 	"time"
 
+	"github.com/fberrez/romantic-aggregator/currency"
+	"github.com/fberrez/romantic-aggregator/metrics"
+	"github.com/shopspring/decimal"
 	"github.com/sirupsen/logrus"
 )
 
+// Number of decimal places of the averages before they are rounded to the tick and lot sizes
+const Precision int32 = 16
+
 // Struct which contains informations about one specific channel & symbols
 type SimpleTicker struct {
 	// Name of the exchange (ex: Bitfinex)
 	Exchange string `json:"exchange"`
 
-	// Symbol of the currency pair (ex: BTCUSD)
+	// Canonical symbol of the currency pair (ex: BTCUSD)
 	Symbol string `json:"symbol"`
 
-	// Last price
-	Price float64 `json:"price"`
+	// Canonical codes of the currency pair (ex: BTC, USDT)
+	Base  string `json:"base"`
+	Quote string `json:"quote"`
+
+	// Symbol used by the exchange (ex: BTC-USD on GDAX, BTCUST on Bitfinex)
+	NativeSymbol string `json:"native_symbol"`
+
+	// Last price, as sent by the exchange
+	Price currency.Decimal `json:"price"`
 
 	// Last bid
-	Bid float64 `json:"bid"`
+	Bid currency.Decimal `json:"bid"`
 
 	// Last ask
-	Ask float64 `json:"ask"`
+	Ask currency.Decimal `json:"ask"`
 
 	// Last volume
-	Volume float64 `json:"volume"`
+	Volume currency.Decimal `json:"volume"`
+}
+
+// Returns the currency pair of the ticker, with its canonical codes
+func (t SimpleTicker) Pair() currency.Pair {
+	return currency.Pair{Base: t.Base, Quote: t.Quote}
 }
 
 // Struct which contains informations about an aggregator
 type Aggregator struct {
-	// Array which contains averages of received tickers
-	tickers []*Ticker
+	// Averages of the received tickers, by interval
+	tickers map[Interval][]*Ticker
 
 	// Channel which receives a ticker to add to the array of tickers
 	AggregatorChannel chan SimpleTicker
@@ -43,61 +61,100 @@ type Aggregator struct {
 	// between the aggregator and the Kafka producer
 	kafkaChannel chan interface{}
 
-	// Timer which determines the interval between
-	// each new messages sent to the Kafka producer
-	timer *time.Ticker
+	// Timer which expires at the end of the next interval,
+	// when new messages are sent to the Kafka producer
+	timer *time.Timer
+
+	// Determines the end of each interval in use
+	scheduler *Scheduler
 
 	// Channel which handles timer updates
 	intervalChannel chan Interval
+
+	// Channel which handles the updates of the intervals of a currency pair
+	pairIntervalsChannel chan pairIntervals
+
+	// Interval of the currency pairs which do not have their own intervals
+	interval Interval
+
+	// Intervals of the currency pairs which do not use the default interval
+	pairIntervals map[currency.Pair][]Interval
+
+	// Last tickers received and last aggregates sent to Kafka
+	Cache *Cache
+
+	// Sends the tickers received and the aggregates to the streaming clients
+	Stream *Broadcaster
+
+	// Wakes the loop up regularly to prove it is not blocked
+	heartbeat *time.Ticker
+
+	// Protects the state below, which is read by the health checks
+	mutex     sync.Mutex
+	lastLoop  time.Time
+	lastFlush time.Time
+}
+
+// Current state of the aggregator loop
+type Status struct {
+	// Interval of the currency pairs which do not have their own intervals (ex: 5m)
+	Interval Interval `json:"interval"`
+
+	// Intervals of the currency pairs which do not use the default interval
+	Pairs map[currency.Pair][]Interval `json:"pairs,omitempty"`
+
+	// Date and time of the last iteration of the loop
+	LastLoop time.Time `json:"last_loop"`
+
+	// Date and time of the last aggregates sent to Kafka
+	LastFlush *time.Time `json:"last_flush,omitempty"`
 }
 
 // Struct which contains the average values ​​of each ticker received
 type Ticker struct {
-	// Symbol of the currency pair (ex: BTCUSD)
+	// Canonical symbol of the currency pair (ex: BTCUSD)
 	Symbol string `json:"symbol"`
 
+	// Canonical codes of the currency pair (ex: BTC, USDT)
+	Base  string `json:"base"`
+	Quote string `json:"quote"`
+
+	// Symbols used by each exchange which sent a ticker (ex: {"Bitfinex": "BTCUST"})
+	NativeSymbols map[string]string `json:"native_symbols"`
+
 	// Average price
-	Price float64 `json:"price"`
+	Price currency.Decimal `json:"price"`
 
 	// High price of the period
-	High float64 `json:"high"`
+	High currency.Decimal `json:"high"`
 
 	// Lowest price of the period
-	Low float64 `json:"low"`
+	Low currency.Decimal `json:"low"`
 
 	// Average Bid
-	Bid float64 `json:"bid"`
+	Bid currency.Decimal `json:"bid"`
 
 	// Average Ask
-	Ask float64 `json:"ask"`
+	Ask currency.Decimal `json:"ask"`
 
 	// Average Volume
-	Volume float64 `json:"volume"`
+	Volume currency.Decimal `json:"volume"`
 
 	// Date and time of the last update
 	LastUpdate time.Time `json:"last_update"`
+
+	// Interval of the aggregation (ex: 5m)
+	Interval Interval `json:"interval"`
 }
 
-// Interval between each messages sent to kafka producer
-type Interval int
-
-// Interval in seconds
-const (
-	OneMinute        Interval = 60
-	ThreeMinutes     Interval = 180
-	FiveMinutes      Interval = 300
-	FifTeenMinutes   Interval = 900
-	ThirtyMinutes    Interval = 1800
-	FortyFiveMinutes Interval = 2700
-	OneHour          Interval = 3600
-	TwoHours         Interval = 7200
-	ThreeHours       Interval = 10800
-	FourHours        Interval = 14400
-	OneDay           Interval = 86400
-	OneWeek          Interval = 604800
-	// One month = 30 days
-	OneMonth Interval = 2592000
-)
+// Intervals of one currency pair, the default interval if empty
+type pairIntervals struct {
+	pair      currency.Pair
+	intervals []Interval
+}
+
+// Period of the heartbeat of the loop
+const HeartbeatPeriod = 5 * time.Second
 
 var (
 	defaultInterval Interval      = OneMinute
@@ -107,21 +164,30 @@ var (
 // Initializes a new aggregator struct
 func Initialize(kafkaChan chan interface{}) *Aggregator {
 	aggregator := &Aggregator{
-		intervalChannel:   make(chan Interval),
-		tickers:           []*Ticker{},
-		AggregatorChannel: make(chan SimpleTicker),
-		interruptChannel:  make(chan bool),
-		kafkaChannel:      kafkaChan,
-		timer:             time.NewTicker(time.Duration(defaultInterval) * time.Second),
+		intervalChannel:      make(chan Interval),
+		pairIntervalsChannel: make(chan pairIntervals),
+		tickers:              map[Interval][]*Ticker{},
+		AggregatorChannel:    make(chan SimpleTicker),
+		interruptChannel:     make(chan bool),
+		kafkaChannel:         kafkaChan,
+		interval:             defaultInterval,
+		pairIntervals:        map[currency.Pair][]Interval{},
+		scheduler:            NewScheduler(),
+		Cache:                NewCache(),
+		Stream:               NewBroadcaster(),
+		heartbeat:            time.NewTicker(HeartbeatPeriod),
+		lastLoop:             time.Now(),
 	}
 
+	aggregator.reschedule(time.Now())
+
 	return aggregator
 }
 
 // Starts the loop which handles each signals:
 // - Receiving a new ticker
 // - A time interval completed
-// - Updating timer
+// - Updating the intervals
 // - Closing/Stopping of the aggregator
 func (a *Aggregator) Start() {
 AggregatorLoop:
@@ -130,19 +196,52 @@ AggregatorLoop:
 		// Ticker received
 		case simpleTicker := <-a.AggregatorChannel:
 			log.WithFields(logrus.Fields{"ticker": simpleTicker}).Debug("Ticker Received")
-			a.makeAverage(simpleTicker)
+			a.Cache.AddTicker(simpleTicker, time.Now())
+			a.Stream.Publish(Record{
+				Type:         TickerRecord,
+				Exchange:     simpleTicker.Exchange,
+				Symbol:       simpleTicker.Symbol,
+				Base:         simpleTicker.Base,
+				Quote:        simpleTicker.Quote,
+				NativeSymbol: simpleTicker.NativeSymbol,
+				Time:         time.Now(),
+				Data:         simpleTicker,
+			})
+			for _, interval := range a.intervalsOf(simpleTicker.Pair()) {
+				a.makeAverage(simpleTicker, interval)
+			}
+
+			metrics.AggregatorQueueDepth.Set(float64(a.pending()))
 
-		// Time interval completed
+		// At least one interval completed
 		case t := <-a.timer.C:
-			for _, ticker := range a.tickers {
-				log.WithField("ticker", *ticker).Infof("Send Ticker to Kafka at %v", t)
-				a.kafkaChannel <- ticker
+			for _, interval := range a.scheduler.Due(t) {
+				a.flush(interval, t)
 			}
-			a.tickers = []*Ticker{}
 
-		// Updates timer
+			a.arm(time.Now())
+			metrics.AggregatorQueueDepth.Set(float64(a.pending()))
+
+		// Updates the default interval
 		case interval := <-a.intervalChannel:
-			a.timer = time.NewTicker(time.Duration(interval) * time.Second)
+			a.mutex.Lock()
+			a.interval = interval
+			a.mutex.Unlock()
+			a.reschedule(time.Now())
+
+		// Updates the intervals of a currency pair
+		case update := <-a.pairIntervalsChannel:
+			a.mutex.Lock()
+			if len(update.intervals) == 0 {
+				delete(a.pairIntervals, update.pair)
+			} else {
+				a.pairIntervals[update.pair] = update.intervals
+			}
+			a.mutex.Unlock()
+			a.reschedule(time.Now())
+
+		// Nothing to do, the loop is alive
+		case <-a.heartbeat.C:
 
 		// SIGINT received
 		case signal := <-a.interruptChannel:
@@ -151,59 +250,232 @@ AggregatorLoop:
 				break AggregatorLoop
 			}
 		}
+
+		a.beat()
 	}
 }
 
-// Modifies the timer interval
+// Returns the current state of the aggregator loop
+func (a *Aggregator) Status() Status {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	status := Status{
+		Interval: a.interval,
+		LastLoop: a.lastLoop,
+		Pairs:    map[currency.Pair][]Interval{},
+	}
+
+	for pair, intervals := range a.pairIntervals {
+		status.Pairs[pair] = append([]Interval{}, intervals...)
+	}
+
+	if !a.lastFlush.IsZero() {
+		lastFlush := a.lastFlush
+		status.LastFlush = &lastFlush
+	}
+
+	return status
+}
+
+// Records an iteration of the loop
+func (a *Aggregator) beat() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.lastLoop = time.Now()
+}
+
+// Records the aggregates sent to Kafka
+func (a *Aggregator) flushed(t time.Time) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.lastFlush = t
+}
+
+// Modifies the default interval, used by the currency pairs
+// which do not have their own intervals
 func (a *Aggregator) SetInterval(interval Interval) {
-	log.WithField("interval", interval).Infof("The interval of the ticker has been changed (in sec)")
+	log.WithField("interval", interval.String()).Infof("The interval of the ticker has been changed")
 	a.intervalChannel <- interval
 }
 
-// Calculates the average of a ticker
-func (a *Aggregator) makeAverage(t SimpleTicker) {
-	currentTicker := a.findTicker(t)
+// Modifies the intervals of a currency pair (ex: BTC-USD).
+// The currency pair uses the default interval if `intervals` is empty.
+func (a *Aggregator) SetPairIntervals(pair currency.Pair, intervals []Interval) {
+	log.WithFields(logrus.Fields{"pair": pair.String(), "intervals": intervals}).Infof("The intervals of the currency pair have been changed")
+	a.pairIntervalsChannel <- pairIntervals{pair: pair, intervals: intervals}
+}
+
+// Returns the intervals of a currency pair
+func (a *Aggregator) intervalsOf(pair currency.Pair) []Interval {
+	if intervals, ok := a.pairIntervals[pair]; ok {
+		return intervals
+	}
+
+	return []Interval{a.interval}
+}
+
+// Schedules the intervals in use and forgets the averages
+// of the intervals which are not used anymore
+func (a *Aggregator) reschedule(now time.Time) {
+	inUse := map[Interval]bool{a.interval: true}
+	intervals := []Interval{a.interval}
+
+	for _, pairIntervals := range a.pairIntervals {
+		for _, interval := range pairIntervals {
+			if !inUse[interval] {
+				inUse[interval] = true
+				intervals = append(intervals, interval)
+			}
+		}
+	}
+
+	for interval, tickers := range a.tickers {
+		kept := []*Ticker{}
+
+		for _, ticker := range tickers {
+			if inUse[interval] && containsInterval(a.intervalsOf(ticker.Pair()), interval) {
+				kept = append(kept, ticker)
+			}
+		}
+
+		a.tickers[interval] = kept
+	}
+
+	a.scheduler.Set(intervals, now)
+	a.arm(now)
+}
+
+// Arms the timer to expire at the end of the next interval
+func (a *Aggregator) arm(now time.Time) {
+	if a.timer != nil {
+		a.timer.Stop()
+	}
 
-	currentTicker.Price = (currentTicker.Price*currentTicker.Volume + t.Price*t.Volume) / (currentTicker.Volume + t.Volume)
-	currentTicker.Bid = (currentTicker.Bid*currentTicker.Volume + t.Bid*t.Volume) / (currentTicker.Volume + t.Volume)
-	currentTicker.Ask = (currentTicker.Ask*currentTicker.Volume + t.Ask*t.Volume) / (currentTicker.Volume + t.Volume)
-	currentTicker.Volume = (currentTicker.Volume + t.Volume) / 2
+	next, _ := a.scheduler.Next()
+	a.timer = time.NewTimer(next.Sub(now))
+}
+
+// Sends the averages of the completed interval to Kafka
+func (a *Aggregator) flush(interval Interval, t time.Time) {
+	start := time.Now()
+	tickers := a.tickers[interval]
+	metrics.TickersEmitted.WithLabelValues(interval.String()).Observe(float64(len(tickers)))
+
+	for _, ticker := range tickers {
+		ticker.round()
+		log.WithField("ticker", *ticker).Infof("Send Ticker to Kafka at %v", t)
+		a.Cache.AddAggregate(*ticker, interval, t)
+		a.Stream.Publish(Record{
+			Type:     AggregateRecord,
+			Symbol:   ticker.Symbol,
+			Base:     ticker.Base,
+			Quote:    ticker.Quote,
+			Interval: &ticker.Interval,
+			Time:     t,
+			Data:     *ticker,
+		})
+		a.kafkaChannel <- ticker
+	}
+
+	delete(a.tickers, interval)
+	a.flushed(t)
+	metrics.FlushDuration.Observe(time.Since(start).Seconds())
+}
+
+// Returns the number of averages waiting for the end of their interval
+func (a *Aggregator) pending() int {
+	count := 0
+
+	for _, tickers := range a.tickers {
+		count += len(tickers)
+	}
+
+	return count
+}
+
+// Calculates the average of a ticker over the interval
+func (a *Aggregator) makeAverage(t SimpleTicker, interval Interval) {
+	currentTicker := a.findTicker(t, interval)
+
+	currentTicker.Price = weightedAverage(currentTicker.Price, currentTicker.Volume, t.Price, t.Volume)
+	currentTicker.Bid = weightedAverage(currentTicker.Bid, currentTicker.Volume, t.Bid, t.Volume)
+	currentTicker.Ask = weightedAverage(currentTicker.Ask, currentTicker.Volume, t.Ask, t.Volume)
+	currentTicker.Volume = currency.NewDecimal(currentTicker.Volume.Add(t.Volume.Decimal).DivRound(decimal.NewFromInt(2), Precision))
 	currentTicker.LastUpdate = time.Now()
+	currentTicker.NativeSymbols[t.Exchange] = t.NativeSymbol
 
-	if t.Price > currentTicker.High {
+	if t.Price.GreaterThan(currentTicker.High.Decimal) {
 		currentTicker.High = t.Price
 	}
 
-	if t.Price < currentTicker.Low {
+	if t.Price.LessThan(currentTicker.Low.Decimal) {
 		currentTicker.Low = t.Price
 	}
 
 	log.WithFields(logrus.Fields{"ticker": currentTicker}).Debug("Ticker Calculated")
 }
 
-// Finds or creates a new ticker to return
-func (a *Aggregator) findTicker(t SimpleTicker) *Ticker {
-	for _, ticker := range a.tickers {
-		if ticker.Symbol == t.Symbol {
+// Finds or creates a new ticker of the interval to return.
+// The codes are compared as well, since a symbol may be read in several ways (ex: BTCUSDT).
+func (a *Aggregator) findTicker(t SimpleTicker, interval Interval) *Ticker {
+	for _, ticker := range a.tickers[interval] {
+		if ticker.Symbol == t.Symbol && ticker.Base == t.Base && ticker.Quote == t.Quote {
 			return ticker
 		}
 	}
 
 	newTicker := &Ticker{
-		Symbol: t.Symbol,
-		Price:  t.Price,
-		High:   0,
-		Low:    math.MaxFloat64,
-		Bid:    t.Bid,
-		Ask:    t.Ask,
-		Volume: t.Volume,
+		Symbol:        t.Symbol,
+		Base:          t.Base,
+		Quote:         t.Quote,
+		NativeSymbols: map[string]string{},
+		Price:         t.Price,
+		High:          t.Price,
+		Low:           t.Price,
+		Bid:           t.Bid,
+		Ask:           t.Ask,
+		Volume:        t.Volume,
+		Interval:      interval,
 	}
 
-	a.tickers = append(a.tickers, newTicker)
+	a.tickers[interval] = append(a.tickers[interval], newTicker)
 
 	return newTicker
 }
 
+// Returns the average of two values weighted by their volumes,
+// the last value if both volumes are 0
+func weightedAverage(value currency.Decimal, volume currency.Decimal, last currency.Decimal, lastVolume currency.Decimal) currency.Decimal {
+	total := volume.Add(lastVolume.Decimal)
+
+	if total.IsZero() {
+		return last
+	}
+
+	return currency.NewDecimal(value.Mul(volume.Decimal).Add(last.Mul(lastVolume.Decimal)).DivRound(total, Precision))
+}
+
+// Returns the currency pair of the aggregate, with its canonical codes
+func (t *Ticker) Pair() currency.Pair {
+	return currency.Pair{Base: t.Base, Quote: t.Quote}
+}
+
+// Rounds the prices to the finest tick size of the markets of the currency pair,
+// and the volume to their finest lot size
+func (t *Ticker) round() {
+	tick, lot := currency.Catalog.Increments(t.Pair())
+
+	t.Price = t.Price.RoundTo(tick)
+	t.High = t.High.RoundTo(tick)
+	t.Low = t.Low.RoundTo(tick)
+	t.Bid = t.Bid.RoundTo(tick)
+	t.Ask = t.Ask.RoundTo(tick)
+	t.Volume = t.Volume.RoundTo(lot)
+}
+
 // Stops the aggregator loop
 func (a *Aggregator) Stop() {
 	a.interruptChannel <- true
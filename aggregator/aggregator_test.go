@@ -0,0 +1,85 @@
+package aggregator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fberrez/romantic-aggregator/currency"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSymbolIntervals(t *testing.T) {
+	kafka := make(chan interface{}, 16)
+	aggregator := Initialize(kafka)
+	go aggregator.Start()
+	defer aggregator.Stop()
+
+	fast := Interval{Duration: 20 * time.Millisecond}
+	btcusd := currency.Pair{Base: "BTC", Quote: "USD"}
+	aggregator.SetPairIntervals(btcusd, []Interval{fast})
+
+	aggregator.AggregatorChannel <- SimpleTicker{Exchange: "GDAX", Symbol: "BTCUSD", Base: "BTC", Quote: "USD", NativeSymbol: "BTC-USD", Price: currency.NewDecimal(decimal.NewFromInt(10)), Volume: currency.NewDecimal(decimal.NewFromInt(1))}
+	aggregator.AggregatorChannel <- SimpleTicker{Exchange: "GDAX", Symbol: "ETHEUR", Base: "ETH", Quote: "EUR", Price: currency.NewDecimal(decimal.NewFromInt(20)), Volume: currency.NewDecimal(decimal.NewFromInt(1))}
+
+	// Only BTCUSD is flushed, ETHEUR waits for the default interval
+	select {
+	case message := <-kafka:
+		ticker := message.(*Ticker)
+		assert.Equal(t, "BTCUSD", ticker.Symbol)
+		assert.Equal(t, fast, ticker.Interval)
+		assert.Equal(t, "BTC", ticker.Base)
+		assert.Equal(t, "USD", ticker.Quote)
+		assert.Equal(t, map[string]string{"GDAX": "BTC-USD"}, ticker.NativeSymbols)
+	case <-time.After(time.Second):
+		t.Fatal("BTCUSD has not been flushed")
+	}
+
+	select {
+	case message := <-kafka:
+		t.Fatalf("unexpected message %v", message)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	assert.Equal(t, map[currency.Pair][]Interval{btcusd: {fast}}, aggregator.Status().Pairs)
+
+	aggregator.SetPairIntervals(btcusd, nil)
+	assert.Eventually(t, func() bool { return len(aggregator.Status().Pairs) == 0 }, time.Second, time.Millisecond)
+}
+
+func TestMakeAverage(t *testing.T) {
+	catalog := currency.Catalog
+	defer func() { currency.Catalog = catalog }()
+
+	currency.Catalog = currency.NewRegistry()
+	currency.Catalog.Set("GDAX", []currency.Market{{Pair: currency.BTCUSD, TickSize: currency.NewDecimal(decimal.RequireFromString("0.01")), LotSize: currency.NewDecimal(decimal.RequireFromString("0.001")), Status: currency.Online}})
+	currency.Catalog.Set("Bitfinex", []currency.Market{{Pair: currency.BTCUSD, TickSize: currency.NewDecimal(decimal.RequireFromString("0.1")), Status: currency.Online}})
+
+	aggregator := Initialize(make(chan interface{}, 1))
+	interval := Interval{Duration: time.Minute}
+
+	tickers := []SimpleTicker{
+		{Exchange: "GDAX", Symbol: "BTCUSD", Base: "BTC", Quote: "USD", Price: currency.NewDecimal(decimal.RequireFromString("0.1")), Bid: currency.NewDecimal(decimal.RequireFromString("0.1")), Ask: currency.NewDecimal(decimal.RequireFromString("0.2")), Volume: currency.NewDecimal(decimal.NewFromInt(1))},
+		{Exchange: "GDAX", Symbol: "BTCUSD", Base: "BTC", Quote: "USD", Price: currency.NewDecimal(decimal.RequireFromString("0.2")), Bid: currency.NewDecimal(decimal.RequireFromString("0.2")), Ask: currency.NewDecimal(decimal.RequireFromString("0.3")), Volume: currency.NewDecimal(decimal.NewFromInt(1))},
+		{Exchange: "Bitfinex", Symbol: "BTCUSD", Base: "BTC", Quote: "USD", Price: currency.NewDecimal(decimal.RequireFromString("0.3")), Bid: currency.NewDecimal(decimal.RequireFromString("0.3")), Ask: currency.NewDecimal(decimal.RequireFromString("0.3")), Volume: currency.NewDecimal(decimal.NewFromInt(2))},
+	}
+
+	for _, ticker := range tickers {
+		aggregator.makeAverage(ticker, interval)
+	}
+
+	ticker := aggregator.tickers[interval][0]
+
+	// The values are exact ((0.1 + 0.2) / 2 is 0.15) until they are rounded
+	assert.Equal(t, "0.25", ticker.Price.String())
+	assert.Equal(t, "0.2833333333333333", ticker.Ask.String())
+	assert.Equal(t, "0.1", ticker.Low.String())
+	assert.Equal(t, "0.3", ticker.High.String())
+	assert.Equal(t, "1.5", ticker.Volume.String())
+
+	// To the finest tick and lot sizes of the markets
+	ticker.round()
+	assert.Equal(t, "0.25", ticker.Price.String())
+	assert.Equal(t, "0.28", ticker.Ask.String())
+	assert.Equal(t, "1.5", ticker.Volume.String())
+}
@@ -0,0 +1,135 @@
+package aggregator
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fberrez/romantic-aggregator/currency"
+)
+
+// Cache keeps the last ticker received from each exchange for each currency pair,
+// and the last aggregate sent to Kafka for each currency pair and interval
+type Cache struct {
+	mutex sync.RWMutex
+
+	tickers    map[tickerKey]CachedTicker
+	aggregates map[aggregateKey]CachedAggregate
+}
+
+// Last ticker received from an exchange
+type CachedTicker struct {
+	SimpleTicker
+
+	// Date and time of the reception
+	Received time.Time `json:"received"`
+
+	// Seconds elapsed since the reception, computed when it is read
+	Age float64 `json:"age"`
+}
+
+// Last aggregate sent to Kafka
+type CachedAggregate struct {
+	Ticker
+
+	// Interval of the aggregation (ex: 5m)
+	Interval Interval `json:"interval"`
+
+	// Date and time of the flush
+	Flushed time.Time `json:"flushed"`
+
+	// Seconds elapsed since the flush, computed when it is read
+	Age float64 `json:"age"`
+}
+
+type tickerKey struct {
+	exchange string
+	pair     currency.Pair
+}
+
+type aggregateKey struct {
+	pair     currency.Pair
+	interval Interval
+}
+
+// Initializes an empty cache
+func NewCache() *Cache {
+	return &Cache{
+		tickers:    map[tickerKey]CachedTicker{},
+		aggregates: map[aggregateKey]CachedAggregate{},
+	}
+}
+
+// Replaces the last ticker of the exchange and currency pair
+func (c *Cache) AddTicker(t SimpleTicker, received time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.tickers[tickerKey{t.Exchange, t.Pair()}] = CachedTicker{SimpleTicker: t, Received: received}
+}
+
+// Replaces the last aggregate of the currency pair and interval
+func (c *Cache) AddAggregate(t Ticker, interval Interval, flushed time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.aggregates[aggregateKey{t.Pair(), interval}] = CachedAggregate{Ticker: t, Interval: interval, Flushed: flushed}
+}
+
+// Returns the last tickers matching the exchange and the currency pair,
+// sorted by currency pair and exchange. Empty values match anything.
+func (c *Cache) Tickers(exchange string, pair currency.Pair) []CachedTicker {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	now := time.Now()
+	tickers := []CachedTicker{}
+
+	for key, ticker := range c.tickers {
+		if (exchange != "" && key.exchange != exchange) || (!pair.IsZero() && key.pair != pair) {
+			continue
+		}
+
+		ticker.Age = now.Sub(ticker.Received).Seconds()
+		tickers = append(tickers, ticker)
+	}
+
+	sort.Slice(tickers, func(i, j int) bool {
+		if tickers[i].Pair() != tickers[j].Pair() {
+			return tickers[i].Pair().String() < tickers[j].Pair().String()
+		}
+
+		return tickers[i].Exchange < tickers[j].Exchange
+	})
+
+	return tickers
+}
+
+// Returns the last aggregates of the currency pair, or of every currency pair
+// if it is empty, sorted by currency pair and interval
+func (c *Cache) Aggregates(pair currency.Pair) []CachedAggregate {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	now := time.Now()
+	aggregates := []CachedAggregate{}
+
+	for key, aggregate := range c.aggregates {
+		if !pair.IsZero() && key.pair != pair {
+			continue
+		}
+
+		aggregate.Age = now.Sub(aggregate.Flushed).Seconds()
+		aggregates = append(aggregates, aggregate)
+	}
+
+	sort.Slice(aggregates, func(i, j int) bool {
+		if aggregates[i].Pair() != aggregates[j].Pair() {
+			return aggregates[i].Pair().String() < aggregates[j].Pair().String()
+		}
+
+		return aggregates[i].Interval.Approx() < aggregates[j].Interval.Approx()
+	})
+
+	return aggregates
+}
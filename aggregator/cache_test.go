@@ -0,0 +1,57 @@
+package aggregator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fberrez/romantic-aggregator/currency"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache(t *testing.T) {
+	cache := NewCache()
+	now := time.Now()
+
+	btcusd := currency.Pair{Base: "BTC", Quote: "USD"}
+	etheur := currency.Pair{Base: "ETH", Quote: "EUR"}
+
+	cache.AddTicker(SimpleTicker{Exchange: "GDAX", Symbol: "BTCUSD", Base: "BTC", Quote: "USD", Price: currency.NewDecimal(decimal.NewFromInt(1))}, now.Add(-time.Minute))
+	cache.AddTicker(SimpleTicker{Exchange: "GDAX", Symbol: "BTCUSD", Base: "BTC", Quote: "USD", Price: currency.NewDecimal(decimal.NewFromInt(2))}, now)
+	cache.AddTicker(SimpleTicker{Exchange: "Bitfinex", Symbol: "BTCUSD", Base: "BTC", Quote: "USD", Price: currency.NewDecimal(decimal.NewFromInt(3))}, now)
+	cache.AddTicker(SimpleTicker{Exchange: "GDAX", Symbol: "ETHEUR", Base: "ETH", Quote: "EUR", Price: currency.NewDecimal(decimal.NewFromInt(4))}, now)
+
+	// Same concatenation as BTC-USD, but another currency pair
+	cache.AddTicker(SimpleTicker{Exchange: "GDAX", Symbol: "BTCUSD", Base: "BTCU", Quote: "SD", Price: currency.NewDecimal(decimal.NewFromInt(7))}, now)
+
+	cache.AddAggregate(Ticker{Symbol: "BTCUSD", Base: "BTC", Quote: "USD", Price: currency.NewDecimal(decimal.NewFromInt(5))}, OneMinute, now.Add(-time.Minute))
+	cache.AddAggregate(Ticker{Symbol: "BTCUSD", Base: "BTC", Quote: "USD", Price: currency.NewDecimal(decimal.NewFromInt(6))}, FiveMinutes, now)
+
+	tables := []struct {
+		exchange string
+		pair     currency.Pair
+		prices   []int64
+	}{
+		{"", currency.Pair{}, []int64{3, 2, 7, 4}},
+		{"GDAX", currency.Pair{}, []int64{2, 7, 4}},
+		{"", btcusd, []int64{3, 2}},
+		{"Bitfinex", etheur, []int64{}},
+	}
+
+	for _, table := range tables {
+		prices := []int64{}
+
+		for _, ticker := range cache.Tickers(table.exchange, table.pair) {
+			prices = append(prices, ticker.Price.IntPart())
+		}
+
+		assert.Equal(t, table.prices, prices)
+	}
+
+	aggregates := cache.Aggregates(btcusd)
+	assert.Equal(t, 2, len(aggregates))
+	assert.Equal(t, OneMinute, aggregates[0].Interval)
+	assert.InDelta(t, 60, aggregates[0].Age, 1)
+	assert.Equal(t, "6", aggregates[1].Price.String())
+	assert.Equal(t, 0, len(cache.Aggregates(etheur)))
+}
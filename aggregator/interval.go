@@ -0,0 +1,325 @@
+package aggregator
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+// Interval between each messages sent to kafka producer.
+// It is made of a number of calendar months and of a fixed duration,
+// because the duration of a month depends on the date.
+type Interval struct {
+	// Number of calendar months
+	Months int
+
+	// Fixed part of the interval
+	Duration time.Duration
+}
+
+const (
+	day  time.Duration = 24 * time.Hour
+	week time.Duration = 7 * day
+
+	// Average duration of a month in the Gregorian calendar,
+	// only used to compare the intervals
+	averageMonth time.Duration = 2629746 * time.Second
+
+	// Number of months whose duration can be written as a time.Duration
+	maxMonths int = int(math.MaxInt64 / averageMonth)
+)
+
+// Common intervals
+var (
+	OneMinute        Interval = Interval{Duration: time.Minute}
+	ThreeMinutes     Interval = Interval{Duration: 3 * time.Minute}
+	FiveMinutes      Interval = Interval{Duration: 5 * time.Minute}
+	FifTeenMinutes   Interval = Interval{Duration: 15 * time.Minute}
+	ThirtyMinutes    Interval = Interval{Duration: 30 * time.Minute}
+	FortyFiveMinutes Interval = Interval{Duration: 45 * time.Minute}
+	OneHour          Interval = Interval{Duration: time.Hour}
+	TwoHours         Interval = Interval{Duration: 2 * time.Hour}
+	ThreeHours       Interval = Interval{Duration: 3 * time.Hour}
+	FourHours        Interval = Interval{Duration: 4 * time.Hour}
+	OneDay           Interval = Interval{Duration: day}
+	OneWeek          Interval = Interval{Duration: week}
+	OneMonth         Interval = Interval{Months: 1}
+)
+
+// Bounds of the intervals accepted by ParseInterval
+var (
+	DefaultMinInterval Interval = Interval{Duration: time.Second}
+	DefaultMaxInterval Interval = Interval{Months: 12}
+
+	MinInterval Interval = DefaultMinInterval
+	MaxInterval Interval = DefaultMaxInterval
+
+	// Protects the bounds, which can be replaced while the API is running
+	boundsMutex sync.RWMutex
+)
+
+// Units of the fixed part of an interval.
+// The uppercase units are the ones used by the exchanges (ex: 1H, 1D).
+var units = map[string]time.Duration{
+	"ns": time.Nanosecond,
+	"us": time.Microsecond,
+	"µs": time.Microsecond,
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+	"H":  time.Hour,
+	"d":  day,
+	"D":  day,
+	"w":  week,
+	"W":  week,
+}
+
+// Unit of the calendar months
+const monthUnit = "M"
+
+// Parses an interval made of numbers followed by a unit
+// (ex: 10s, 90s, 2h30m, 1d, 1w, 1M, 1M15d) and checks
+// that it is between MinInterval and MaxInterval.
+// "m" is a minute and "M" a calendar month.
+func ParseInterval(value string) (Interval, error) {
+	interval, err := parseInterval(value)
+
+	if err != nil {
+		return Interval{}, err
+	}
+
+	min, max := IntervalBounds()
+
+	if interval.Approx() < min.Approx() {
+		return Interval{}, errors.NotValidf("interval %s shorter than %s", value, min)
+	}
+
+	if interval.Approx() > max.Approx() {
+		return Interval{}, errors.NotValidf("interval %s longer than %s", value, max)
+	}
+
+	return interval, nil
+}
+
+// Replaces the bounds of the intervals accepted by ParseInterval.
+// An empty value keeps the current bound.
+func SetIntervalBounds(min string, max string) error {
+	minInterval, maxInterval, err := ParseIntervalBounds(min, max)
+
+	if err != nil {
+		return err
+	}
+
+	boundsMutex.Lock()
+	defer boundsMutex.Unlock()
+
+	MinInterval, MaxInterval = minInterval, maxInterval
+
+	return nil
+}
+
+// Returns the bounds of the intervals accepted by ParseInterval
+func IntervalBounds() (Interval, Interval) {
+	boundsMutex.RLock()
+	defer boundsMutex.RUnlock()
+
+	return MinInterval, MaxInterval
+}
+
+// Parses the bounds of the intervals without replacing the current ones.
+// An empty value is replaced by the current bound.
+func ParseIntervalBounds(min string, max string) (Interval, Interval, error) {
+	minInterval, maxInterval := IntervalBounds()
+	bounds := []Interval{minInterval, maxInterval}
+
+	for i, value := range []string{min, max} {
+		if value == "" {
+			continue
+		}
+
+		interval, err := parseInterval(value)
+
+		if err != nil {
+			return Interval{}, Interval{}, err
+		}
+
+		bounds[i] = interval
+	}
+
+	if bounds[0].Approx() > bounds[1].Approx() {
+		return Interval{}, Interval{}, errors.NotValidf("interval bounds %s greater than %s", bounds[0], bounds[1])
+	}
+
+	return bounds[0], bounds[1], nil
+}
+
+// Parses an interval without checking its bounds
+func parseInterval(value string) (Interval, error) {
+	interval := Interval{}
+	remaining := strings.TrimSpace(value)
+
+	if remaining == "" {
+		return interval, errors.NotValidf("empty interval")
+	}
+
+	for remaining != "" {
+		// Number, with an optional fraction
+		i := strings.IndexFunc(remaining, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+
+		if i <= 0 {
+			return Interval{}, errors.NotValidf("interval %s", value)
+		}
+
+		number := remaining[:i]
+		remaining = remaining[i:]
+
+		// Unit
+		j := strings.IndexFunc(remaining, func(r rune) bool { return r >= '0' && r <= '9' })
+
+		if j < 0 {
+			j = len(remaining)
+		}
+
+		unit := remaining[:j]
+		remaining = remaining[j:]
+
+		if unit == monthUnit {
+			months, err := strconv.Atoi(number)
+
+			if err != nil {
+				return Interval{}, errors.NotValidf("interval %s: months must be a whole number", value)
+			}
+
+			if months > maxMonths-interval.Months {
+				return Interval{}, errors.NotValidf("interval %s: too many months", value)
+			}
+
+			interval.Months += months
+			continue
+		}
+
+		duration, ok := units[unit]
+
+		if !ok {
+			return Interval{}, errors.NotValidf("interval %s: unknown unit %q", value, unit)
+		}
+
+		n, err := strconv.ParseFloat(number, 64)
+
+		if err != nil {
+			return Interval{}, errors.NotValidf("interval %s", value)
+		}
+
+		// Beyond this limit, the conversion and the sum would wrap around
+		if n*float64(duration) >= float64(math.MaxInt64-interval.Duration) {
+			return Interval{}, errors.NotValidf("interval %s: too long", value)
+		}
+
+		interval.Duration += time.Duration(n * float64(duration))
+	}
+
+	if interval.Approx() <= 0 {
+		return Interval{}, errors.NotValidf("interval %s", value)
+	}
+
+	return interval, nil
+}
+
+// Returns the duration of the interval, a month being 1/12 of the average year.
+// Only meant to compare the intervals: it is capped to the longest duration
+// instead of wrapping around.
+func (i Interval) Approx() time.Duration {
+	if i.Months > maxMonths {
+		return math.MaxInt64
+	}
+
+	months := time.Duration(i.Months) * averageMonth
+
+	if i.Duration > math.MaxInt64-months {
+		return math.MaxInt64
+	}
+
+	return months + i.Duration
+}
+
+// Returns the end of the n-th interval which started at `start`.
+// The months are added first, the day being clamped to the last day
+// of the month (ex: Jan 31 + 1 month = Feb 28), then the fixed duration.
+// Counting from `start` prevents the clamped days from drifting.
+func (i Interval) After(start time.Time, n int) time.Time {
+	return addMonths(start, i.Months*n).Add(time.Duration(n) * i.Duration)
+}
+
+// Adds calendar months to a date, clamping the day to the end of the month
+func addMonths(t time.Time, months int) time.Time {
+	if months == 0 {
+		return t
+	}
+
+	year, month, d := t.Date()
+	hour, min, sec := t.Clock()
+
+	// The day 0 of the following month is the last day of the month
+	last := time.Date(year, month+time.Month(months)+1, 0, 0, 0, 0, 0, t.Location()).Day()
+
+	if d > last {
+		d = last
+	}
+
+	return time.Date(year, month+time.Month(months), d, hour, min, sec, t.Nanosecond(), t.Location())
+}
+
+// Returns the interval in the format accepted by ParseInterval (ex: 1M, 1d12h, 1m30s)
+func (i Interval) String() string {
+	if i.Months == 0 && i.Duration == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+
+	if i.Months != 0 {
+		fmt.Fprintf(&b, "%d%s", i.Months, monthUnit)
+	}
+
+	remaining := i.Duration
+
+	for _, unit := range []struct {
+		name     string
+		duration time.Duration
+	}{{"w", week}, {"d", day}, {"h", time.Hour}, {"m", time.Minute}} {
+		if n := remaining / unit.duration; n > 0 {
+			fmt.Fprintf(&b, "%d%s", n, unit.name)
+			remaining -= n * unit.duration
+		}
+	}
+
+	if remaining > 0 {
+		// Seconds and fractions of a second (ex: 1.5s, 500ms)
+		b.WriteString(remaining.String())
+	}
+
+	return b.String()
+}
+
+// Writes the interval as a string (ex: "5m") in JSON
+func (i Interval) MarshalText() ([]byte, error) {
+	return []byte(i.String()), nil
+}
+
+// Reads an interval written as a string (ex: "5m")
+func (i *Interval) UnmarshalText(text []byte) error {
+	interval, err := parseInterval(string(text))
+
+	if err != nil {
+		return err
+	}
+
+	*i = interval
+	return nil
+}
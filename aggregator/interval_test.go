@@ -0,0 +1,143 @@
+package aggregator
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseInterval(t *testing.T) {
+	tables := []struct {
+		value  string
+		result Interval
+		text   string
+		err    string
+	}{
+		{"10s", Interval{Duration: 10 * time.Second}, "10s", ""},
+		{"90s", Interval{Duration: 90 * time.Second}, "1m30s", ""},
+		{"2h30m", Interval{Duration: 150 * time.Minute}, "2h30m", ""},
+		{"1.5h", Interval{Duration: 90 * time.Minute}, "1h30m", ""},
+		{"1d", OneDay, "1d", ""},
+		{"36h", Interval{Duration: 36 * time.Hour}, "1d12h", ""},
+		{"1w", OneWeek, "1w", ""},
+		{"1M", OneMonth, "1M", ""},
+		{"1M15d", Interval{Months: 1, Duration: 15 * day}, "1M2w1d", ""},
+		// Legacy values
+		{"1m", OneMinute, "1m", ""},
+		{"1H", OneHour, "1h", ""},
+		{"1D", OneDay, "1d", ""},
+		{"1W", OneWeek, "1w", ""},
+		{"", Interval{}, "", "notValid"},
+		{"5", Interval{}, "", "notValid"},
+		{"m", Interval{}, "", "notValid"},
+		{"5y", Interval{}, "", "notValid"},
+		{"1.5M", Interval{}, "", "notValid"},
+		{"0s", Interval{}, "", "notValid"},
+		// Out of bounds
+		{"500ms", Interval{}, "", "notValid"},
+		{"13M", Interval{}, "", "notValid"},
+		{"12M", Interval{Months: 12}, "12M", ""},
+		// Overflows
+		{"7015M", Interval{}, "", "notValid"},
+		{"3000M3000M", Interval{}, "", "notValid"},
+		{"3000000h", Interval{}, "", "notValid"},
+		{"2000000h2000000h", Interval{}, "", "notValid"},
+	}
+
+	for _, table := range tables {
+		result, err := ParseInterval(table.value)
+
+		assert.Equal(t, table.result, result, table.value)
+		assert.Equal(t, table.text, result.String(), table.value)
+
+		switch table.err {
+		case "notValid":
+			assert.True(t, errors.IsNotValid(err), table.value)
+		case "":
+			assert.Nil(t, err, table.value)
+		}
+	}
+}
+
+func TestSetIntervalBounds(t *testing.T) {
+	defer func(min Interval, max Interval) { MinInterval, MaxInterval = min, max }(MinInterval, MaxInterval)
+
+	assert.True(t, errors.IsNotValid(SetIntervalBounds("1d", "1h")))
+	assert.True(t, errors.IsNotValid(SetIntervalBounds("1x", "")))
+
+	assert.Nil(t, SetIntervalBounds("100ms", ""))
+	assert.Equal(t, Interval{Duration: 100 * time.Millisecond}, MinInterval)
+	assert.Equal(t, Interval{Months: 12}, MaxInterval)
+
+	_, err := ParseInterval("500ms")
+	assert.Nil(t, err)
+}
+
+func TestIntervalAfter(t *testing.T) {
+	start := time.Date(2024, time.January, 31, 10, 0, 0, 0, time.UTC)
+
+	tables := []struct {
+		interval Interval
+		n        int
+		result   time.Time
+	}{
+		{OneMinute, 3, start.Add(3 * time.Minute)},
+		// The day is clamped to the end of the month without drifting
+		{OneMonth, 1, time.Date(2024, time.February, 29, 10, 0, 0, 0, time.UTC)},
+		{OneMonth, 2, time.Date(2024, time.March, 31, 10, 0, 0, 0, time.UTC)},
+		{OneMonth, 3, time.Date(2024, time.April, 30, 10, 0, 0, 0, time.UTC)},
+		{OneMonth, 13, time.Date(2025, time.February, 28, 10, 0, 0, 0, time.UTC)},
+		{Interval{Months: 1, Duration: day}, 1, time.Date(2024, time.March, 1, 10, 0, 0, 0, time.UTC)},
+	}
+
+	for _, table := range tables {
+		assert.Equal(t, table.result, table.interval.After(start, table.n))
+	}
+}
+
+func TestIntervalJSON(t *testing.T) {
+	b, err := json.Marshal(CachedAggregate{Interval: Interval{Months: 1}})
+	assert.Nil(t, err)
+	assert.Contains(t, string(b), `"interval":"1M"`)
+
+	aggregate := CachedAggregate{}
+	assert.Nil(t, json.Unmarshal(b, &aggregate))
+	assert.Equal(t, OneMonth, aggregate.Interval)
+}
+
+func TestIntervalOverflow(t *testing.T) {
+	tables := []struct {
+		value string
+		err   bool
+	}{
+		{"3507M", false},
+		{"3508M", true},
+		{"7015M", true},
+		{"2000M2000M", true},
+		{"2562047h", false},
+		{"2562048h", true},
+		{"1000000000000000000000000h", true},
+		{"2000000h2000000h", true},
+	}
+
+	for _, table := range tables {
+		interval, err := parseInterval(table.value)
+
+		if table.err {
+			assert.True(t, errors.IsNotValid(err), "%s: %v", table.value, err)
+			continue
+		}
+
+		assert.Nil(t, err, table.value)
+		assert.True(t, interval.Approx() > 0, table.value)
+	}
+
+	// The approximation does not wrap around
+	assert.Equal(t, time.Duration(math.MaxInt64), Interval{Months: 7015}.Approx())
+	assert.Equal(t, time.Duration(math.MaxInt64), Interval{Months: 3507, Duration: 100 * 365 * day}.Approx())
+	assert.True(t, Interval{Months: 13}.Approx() > Interval{Months: 12}.Approx())
+}
@@ -0,0 +1,115 @@
+package aggregator
+
+import (
+	"container/heap"
+	"time"
+)
+
+// Scheduler determines when each interval ends.
+// The intervals are kept in a min-heap ordered by the end of their current period,
+// so a single timer is enough whatever the number of intervals.
+type Scheduler struct {
+	schedules scheduleHeap
+}
+
+// Periods of one interval
+type schedule struct {
+	interval Interval
+
+	// Start of the first period and number of periods completed since then.
+	// The end of each period is computed from `start`, see Interval.After
+	start   time.Time
+	periods int
+
+	// End of the current period
+	next time.Time
+}
+
+// Initializes an empty scheduler
+func NewScheduler() *Scheduler {
+	return &Scheduler{schedules: scheduleHeap{}}
+}
+
+// Replaces the scheduled intervals. The intervals which were already scheduled
+// keep their periods, the new ones start at `now`.
+func (s *Scheduler) Set(intervals []Interval, now time.Time) {
+	previous := map[Interval]*schedule{}
+
+	for _, sched := range s.schedules {
+		previous[sched.interval] = sched
+	}
+
+	s.schedules = scheduleHeap{}
+
+	for _, interval := range intervals {
+		sched, ok := previous[interval]
+
+		if !ok {
+			sched = &schedule{interval: interval, start: now, next: interval.After(now, 1)}
+		}
+
+		// Ignores the duplicates
+		previous[interval] = nil
+
+		if sched != nil {
+			s.schedules = append(s.schedules, sched)
+		}
+	}
+
+	heap.Init(&s.schedules)
+}
+
+// Returns the end of the first period to complete,
+// false if no interval is scheduled
+func (s *Scheduler) Next() (time.Time, bool) {
+	if len(s.schedules) == 0 {
+		return time.Time{}, false
+	}
+
+	return s.schedules[0].next, true
+}
+
+// Returns the intervals whose period is completed at `now`
+// and schedules their following period. The periods which
+// have been missed are skipped.
+func (s *Scheduler) Due(now time.Time) []Interval {
+	due := []Interval{}
+
+	for len(s.schedules) > 0 && !s.schedules[0].next.After(now) {
+		sched := s.schedules[0]
+		due = append(due, sched.interval)
+
+		for !sched.next.After(now) {
+			sched.periods++
+			sched.next = sched.interval.After(sched.start, sched.periods+1)
+		}
+
+		heap.Fix(&s.schedules, 0)
+	}
+
+	return due
+}
+
+// Implements heap.Interface
+type scheduleHeap []*schedule
+
+func (h scheduleHeap) Len() int { return len(h) }
+
+func (h scheduleHeap) Less(i, j int) bool {
+	if h[i].next.Equal(h[j].next) {
+		return h[i].interval.Approx() < h[j].interval.Approx()
+	}
+
+	return h[i].next.Before(h[j].next)
+}
+
+func (h scheduleHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *scheduleHeap) Push(x interface{}) { *h = append(*h, x.(*schedule)) }
+
+func (h *scheduleHeap) Pop() interface{} {
+	old := *h
+	sched := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return sched
+}
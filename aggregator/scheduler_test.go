@@ -0,0 +1,44 @@
+package aggregator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduler(t *testing.T) {
+	now := time.Date(2024, time.January, 31, 10, 0, 0, 0, time.UTC)
+	tenSeconds := Interval{Duration: 10 * time.Second}
+
+	scheduler := NewScheduler()
+	_, ok := scheduler.Next()
+	assert.False(t, ok)
+
+	scheduler.Set([]Interval{OneMinute, tenSeconds, OneMonth, tenSeconds}, now)
+
+	tables := []struct {
+		now  time.Time
+		due  []Interval
+		next time.Time
+	}{
+		{now.Add(5 * time.Second), []Interval{}, now.Add(10 * time.Second)},
+		{now.Add(10 * time.Second), []Interval{tenSeconds}, now.Add(20 * time.Second)},
+		// The missed periods are skipped
+		{now.Add(65 * time.Second), []Interval{tenSeconds, OneMinute}, now.Add(70 * time.Second)},
+		{time.Date(2024, time.February, 29, 10, 0, 0, 0, time.UTC), []Interval{tenSeconds, OneMinute, OneMonth}, time.Date(2024, time.February, 29, 10, 0, 10, 0, time.UTC)},
+	}
+
+	for _, table := range tables {
+		assert.Equal(t, table.due, scheduler.Due(table.now))
+
+		next, ok := scheduler.Next()
+		assert.True(t, ok)
+		assert.Equal(t, table.next, next)
+	}
+
+	// The intervals still scheduled keep their periods
+	scheduler.Set([]Interval{OneMonth, FiveMinutes}, now.Add(time.Hour))
+	assert.Equal(t, []Interval{FiveMinutes}, scheduler.Due(now.Add(time.Hour+5*time.Minute)))
+	assert.Equal(t, []Interval{FiveMinutes, OneMonth}, scheduler.Due(time.Date(2024, time.March, 31, 10, 0, 0, 0, time.UTC)))
+}
@@ -0,0 +1,200 @@
+package aggregator
+
+import (
+	"sync"
+	"time"
+)
+
+// Type of the records sent to the streams
+type RecordType string
+
+const (
+	// Ticker received from an exchange
+	TickerRecord RecordType = "ticker"
+	// Aggregate sent to Kafka
+	AggregateRecord RecordType = "aggregate"
+)
+
+// Record sent to the streams each time a ticker is received
+// or an aggregate is sent to Kafka
+type Record struct {
+	Type RecordType `json:"type"`
+
+	// Name of the exchange, empty for an aggregate
+	Exchange string `json:"exchange,omitempty"`
+
+	// Canonical symbol of the currency pair (ex: BTCUSD)
+	Symbol string `json:"symbol"`
+
+	// Canonical codes of the currency pair (ex: BTC, USDT)
+	Base  string `json:"base,omitempty"`
+	Quote string `json:"quote,omitempty"`
+
+	// Symbol used by the exchange (ex: BTCUST on Bitfinex), empty for an aggregate
+	NativeSymbol string `json:"native_symbol,omitempty"`
+
+	// Interval of the aggregation (ex: 5m), nil for a ticker
+	Interval *Interval `json:"interval,omitempty"`
+
+	Time time.Time `json:"time"`
+
+	// SimpleTicker or Ticker
+	Data interface{} `json:"data"`
+}
+
+// Filter selects the records sent to a stream.
+// An empty field matches every record.
+type Filter struct {
+	Exchanges []string     `json:"exchanges"`
+	Symbols   []string     `json:"symbols"`
+	Intervals []Interval   `json:"intervals"`
+	Types     []RecordType `json:"types"`
+}
+
+// Broadcaster sends the records to every stream without ever blocking
+type Broadcaster struct {
+	mutex   sync.RWMutex
+	streams map[*Stream]struct{}
+}
+
+// Stream receives the records matching its filter.
+// Records are dropped when its buffer is full,
+// so that a slow client never stalls the aggregator.
+type Stream struct {
+	mutex   sync.Mutex
+	filter  Filter
+	dropped uint64
+
+	// Receives the records. It is closed when the stream is removed
+	C chan Record
+}
+
+// Initializes a broadcaster without any stream
+func NewBroadcaster() *Broadcaster {
+	return &Broadcaster{
+		streams: map[*Stream]struct{}{},
+	}
+}
+
+// Adds a stream which buffers up to `buffer` records
+func (b *Broadcaster) Add(filter Filter, buffer int) *Stream {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	stream := &Stream{filter: filter, C: make(chan Record, buffer)}
+	b.streams[stream] = struct{}{}
+
+	return stream
+}
+
+// Removes a stream and closes its channel
+func (b *Broadcaster) Remove(stream *Stream) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if _, ok := b.streams[stream]; ok {
+		delete(b.streams, stream)
+		close(stream.C)
+	}
+}
+
+// Sends the record to every stream whose filter matches it
+func (b *Broadcaster) Publish(record Record) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	for stream := range b.streams {
+		stream.send(record)
+	}
+}
+
+// Returns the number of streams
+func (b *Broadcaster) Len() int {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	return len(b.streams)
+}
+
+// Replaces the filter of the stream
+func (s *Stream) SetFilter(filter Filter) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.filter = filter
+}
+
+// Returns the number of records dropped because the buffer was full
+func (s *Stream) Dropped() uint64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.dropped
+}
+
+// Sends the record if it matches the filter, without blocking
+func (s *Stream) send(record Record) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.filter.Matches(record) {
+		return
+	}
+
+	select {
+	case s.C <- record:
+	default:
+		s.dropped++
+	}
+}
+
+// Returns true if the record matches every field of the filter
+func (f Filter) Matches(record Record) bool {
+	if len(f.Types) > 0 && !containsType(f.Types, record.Type) {
+		return false
+	}
+
+	if len(f.Exchanges) > 0 && record.Type == TickerRecord && !containsString(f.Exchanges, record.Exchange) {
+		return false
+	}
+
+	if len(f.Symbols) > 0 && !containsString(f.Symbols, record.Symbol) {
+		return false
+	}
+
+	if len(f.Intervals) > 0 && record.Type == AggregateRecord && (record.Interval == nil || !containsInterval(f.Intervals, *record.Interval)) {
+		return false
+	}
+
+	return true
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsType(values []RecordType, value RecordType) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsInterval(values []Interval, value Interval) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,60 @@
+package aggregator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilter(t *testing.T) {
+	ticker := Record{Type: TickerRecord, Exchange: "GDAX", Symbol: "BTCUSD"}
+	aggregate := Record{Type: AggregateRecord, Symbol: "BTCUSD", Interval: &FiveMinutes}
+
+	tables := []struct {
+		filter    Filter
+		ticker    bool
+		aggregate bool
+	}{
+		{Filter{}, true, true},
+		{Filter{Types: []RecordType{AggregateRecord}}, false, true},
+		{Filter{Exchanges: []string{"Bitfinex"}}, false, true},
+		{Filter{Symbols: []string{"ETHEUR"}}, false, false},
+		{Filter{Intervals: []Interval{OneMinute}}, true, false},
+		{Filter{Exchanges: []string{"GDAX"}, Symbols: []string{"BTCUSD"}, Intervals: []Interval{FiveMinutes}}, true, true},
+	}
+
+	for _, table := range tables {
+		assert.Equal(t, table.ticker, table.filter.Matches(ticker))
+		assert.Equal(t, table.aggregate, table.filter.Matches(aggregate))
+	}
+}
+
+func TestBroadcaster(t *testing.T) {
+	broadcaster := NewBroadcaster()
+	all := broadcaster.Add(Filter{}, 2)
+	gdax := broadcaster.Add(Filter{Exchanges: []string{"GDAX"}}, 2)
+	assert.Equal(t, 2, broadcaster.Len())
+
+	// Publishing never blocks: the records which do not fit are dropped
+	for i := 0; i < 3; i++ {
+		broadcaster.Publish(Record{Type: TickerRecord, Exchange: "Bitfinex", Symbol: "BTCUSD"})
+	}
+
+	assert.Equal(t, 2, len(all.C))
+	assert.Equal(t, uint64(1), all.Dropped())
+	assert.Equal(t, 0, len(gdax.C))
+	assert.Equal(t, uint64(0), gdax.Dropped())
+
+	gdax.SetFilter(Filter{Exchanges: []string{"Bitfinex"}})
+	broadcaster.Publish(Record{Type: TickerRecord, Exchange: "Bitfinex", Symbol: "BTCUSD"})
+	assert.Equal(t, 1, len(gdax.C))
+
+	broadcaster.Remove(all)
+	broadcaster.Remove(all)
+	assert.Equal(t, 1, broadcaster.Len())
+
+	<-all.C
+	<-all.C
+	_, ok := <-all.C
+	assert.False(t, ok)
+}
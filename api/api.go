@@ -1,18 +1,26 @@
 package api
 
 import (
-	"os"
 	"sync"
 	"time"
 
 	"github.com/fberrez/romantic-aggregator/aggregator"
+	"github.com/fberrez/romantic-aggregator/auth"
+	"github.com/fberrez/romantic-aggregator/config"
+	"github.com/fberrez/romantic-aggregator/currency"
 	"github.com/fberrez/romantic-aggregator/exchange"
 	"github.com/fberrez/romantic-aggregator/kafka"
+	"github.com/fberrez/romantic-aggregator/rates"
+	"github.com/fberrez/romantic-aggregator/state"
+	"github.com/fberrez/romantic-aggregator/transport"
+	"github.com/fberrez/romantic-aggregator/websocket"
 	"github.com/gin-gonic/gin"
 	"github.com/loopfz/gadgeto/tonic"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
 	"github.com/wI2L/fizz"
 	"github.com/wI2L/fizz/openapi"
+	"google.golang.org/grpc"
 )
 
 // Contains each part of the aggregator
@@ -26,26 +34,54 @@ type Api struct {
 
 	producer   *kafka.AggregatorProducer
 	aggregator *aggregator.Aggregator
+	recorder   *websocket.Recorder
+	store      *state.Store
+	grpc       *grpc.Server
+	rates      *rates.Service
+
+	// Configuration loaded at startup, replaced by each reload
+	config *config.Config
+
+	// Protects the producer, which is replaced when the Kafka settings are reloaded
+	producerMutex sync.RWMutex
+	// Serializes the reloads of the configuration and the changes of the drivers
+	reloadMutex sync.Mutex
+
+	// Intervals of the currency pairs which are being subscribed,
+	// saved once an exchange confirms the subscription
+	pendingIntervals map[currency.Pair][]string
+	// Protects the pending intervals and their saving
+	intervalsMutex sync.Mutex
+
+	// Identifies the principal of each request, nil if the authentication is disabled
+	authenticator auth.Authenticator
+	// Identifies the principal of each gRPC call
+	keys *auth.KeyAuthenticator
+
+	// Duration without any frame after which an exchange is not ready
+	staleAfter time.Duration
+
+	// Counts the running services, waited for by the shutdown
+	waitGroup *sync.WaitGroup
 }
 
 var (
 	log = logrus.WithFields(logrus.Fields{"element": "api"})
 )
 
-const (
-	maxNumberOfTest = 5
-	initialDelay    = 2
-)
-
 // Initializes the api with a new struct and initialized routes
-func Initiliaze() *Api {
+func Initiliaze(cfg *config.Config) *Api {
 	engine := gin.New()
+	engine.Use(instrument)
 
 	f := fizz.NewFromEngine(engine)
 
 	api := &Api{
 		Fizz:             f,
 		InterruptChannel: make([]chan bool, 1),
+		config:           cfg,
+		staleAfter:       cfg.Health.StaleAfter.Duration,
+		pendingIntervals: map[currency.Pair][]string{},
 	}
 
 	infos := &openapi.Info{
@@ -54,13 +90,51 @@ func Initiliaze() *Api {
 		Version:     "0.0.1",
 	}
 
+	tonic.SetErrorHook(errorHook)
+	api.InitializeAuthentication(cfg.API)
+	InitializeIntervalBounds(cfg.Intervals)
+
+	reader := api.authorize(auth.Reader)
+	admin := api.authorize(auth.Admin)
+
 	f.GET("/openapi.json", nil, f.OpenAPI(infos, "json"))
-	f.GET("/ticker/:base/:target/:action", nil, tonic.Handler(api.subscribeHandler, 200))
-	f.GET("/timer/:new", nil, tonic.Handler(api.timerHandler, 200))
+
+	// The probes and the metrics are not authenticated
+	f.GET("/healthz", []fizz.OperationOption{
+		fizz.Summary("Liveness probe"),
+		fizz.Response("503", "The aggregator loop is blocked", HealthOut{}, nil),
+	}, tonic.Handler(api.healthHandler, 200))
+	f.GET("/readyz", []fizz.OperationOption{
+		fizz.Summary("Readiness probe"),
+		fizz.Response("503", "At least one component is down", HealthOut{}, nil),
+	}, tonic.Handler(api.readyHandler, 200))
+	f.GET("/metrics", nil, gin.WrapH(promhttp.Handler()))
+	api.registerV1(f, reader, admin)
+
+	// Superseded by POST/DELETE /v1/subscriptions and PUT /v1/interval
+	deprecated := []fizz.OperationOption{fizz.Deprecated(true)}
+
+	f.GET("/ticker/:base/:target/:action", deprecated, api.audit, admin, tonic.Handler(api.subscribeHandler, 200))
+	f.POST("/ticker/:base/:target/:action", deprecated, api.audit, admin, tonic.Handler(api.subscribeBodyHandler, 200))
+	f.GET("/timer/:new", deprecated, api.audit, admin, tonic.Handler(api.timerHandler, 200))
+	f.GET("/requests/:id", nil, reader, tonic.Handler(api.requestHandler, 200))
+	f.GET("/exchanges", nil, reader, tonic.Handler(api.exchangesHandler, 200))
+	f.GET("/subscriptions", nil, reader, tonic.Handler(api.subscriptionsHandler, 200))
+	f.GET("/subscriptions/:exchange", nil, reader, tonic.Handler(api.exchangeSubscriptionsHandler, 200))
 
 	return api
 }
 
+// Initializes the bounds of the intervals (ex: 10s, 1M)
+func InitializeIntervalBounds(intervals config.Intervals) {
+	if err := aggregator.SetIntervalBounds(intervals.Min, intervals.Max); err != nil {
+		log.WithField("error", err).Fatal("Cannot set the interval bounds")
+	}
+
+	min, max := aggregator.IntervalBounds()
+	log.WithFields(logrus.Fields{"min": min.String(), "max": max.String()}).Debug("Interval bounds")
+}
+
 // Initializes the aggregator
 func InitializeAggregator(kafkaChan chan interface{}) *aggregator.Aggregator {
 	aggregator := aggregator.Initialize(kafkaChan)
@@ -69,17 +143,11 @@ func InitializeAggregator(kafkaChan chan interface{}) *aggregator.Aggregator {
 }
 
 // Initializes the kafka producer
-func InitializeProducer() *kafka.AggregatorProducer {
-	kafkaAddr := os.Getenv("KAFKA_ADDRESS")
+func InitializeProducer(settings config.Kafka) *kafka.AggregatorProducer {
+	producer, err := newProducer(settings)
 
-	if kafkaAddr == "" {
-		log.Fatal("Please, export KAFKA_ADDRESS. Try `export KAFKA_ADDRESS=XXX.XXX.XXX.XXX:9092` OR `-e KAFKA_ADDRESS=XXX.XXX.XXX.XXX:9092` if you're running it with Docker")
-	}
-
-	producer, err := kafka.Initialize(kafkaAddr)
-
-	delay := initialDelay
-	currentNumberOfTestRemaining := maxNumberOfTest
+	delay := settings.RetryDelay.Duration
+	currentNumberOfTestRemaining := settings.Retries
 
 	// while an error occured when initializing the kafka producer,
 	// tries to reinitialize a new one until the number of test reach 0
@@ -88,10 +156,10 @@ func InitializeProducer() *kafka.AggregatorProducer {
 			panic(err)
 		}
 
-		log.WithField("test-remaining", currentNumberOfTestRemaining).Warningf("Initializing the kafka producer failed. Retrying in %d seconds", delay)
-		time.Sleep(time.Duration(delay) * time.Second)
+		log.WithField("test-remaining", currentNumberOfTestRemaining).Warningf("Initializing the kafka producer failed. Retrying in %s", delay)
+		time.Sleep(delay)
 
-		producer, err = kafka.Initialize(kafkaAddr)
+		producer, err = newProducer(settings)
 		currentNumberOfTestRemaining--
 		delay *= 2
 	}
@@ -99,12 +167,82 @@ func InitializeProducer() *kafka.AggregatorProducer {
 	return producer
 }
 
+// Connects a kafka producer, or initializes one which discards the messages
+func newProducer(settings config.Kafka) (*kafka.AggregatorProducer, error) {
+	if settings.Discard {
+		return kafka.Discard(settings.Topic), nil
+	}
+
+	return kafka.Initialize(settings.Address, settings.Topic)
+}
+
+// Initializes the websocket recorder and the replay mode.
+// Every frame is recorded in the record file if it is set.
+// If the replay file is set, exchanges are fed with the recording
+// instead of their websocket.
+func InitializeRecorder(settings config.Recording) *websocket.Recorder {
+	if path := settings.ReplayFile; path != "" {
+		log.WithFields(logrus.Fields{"path": path, "speed": settings.ReplaySpeed}).Info("Replay mode enabled")
+		websocket.SetReplay(&websocket.ReplayOptions{Path: path, Speed: settings.ReplaySpeed})
+	}
+
+	path := settings.RecordFile
+
+	if path == "" {
+		return nil
+	}
+
+	recorder, err := websocket.NewRecorder(path)
+
+	if err != nil {
+		log.WithField("error", err).Fatal("Cannot initialize the websocket recorder")
+	}
+
+	log.WithField("path", path).Info("Recording websocket frames")
+	websocket.SetRecorder(recorder)
+
+	return recorder
+}
+
+// Applies the settings of each exchange and initializes the enabled ones
+func InitializeExchanges(aggregatorChan chan aggregator.SimpleTicker, cfg *config.Config) *exchange.FetcherGroup {
+	disabled := map[string]bool{}
+
+	for label := range exchange.Drivers {
+		settings := cfg.Exchange(label)
+
+		if !settings.IsEnabled() {
+			disabled[label] = true
+			continue
+		}
+
+		configureExchange(label, settings)
+	}
+
+	return exchange.Initialize(aggregatorChan, disabled)
+}
+
+// Applies the connection and websocket settings of an exchange
+func configureExchange(label string, settings *config.Exchange) {
+	transport.Set(label, settings.Transport())
+	websocket.Configure(label, settings.Websocket())
+}
+
 // Starts api and its services (kafka producer, exchanges, aggregator)
-func (a *Api) Start(waitGroup sync.WaitGroup) {
+// The shutdown waits for them through `waitGroup`.
+func (a *Api) Start(waitGroup *sync.WaitGroup) {
+	a.waitGroup = waitGroup
 
-	a.producer = InitializeProducer()
+	a.producer = InitializeProducer(a.config.Sinks.Kafka)
 	a.aggregator = InitializeAggregator(a.producer.Channel)
-	a.FetcherGroup = exchange.Initialize(a.aggregator.AggregatorChannel)
+	a.recorder = InitializeRecorder(a.config.Recording)
+	a.FetcherGroup = InitializeExchanges(a.aggregator.AggregatorChannel, a.config)
+	a.store = InitializeStore(a.config.State.File)
+	a.rates = InitializeRates(a.aggregator.Cache, a.config.Rates)
+
+	// An exchange started at runtime subscribes again to its currency pairs
+	a.FetcherGroup.OnAdd(a.resubscribe)
+	a.FetcherGroup.Registry.OnResolve(a.saveConfirmed)
 
 	waitGroup.Add(3)
 
@@ -123,11 +261,32 @@ func (a *Api) Start(waitGroup sync.WaitGroup) {
 		a.aggregator.Start()
 	}()
 
+	go a.rates.Start(a.config.Rates.Refresh.Duration)
+
+	// The aggregator must be started to receive the restored interval
+	a.restore()
+
+	a.grpc = a.NewGrpcServer()
+
+	go func() {
+		if err := a.ServeGrpc(a.grpc, a.config.API.GrpcPort); err != nil {
+			log.WithField("error", err).Error("An error occured while launching the gRPC server")
+		}
+	}()
+
 }
 
-// Stops api and its services (kafka producer, exchanges, aggregator)
+// Stops api and its services (kafka producer, exchanges, aggregator, rates)
 func (a *Api) Stop() {
-	a.producer.Stop()
+	a.grpc.Stop()
+	a.kafkaProducer().Stop()
 	a.FetcherGroup.Stop()
 	a.aggregator.Stop()
+	a.rates.Stop()
+
+	if a.recorder != nil {
+		if err := a.recorder.Close(); err != nil {
+			log.WithField("error", err).Error("Closing the websocket recorder")
+		}
+	}
 }
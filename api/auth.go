@@ -0,0 +1,194 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/fberrez/romantic-aggregator/auth"
+	"github.com/fberrez/romantic-aggregator/config"
+	"github.com/fberrez/romantic-aggregator/rpc"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// Key of the principal in the gin context
+	principalKey = "principal"
+)
+
+var (
+	audit = logrus.WithFields(logrus.Fields{"element": "audit"})
+
+	// Principal of the requests when the authentication is disabled
+	anonymous = &auth.Principal{Name: "anonymous", Role: auth.Admin}
+
+	// Role required by each gRPC method
+	grpcRoles = map[string]auth.Role{
+		rpc.Aggregator_Subscribe_FullMethodName:         auth.Admin,
+		rpc.Aggregator_Unsubscribe_FullMethodName:       auth.Admin,
+		rpc.Aggregator_SetIntervals_FullMethodName:      auth.Admin,
+		rpc.Aggregator_SetPairIntervals_FullMethodName:  auth.Admin,
+		rpc.Aggregator_ListSubscriptions_FullMethodName: auth.Reader,
+		rpc.Aggregator_StreamTickers_FullMethodName:     auth.Reader,
+	}
+)
+
+// Initializes the authenticators with the API keys and the HMAC secrets
+// of the configuration (ex: "front:reader:s3cr3t", "core:admin:0th3r").
+// The authentication is disabled if none of them is set.
+func (a *Api) InitializeAuthentication(settings config.API) {
+	keys, err := auth.ParseKeys(strings.Join(settings.Keys, ","))
+
+	if err != nil {
+		log.WithField("error", err).Fatal("Cannot read the API keys")
+	}
+
+	secrets, err := auth.ParseSecrets(strings.Join(settings.HMACSecrets, ","))
+
+	if err != nil {
+		log.WithField("error", err).Fatal("Cannot read the HMAC secrets")
+	}
+
+	if len(keys) == 0 && len(secrets) == 0 {
+		log.Warning("No API keys nor HMAC secrets: anyone can change the subscriptions")
+		return
+	}
+
+	a.keys = auth.NewKeyAuthenticator(keys)
+	a.authenticator = auth.Chain{a.keys, auth.NewHMACAuthenticator(secrets)}
+}
+
+// Returns the middleware which rejects the requests
+// whose principal does not have the required role
+func (a *Api) authorize(required auth.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := anonymous
+
+		if a.authenticator != nil {
+			var err error
+			principal, err = a.authenticator.Authenticate(c.Request)
+
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, &ErrorOut{Error: ErrorBody{Code: "unauthorized", Message: err.Error()}})
+				return
+			}
+		}
+
+		c.Set(principalKey, principal)
+
+		if !principal.Role.Allows(required) {
+			c.AbortWithStatusJSON(http.StatusForbidden, &ErrorOut{Error: ErrorBody{Code: "forbidden", Message: string(required) + " role required"}})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// Middleware which logs the mutating calls with their principal,
+// including the ones which are rejected
+func (a *Api) audit(c *gin.Context) {
+	c.Next()
+
+	name, role := "", auth.Role("")
+
+	if value, ok := c.Get(principalKey); ok {
+		principal := value.(*auth.Principal)
+		name, role = principal.Name, principal.Role
+	}
+
+	audit.WithFields(logrus.Fields{
+		"principal": name,
+		"role":      role,
+		"method":    c.Request.Method,
+		"path":      c.Request.URL.RequestURI(),
+		"status":    c.Writer.Status(),
+		"client":    c.ClientIP(),
+	}).Info("Mutating call")
+}
+
+// Returns the options adding the metrics, the authentication and the audit to the gRPC server
+func (a *Api) grpcOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(instrumentUnary, func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			var resp interface{}
+			principal, err := a.authorizeGrpc(ctx, info.FullMethod)
+
+			if err == nil {
+				resp, err = handler(ctx, req)
+			}
+
+			if grpcRoles[info.FullMethod] != auth.Reader {
+				auditGrpc(principal, info.FullMethod, err)
+			}
+
+			return resp, err
+		}),
+		grpc.ChainStreamInterceptor(instrumentStream, func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if _, err := a.authorizeGrpc(stream.Context(), info.FullMethod); err != nil {
+				return err
+			}
+
+			return handler(srv, stream)
+		}),
+	}
+}
+
+// Returns the principal of a gRPC call, identified by the API key sent
+// in the "authorization" (Bearer <key>) or "x-api-key" metadata
+func (a *Api) authorizeGrpc(ctx context.Context, method string) (*auth.Principal, error) {
+	principal := anonymous
+
+	if a.keys != nil {
+		md, _ := metadata.FromIncomingContext(ctx)
+		key := ""
+
+		if values := md.Get("x-api-key"); len(values) > 0 {
+			key = values[0]
+		}
+
+		if values := md.Get("authorization"); len(values) > 0 && strings.HasPrefix(values[0], "Bearer ") {
+			key = strings.TrimPrefix(values[0], "Bearer ")
+		}
+
+		if key == "" {
+			return nil, status.Error(codes.Unauthenticated, auth.ErrNoCredentials.Error())
+		}
+
+		var err error
+		principal, err = a.keys.Lookup(key)
+
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+	}
+
+	required, ok := grpcRoles[method]
+
+	if !ok {
+		required = auth.Admin
+	}
+
+	if !principal.Role.Allows(required) {
+		return principal, status.Errorf(codes.PermissionDenied, "%s role required", required)
+	}
+
+	return principal, nil
+}
+
+// Logs a mutating gRPC call with its principal
+func auditGrpc(principal *auth.Principal, method string, err error) {
+	fields := logrus.Fields{"method": method, "status": status.Code(err).String()}
+
+	if principal != nil {
+		fields["principal"] = principal.Name
+		fields["role"] = principal.Role
+	}
+
+	audit.WithFields(fields).Info("Mutating call")
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/fberrez/romantic-aggregator/currency"
+	"github.com/fberrez/romantic-aggregator/exchange"
+	"github.com/gin-gonic/gin"
+	"github.com/juju/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Path of POST /v1/drivers/{exchange}/{action}
+type DriverIn struct {
+	// Name of the exchange, case insensitive (ex: gdax)
+	Exchange string `path:"exchange" validate:"required"`
+	Action   string `path:"action" enum:"start,stop,restart" validate:"required"`
+}
+
+// Answer to GET /v1/drivers
+type DriversOut struct {
+	Drivers []exchange.DriverStatus `json:"drivers"`
+}
+
+// Handles GET requests sent to /v1/drivers
+func (a *Api) driversHandler(c *gin.Context) error {
+	c.JSON(http.StatusOK, &DriversOut{Drivers: a.FetcherGroup.States()})
+	return nil
+}
+
+// Handles POST requests sent to /v1/drivers/{exchange}/{action}
+func (a *Api) driverHandler(c *gin.Context, in *DriverIn) error {
+	status, err := a.changeDriver(in.Exchange, in.Action)
+
+	if err != nil {
+		return err
+	}
+
+	c.JSON(http.StatusOK, status)
+	return nil
+}
+
+// Starts, stops or restarts an exchange driver.
+// Returns its new state.
+func (a *Api) changeDriver(name string, action string) (*exchange.DriverStatus, error) {
+	status, ok := a.driverStatus(name)
+
+	if !ok {
+		return nil, errors.NotFoundf("exchange driver %s", name)
+	}
+
+	a.reloadMutex.Lock()
+	defer a.reloadMutex.Unlock()
+
+	var err error
+
+	switch action {
+	case "start":
+		configureExchange(status.Name, a.config.Exchange(status.Name))
+		err = a.FetcherGroup.Add(status.Name)
+	case "stop":
+		err = a.FetcherGroup.Remove(status.Name)
+	case "restart":
+		configureExchange(status.Name, a.config.Exchange(status.Name))
+		err = a.FetcherGroup.Restart(status.Name)
+	default:
+		return nil, errors.NotValidf("action %q, expected start, stop or restart", action)
+	}
+
+	switch {
+	case action == "stop" && errors.IsNotFound(err):
+		return nil, errors.NewAlreadyExists(nil, fmt.Sprintf("exchange %s is already stopped", status.Name))
+	case errors.IsAlreadyExists(err), errors.IsNotValid(err):
+		return nil, err
+	}
+
+	// A driver which fails to start is reported with its error,
+	// it is retried in the background
+	log.WithFields(logrus.Fields{"exchange": status.Name, "action": action, "error": err}).Info("Driver changed")
+
+	status, _ = a.driverStatus(status.Name)
+	return status, nil
+}
+
+// Returns the state of the driver matching `name` (case insensitive)
+func (a *Api) driverStatus(name string) (*exchange.DriverStatus, bool) {
+	for _, status := range a.FetcherGroup.States() {
+		if strings.EqualFold(status.Name, name) {
+			return &status, true
+		}
+	}
+
+	return nil, false
+}
+
+// Subscribes an exchange which has been added at runtime
+// to the currency pairs saved for it
+func (a *Api) resubscribe(name string) {
+	for _, sub := range a.store.State().Subscriptions {
+		if !containsFold(sub.Exchanges, name) {
+			continue
+		}
+
+		fields := logrus.Fields{"base": sub.Base, "target": sub.Target, "exchange": name}
+		pair, err := currency.FindCurrencyPair(sub.Base, sub.Target)
+
+		if err != nil {
+			log.WithFields(fields).WithField("error", err).Error("Cannot restore subscription")
+			continue
+		}
+
+		if _, err := a.FetcherGroup.SendMessage(exchange.Subscribe, currency.CurrencySlice{pair}, []string{"ticker"}, []string{name}); err != nil {
+			log.WithFields(fields).WithField("error", err).Error("Cannot restore subscription")
+			continue
+		}
+
+		log.WithFields(fields).Info("Subscription restored")
+	}
+}
+
+// Returns true if `values` contains `value`, whatever its case
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,266 @@
+package api
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/fberrez/romantic-aggregator/aggregator"
+	"github.com/fberrez/romantic-aggregator/currency"
+	"github.com/fberrez/romantic-aggregator/rpc"
+	"github.com/juju/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Serves the gRPC Aggregator service with the handlers of the /v1 HTTP API
+type grpcServer struct {
+	rpc.UnimplementedAggregatorServer
+
+	api *Api
+}
+
+// Initializes the gRPC server, with the same authentication as the HTTP API,
+// and registers the Aggregator service
+func (a *Api) NewGrpcServer() *grpc.Server {
+	server := grpc.NewServer(a.grpcOptions()...)
+	rpc.RegisterAggregatorServer(server, &grpcServer{api: a})
+
+	return server
+}
+
+// Serves the gRPC API on `port` until the server is stopped
+func (a *Api) ServeGrpc(server *grpc.Server, port string) error {
+	listener, err := net.Listen("tcp", ":"+port)
+
+	if err != nil {
+		return errors.Annotatef(err, "tried to listen on :%s", port)
+	}
+
+	log.WithField("port", ":"+port).Info("Launching gRPC server")
+
+	return server.Serve(listener)
+}
+
+func (s *grpcServer) Subscribe(ctx context.Context, in *rpc.SubscribeRequest) (*rpc.SubscriptionResponse, error) {
+	out, err := s.api.subscribeMany(&SubscribeBody{
+		Pairs:     in.Pairs,
+		Channels:  in.Channels,
+		Exchanges: in.Exchanges,
+		Interval:  in.Interval,
+		Intervals: in.Intervals,
+		Wait:      in.Wait,
+	})
+
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return subscriptionResponse(out), nil
+}
+
+func (s *grpcServer) Unsubscribe(ctx context.Context, in *rpc.UnsubscribeRequest) (*rpc.SubscriptionResponse, error) {
+	out, err := s.api.unsubscribeMany(&UnsubscribeBody{
+		Pairs:     in.Pairs,
+		Channels:  in.Channels,
+		Exchanges: in.Exchanges,
+		Wait:      in.Wait,
+	})
+
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return subscriptionResponse(out), nil
+}
+
+func (s *grpcServer) ListSubscriptions(ctx context.Context, in *rpc.ListSubscriptionsRequest) (*rpc.ListSubscriptionsResponse, error) {
+	entries, err := s.api.FetcherGroup.Subscriptions(in.Exchange)
+
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	out := &rpc.ListSubscriptionsResponse{}
+
+	for _, entry := range entries {
+		out.Subscriptions = append(out.Subscriptions, &rpc.Subscription{
+			Exchange:    entry.Exchange,
+			Pair:        entry.Pair,
+			Symbol:      entry.Symbol,
+			Channel:     entry.Channel,
+			Status:      string(entry.Status),
+			Reason:      entry.Reason,
+			Since:       timestamp(entry.Since),
+			LastMessage: timestamp(entry.LastMessage),
+			Messages:    entry.Messages,
+		})
+	}
+
+	return out, nil
+}
+
+func (s *grpcServer) SetIntervals(ctx context.Context, in *rpc.SetIntervalsRequest) (*rpc.SetIntervalsResponse, error) {
+	interval, err := s.api.setInterval(in.Interval)
+
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return &rpc.SetIntervalsResponse{Interval: interval.String()}, nil
+}
+
+func (s *grpcServer) SetPairIntervals(ctx context.Context, in *rpc.SetPairIntervalsRequest) (*rpc.SetPairIntervalsResponse, error) {
+	pair, err := currency.ParseCurrencyPair(in.Pair)
+
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	out, err := s.api.setPairIntervals(pair.Base, pair.Quote, in.Intervals)
+
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return &rpc.SetPairIntervalsResponse{Pair: out.Pair, Intervals: out.Intervals}, nil
+}
+
+func (s *grpcServer) StreamTickers(in *rpc.StreamTickersRequest, server rpc.Aggregator_StreamTickersServer) error {
+	filter, err := parseFilter(StreamFilterIn{
+		Exchanges: in.Exchanges,
+		Symbols:   in.Symbols,
+		Intervals: in.Intervals,
+		Types:     in.Types,
+	})
+
+	if err != nil {
+		return grpcError(err)
+	}
+
+	stream := s.api.aggregator.Stream.Add(filter, streamBuffer)
+	defer s.api.aggregator.Stream.Remove(stream)
+
+	var dropped uint64
+
+	for {
+		select {
+		case record, ok := <-stream.C:
+			if !ok {
+				return nil
+			}
+
+			if current := stream.Dropped(); current > dropped {
+				dropped = current
+
+				if err := server.Send(&rpc.Record{Type: "dropped", Dropped: dropped}); err != nil {
+					return err
+				}
+			}
+
+			if err := server.Send(grpcRecord(record)); err != nil {
+				return err
+			}
+		case <-server.Context().Done():
+			return nil
+		}
+	}
+}
+
+// Converts the answer of a (un)subscription to its gRPC message
+func subscriptionResponse(out *SubscriptionsOut) *rpc.SubscriptionResponse {
+	response := &rpc.SubscriptionResponse{
+		Request: out.Request,
+		Status:  string(out.Status),
+	}
+
+	for _, outcome := range out.Outcomes {
+		grpcOutcome := &rpc.Outcome{
+			Exchange: outcome.Exchange,
+			Status:   string(outcome.Status),
+			Reason:   outcome.Reason,
+		}
+
+		for _, item := range outcome.Items {
+			grpcOutcome.Items = append(grpcOutcome.Items, &rpc.ItemOutcome{
+				Symbol:  item.Symbol,
+				Channel: item.Channel,
+				Status:  string(item.Status),
+				Reason:  item.Reason,
+			})
+		}
+
+		response.Outcomes = append(response.Outcomes, grpcOutcome)
+	}
+
+	return response
+}
+
+// Converts a streamed record to its gRPC message
+func grpcRecord(record aggregator.Record) *rpc.Record {
+	out := &rpc.Record{
+		Type:         string(record.Type),
+		Exchange:     record.Exchange,
+		Symbol:       record.Symbol,
+		Base:         record.Base,
+		Quote:        record.Quote,
+		NativeSymbol: record.NativeSymbol,
+		Time:         timestamppb.New(record.Time),
+	}
+
+	if record.Interval != nil {
+		out.Interval = record.Interval.String()
+	}
+
+	switch data := record.Data.(type) {
+	case aggregator.SimpleTicker:
+		out.Price, out.Bid, out.Ask, out.Volume = data.Price.InexactFloat64(), data.Bid.InexactFloat64(), data.Ask.InexactFloat64(), data.Volume.InexactFloat64()
+		out.Decimals = &rpc.Decimals{Price: data.Price.String(), Bid: data.Bid.String(), Ask: data.Ask.String(), Volume: data.Volume.String()}
+	case aggregator.Ticker:
+		out.Price, out.Bid, out.Ask, out.Volume = data.Price.InexactFloat64(), data.Bid.InexactFloat64(), data.Ask.InexactFloat64(), data.Volume.InexactFloat64()
+		out.High, out.Low = data.High.InexactFloat64(), data.Low.InexactFloat64()
+		out.Decimals = &rpc.Decimals{
+			Price:  data.Price.String(),
+			Bid:    data.Bid.String(),
+			Ask:    data.Ask.String(),
+			Volume: data.Volume.String(),
+			High:   data.High.String(),
+			Low:    data.Low.String(),
+		}
+		out.NativeSymbols = data.NativeSymbols
+	}
+
+	return out
+}
+
+// Converts an optional time to a timestamp
+func timestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+
+	return timestamppb.New(*t)
+}
+
+// Converts an error to a gRPC status matching its kind.
+// The errors which are not expected are internal ones.
+func grpcError(err error) error {
+	code := codes.Internal
+
+	switch {
+	case errors.IsNotValid(err):
+		code = codes.InvalidArgument
+	case errors.IsNotFound(err):
+		code = codes.NotFound
+	case errors.IsAlreadyExists(err):
+		code = codes.AlreadyExists
+	case errors.IsUnauthorized(err):
+		code = codes.Unauthenticated
+	}
+
+	return status.Error(code, err.Error())
+}
+
+var _ rpc.AggregatorServer = &grpcServer{}
@@ -0,0 +1,321 @@
+package api
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fberrez/romantic-aggregator/aggregator"
+	"github.com/fberrez/romantic-aggregator/auth"
+	"github.com/fberrez/romantic-aggregator/currency"
+	"github.com/fberrez/romantic-aggregator/exchange"
+	"github.com/fberrez/romantic-aggregator/rpc"
+	"github.com/fberrez/romantic-aggregator/state"
+	"github.com/fberrez/romantic-aggregator/subscription"
+	"github.com/fberrez/romantic-aggregator/websocket"
+	"github.com/juju/errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// Fetcher which acknowledges every message at once,
+// or never if it is silent.
+// The messages are rejected with `rejection` if it is set.
+type fakeFetcher struct {
+	acks      chan subscription.Ack
+	silent    bool
+	rejection string
+}
+
+func (f *fakeFetcher) Initialize(chan aggregator.SimpleTicker) error { return nil }
+func (f *fakeFetcher) Start() error                                  { return nil }
+func (f *fakeFetcher) Interrupt()                                    {}
+func (f *fakeFetcher) Status() websocket.Status                      { return websocket.Status{Label: "GDAX"} }
+func (f *fakeFetcher) Acknowledgements() chan subscription.Ack       { return f.acks }
+func (f *fakeFetcher) FetchMarkets() ([]currency.Market, error)      { return nil, nil }
+
+func (f *fakeFetcher) Pairs() currency.CurrencySlice {
+	return currency.CurrencySlice{currency.BTCUSD, currency.ETHEUR}
+}
+
+func (f *fakeFetcher) TranslateCurrency(pairs currency.CurrencySlice) ([]string, error) {
+	return pairs.ToGDAX()
+}
+
+func (f *fakeFetcher) NewMessage(isSubscribe bool, productIds []string, channels []string) error {
+	if f.silent {
+		return nil
+	}
+
+	for _, productId := range productIds {
+		for _, channel := range channels {
+			f.acks <- subscription.Ack{
+				Exchange: "GDAX",
+				Action:   subscription.ActionOf(isSubscribe),
+				Symbol:   productId,
+				Channel:  channel,
+				Error:    f.rejection,
+			}
+		}
+	}
+
+	return nil
+}
+
+// Starts an Api with the fake exchanges, GDAX by default
+func newTestApi(t *testing.T, fetchers map[string]exchange.Fetcher) (*Api, func()) {
+	dir, err := ioutil.TempDir("", "api")
+	assert.Nil(t, err)
+
+	store, err := state.Open(filepath.Join(dir, "state.json"))
+	assert.Nil(t, err)
+
+	if fetchers == nil {
+		fetchers = map[string]exchange.Fetcher{"GDAX": &fakeFetcher{acks: make(chan subscription.Ack, 16)}}
+	}
+
+	a := &Api{
+		aggregator:       aggregator.Initialize(make(chan interface{}, 16)),
+		store:            store,
+		pendingIntervals: map[currency.Pair][]string{},
+	}
+	a.FetcherGroup = exchange.NewFetcherGroup(a.aggregator.AggregatorChannel, fetchers)
+	a.FetcherGroup.Registry.OnResolve(a.saveConfirmed)
+
+	go a.aggregator.Start()
+	go a.FetcherGroup.Start()
+
+	return a, func() {
+		a.FetcherGroup.Stop()
+		a.aggregator.Stop()
+		os.RemoveAll(dir)
+	}
+}
+
+// Starts an Api with a fake exchange and returns a gRPC client connected through bufconn
+func newGrpcClient(t *testing.T, keys map[string]auth.Principal) (rpc.AggregatorClient, *Api, func()) {
+	a, stop := newTestApi(t, nil)
+
+	if len(keys) > 0 {
+		a.keys = auth.NewKeyAuthenticator(keys)
+	}
+
+	listener := bufconn.Listen(1 << 20)
+	server := a.NewGrpcServer()
+	go server.Serve(listener)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.Nil(t, err)
+
+	return rpc.NewAggregatorClient(conn), a, func() {
+		conn.Close()
+		server.Stop()
+		stop()
+	}
+}
+
+func TestGrpcSubscriptions(t *testing.T) {
+	client, a, stop := newGrpcClient(t, nil)
+	defer stop()
+
+	ctx := context.Background()
+
+	out, err := client.Subscribe(ctx, &rpc.SubscribeRequest{Pairs: []string{"BTC-USD", "ETH-EUR"}, Interval: "5m", Wait: true})
+	assert.Nil(t, err)
+	assert.Equal(t, string(subscription.Confirmed), out.Status)
+	assert.Equal(t, 1, len(out.Outcomes))
+	assert.Equal(t, 2, len(out.Outcomes[0].Items))
+
+	_, err = client.Subscribe(ctx, &rpc.SubscribeRequest{Pairs: []string{"BTC-USD"}})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	_, err = client.Subscribe(ctx, &rpc.SubscribeRequest{Pairs: []string{"LTC-GBP"}})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	list, err := client.ListSubscriptions(ctx, &rpc.ListSubscriptionsRequest{Exchange: "gdax"})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(list.Subscriptions))
+	assert.Equal(t, "BTC-USD", list.Subscriptions[0].Pair)
+	assert.NotNil(t, list.Subscriptions[0].Since)
+
+	_, err = client.ListSubscriptions(ctx, &rpc.ListSubscriptionsRequest{Exchange: "Kraken"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	out, err = client.Unsubscribe(ctx, &rpc.UnsubscribeRequest{Pairs: []string{"ETH-EUR"}, Wait: true})
+	assert.Nil(t, err)
+	assert.Equal(t, string(subscription.Confirmed), out.Status)
+
+	_, err = client.Unsubscribe(ctx, &rpc.UnsubscribeRequest{Pairs: []string{"ETH-EUR"}})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.SetIntervals(ctx, &rpc.SetIntervalsRequest{Interval: "7x"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	interval, err := client.SetIntervals(ctx, &rpc.SetIntervalsRequest{Interval: "15m"})
+	assert.Nil(t, err)
+	assert.Equal(t, "15m", interval.Interval)
+
+	// Both transports save the desired state
+	assert.Equal(t, state.State{
+		Subscriptions: []state.Subscription{{Base: "BTC", Target: "USD", Exchanges: []string{"GDAX"}}},
+		Interval:      "15m",
+	}, a.store.State())
+}
+
+func TestGrpcRejectedSubscriptions(t *testing.T) {
+	fetcher := &fakeFetcher{acks: make(chan subscription.Ack, 16), rejection: "unknown product"}
+	a, stop := newTestApi(t, map[string]exchange.Fetcher{"GDAX": fetcher})
+	defer stop()
+
+	out, err := a.subscribeMany(&SubscribeBody{Pairs: []string{"BTC-USD"}, Intervals: []string{"1m"}, Wait: true})
+	assert.Nil(t, err)
+	assert.Equal(t, subscription.Rejected, out.Status)
+
+	// Only the subscriptions confirmed by the exchanges are saved
+	assert.Equal(t, []state.Subscription{}, a.store.State().Subscriptions)
+	assert.False(t, a.store.Exists())
+}
+
+func TestGrpcPairIntervals(t *testing.T) {
+	client, a, stop := newGrpcClient(t, nil)
+	defer stop()
+
+	ctx := context.Background()
+
+	_, err := client.Subscribe(ctx, &rpc.SubscribeRequest{Pairs: []string{"BTC-USD"}, Intervals: []string{"10s", "1m", "10s"}, Wait: true})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"10s", "1m"}, a.store.State().Subscriptions[0].Intervals)
+
+	tables := []struct {
+		pair      string
+		intervals []string
+		code      codes.Code
+		expected  []string
+	}{
+		{"BTC-USD", []string{"30s"}, codes.OK, []string{"30s"}},
+		{"btc-usd", []string{"1h", "5m"}, codes.OK, []string{"1h", "5m"}},
+		{"BTC-USD", []string{"7x"}, codes.InvalidArgument, nil},
+		{"BTCUSD", []string{"5m"}, codes.OK, []string{"5m"}},
+		{"ETH-EUR", []string{"5m"}, codes.NotFound, nil},
+		{"BTC-USD", nil, codes.OK, []string{}},
+	}
+
+	for _, table := range tables {
+		out, err := client.SetPairIntervals(ctx, &rpc.SetPairIntervalsRequest{Pair: table.pair, Intervals: table.intervals})
+		assert.Equal(t, table.code, status.Code(err), table.pair)
+
+		if table.code == codes.OK {
+			assert.Equal(t, "BTC-USD", out.Pair)
+			assert.Equal(t, len(table.expected), len(out.Intervals))
+			assert.Equal(t, len(table.expected), len(a.store.State().Subscriptions[0].Intervals))
+		}
+	}
+
+	// The intervals are forgotten with the subscription
+	_, err = client.SetPairIntervals(ctx, &rpc.SetPairIntervalsRequest{Pair: "BTC-USD", Intervals: []string{"5m"}})
+	assert.Nil(t, err)
+
+	_, err = client.Unsubscribe(ctx, &rpc.UnsubscribeRequest{Pairs: []string{"BTC-USD"}, Wait: true})
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool { return len(a.aggregator.Status().Pairs) == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func TestGrpcStreamTickers(t *testing.T) {
+	client, a, stop := newGrpcClient(t, nil)
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	invalid, err := client.StreamTickers(ctx, &rpc.StreamTickersRequest{Types: []string{"unknown"}})
+	assert.Nil(t, err)
+	_, err = invalid.Recv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	stream, err := client.StreamTickers(ctx, &rpc.StreamTickersRequest{Symbols: []string{"BTC-USD"}, Types: []string{"ticker"}})
+	assert.Nil(t, err)
+
+	// Waits until the stream is registered
+	for a.aggregator.Stream.Len() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	a.aggregator.AggregatorChannel <- aggregator.SimpleTicker{Exchange: "GDAX", Symbol: "ETHEUR", Price: currency.NewDecimal(decimal.NewFromInt(1)), Volume: currency.NewDecimal(decimal.NewFromInt(1))}
+	a.aggregator.AggregatorChannel <- aggregator.SimpleTicker{Exchange: "GDAX", Symbol: "BTCUSD", Price: currency.NewDecimal(decimal.RequireFromString("2.10000000001")), Volume: currency.NewDecimal(decimal.NewFromInt(1))}
+
+	record, err := stream.Recv()
+	assert.Nil(t, err)
+	assert.Equal(t, "ticker", record.Type)
+	assert.Equal(t, "BTCUSD", record.Symbol)
+	assert.Equal(t, 2.10000000001, record.Price)
+	assert.Equal(t, "2.10000000001", record.Decimals.Price)
+}
+
+func TestGrpcError(t *testing.T) {
+	tables := []struct {
+		err  error
+		code codes.Code
+	}{
+		{errors.NotFoundf("exchange Kraken"), codes.NotFound},
+		{errors.AlreadyExistsf("subscription"), codes.AlreadyExists},
+		{errors.NotValidf("channel trades"), codes.InvalidArgument},
+		{errors.Unauthorizedf("key"), codes.Unauthenticated},
+		{errors.Annotate(errors.NotValidf("pair"), "tried to subscribe"), codes.InvalidArgument},
+		{errors.New("connection reset"), codes.Internal},
+	}
+
+	for _, table := range tables {
+		err := grpcError(table.err)
+
+		assert.Equal(t, table.code, status.Code(err), "%s", table.err)
+		assert.Equal(t, table.err.Error(), status.Convert(err).Message())
+	}
+}
+
+func TestGrpcAuthentication(t *testing.T) {
+	client, _, stop := newGrpcClient(t, map[string]auth.Principal{
+		"r34d":  {Name: "front", Role: auth.Reader},
+		"4dm1n": {Name: "core", Role: auth.Admin},
+	})
+	defer stop()
+
+	tables := []struct {
+		key       string
+		list      codes.Code
+		subscribe codes.Code
+	}{
+		{"", codes.Unauthenticated, codes.Unauthenticated},
+		{"wrong", codes.Unauthenticated, codes.Unauthenticated},
+		{"r34d", codes.OK, codes.PermissionDenied},
+		{"4dm1n", codes.OK, codes.OK},
+	}
+
+	for _, table := range tables {
+		ctx := context.Background()
+
+		if table.key != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+table.key)
+		}
+
+		_, err := client.ListSubscriptions(ctx, &rpc.ListSubscriptionsRequest{})
+		assert.Equal(t, table.list, status.Code(err))
+
+		_, err = client.SetIntervals(ctx, &rpc.SetIntervalsRequest{Interval: "5m"})
+		assert.Equal(t, table.subscribe, status.Code(err))
+	}
+}
@@ -2,87 +2,190 @@ package api
 
 import (
 	"fmt"
+	"time"
 
-	"github.com/fberrez/romantic-aggregator/aggregator"
 	"github.com/fberrez/romantic-aggregator/currency"
 	"github.com/fberrez/romantic-aggregator/exchange"
+	"github.com/fberrez/romantic-aggregator/websocket"
 	"github.com/gin-gonic/gin"
 )
 
 type SubscribeIn struct {
-	Base   string `path:"base" validate="required"`
-	Target string `path:"target" validate="required"`
-	Action string `path:"action" enum:"subscribe,unsubscribe" validate="required"`
+	Base   string `path:"base" validate:"required"`
+	Target string `path:"target" validate:"required"`
+	Action string `path:"action" enum:"subscribe,unsubscribe" validate:"required"`
+	// Waits for the acknowledgement of the exchanges before answering
+	Wait bool `query:"wait" default:"true"`
+	// Comma-separated list of the targeted exchanges (ex: GDAX,Bitfinex).
+	// Every exchange listing the currency pair is targeted if it is empty.
+	Exchanges string `query:"exchanges"`
+}
+
+type SubscribeBodyIn struct {
+	SubscribeIn
+
+	// Targeted exchanges (ex: ["GDAX", "Bitfinex"]), replaces the exchanges of the query.
+	// Every exchange listing the currency pair is targeted if both are empty.
+	Exchanges []string `json:"exchanges"`
+}
+
+// Exchange and the currency pairs it lists
+type ExchangeOut struct {
+	websocket.Status
+	Pairs []string `json:"pairs"`
+}
+
+type SubscriptionsIn struct {
+	Exchange string `path:"exchange"`
+}
+
+type RequestIn struct {
+	Id string `path:"id" validate:"required"`
+}
+
+// Answer to a (un)subscription
+type SubscribeOut struct {
+	Message string `json:"message"`
+	// Id of the request, which can be consulted on /requests/{id}
+	Request string `json:"request"`
+	// Outcome of the request on each exchange (pending, confirmed or rejected)
+	Outcomes []exchange.Outcome `json:"outcomes"`
 }
 
 type TimerIn struct {
-	New string `path:"new" enum:"1m,3m,5m,15m,30m,45m,1H,2H,3H,4H,1D,1W,1M" validate="required"`
+	New string `path:"new" validate:"required"`
 }
 
 var (
 	subscribe   string = "subscribe"
 	unsubscribe string = "unsubscribe"
+
+	// Maximum duration during which a request waits for the exchanges acknowledgements
+	ackWait time.Duration = 5 * time.Second
 )
 
-// Handles requests sent to /ticker/{base}/{target}/{action}
+// Handles GET requests sent to /ticker/{base}/{target}/{action}?exchanges=...
 func (a *Api) subscribeHandler(c *gin.Context, in *SubscribeIn) error {
-	currencyPair, err := currency.FindCurrencyPair(in.Base, in.Target)
+	return a.subscribe(c, in.Base, in.Target, in.Action, split(in.Exchanges), in.Wait)
+}
+
+// Handles POST requests sent to /ticker/{base}/{target}/{action}
+// with the targeted exchanges in the body
+func (a *Api) subscribeBodyHandler(c *gin.Context, in *SubscribeBodyIn) error {
+	exchanges := in.Exchanges
+
+	if len(exchanges) == 0 {
+		exchanges = split(in.SubscribeIn.Exchanges)
+	}
+
+	return a.subscribe(c, in.Base, in.Target, in.Action, exchanges, in.Wait)
+}
+
+// Sends the (un)subscription to the targeted exchanges
+// and answers with the outcome on each of them
+func (a *Api) subscribe(c *gin.Context, base string, target string, action string, exchanges []string, wait bool) error {
+	currencyPair, err := currency.FindCurrencyPair(base, target)
 
 	if err != nil {
 		return err
 	}
 
-	errorsArray := []error{}
+	var request *exchange.Request
 
-	switch in.Action {
+	switch action {
 	case subscribe:
-		errorsArray = a.FetcherGroup.SendMessage(exchange.Subscribe, currency.CurrencySlice{currencyPair}, []string{"ticker"})
+		request, err = a.FetcherGroup.SendMessage(exchange.Subscribe, currency.CurrencySlice{currencyPair}, []string{"ticker"}, exchanges)
 	case unsubscribe:
-		errorsArray = a.FetcherGroup.SendMessage(exchange.Unsubscribe, currency.CurrencySlice{currencyPair}, []string{"ticker"})
+		request, err = a.FetcherGroup.SendMessage(exchange.Unsubscribe, currency.CurrencySlice{currencyPair}, []string{"ticker"}, exchanges)
+	}
+
+	if err != nil {
+		return err
 	}
 
-	if len(errorsArray) > 0 {
-		return errorsArray[0]
+	if action == unsubscribe {
+		a.saveUnsubscriptions(currency.CurrencySlice{currencyPair}, request)
 	}
 
-	message := fmt.Sprintf("subscription to %s%s done", in.Base, in.Target)
-	c.JSON(200, gin.H{"message": message})
+	if wait {
+		request.Wait(ackWait)
+	}
+
+	c.JSON(200, &SubscribeOut{
+		Message:  fmt.Sprintf("%s to %s%s sent", action, base, target),
+		Request:  request.Id,
+		Outcomes: a.FetcherGroup.Tracker.Outcomes(request),
+	})
+
+	return nil
+}
+
+// Handles requests sent to /requests/{id}.
+// Returns the outcome of a (un)subscription on each exchange.
+func (a *Api) requestHandler(c *gin.Context, in *RequestIn) error {
+	request, err := a.FetcherGroup.Tracker.Find(in.Id)
+
+	if err != nil {
+		return err
+	}
+
+	c.JSON(200, &SubscribeOut{
+		Message:  fmt.Sprintf("%s request", request.Action),
+		Request:  request.Id,
+		Outcomes: a.FetcherGroup.Tracker.Outcomes(request),
+	})
 
 	return nil
 }
 
 func (a *Api) timerHandler(c *gin.Context, in *TimerIn) error {
-	switch in.New {
-	case "1m":
-		a.aggregator.SetInterval(aggregator.OneMinute)
-	case "3m":
-		a.aggregator.SetInterval(aggregator.ThreeMinutes)
-	case "5m":
-		a.aggregator.SetInterval(aggregator.FiveMinutes)
-	case "15m":
-		a.aggregator.SetInterval(aggregator.FifTeenMinutes)
-	case "30m":
-		a.aggregator.SetInterval(aggregator.ThirtyMinutes)
-	case "45m":
-		a.aggregator.SetInterval(aggregator.FortyFiveMinutes)
-	case "1H":
-		a.aggregator.SetInterval(aggregator.OneHour)
-	case "2H":
-		a.aggregator.SetInterval(aggregator.TwoHours)
-	case "3H":
-		a.aggregator.SetInterval(aggregator.ThreeHours)
-	case "4H":
-		a.aggregator.SetInterval(aggregator.FourHours)
-	case "1D":
-		a.aggregator.SetInterval(aggregator.OneDay)
-	case "1W":
-		a.aggregator.SetInterval(aggregator.OneWeek)
-	case "1M":
-		a.aggregator.SetInterval(aggregator.OneMonth)
+	interval, err := a.setInterval(in.New)
+
+	if err != nil {
+		return err
 	}
 
-	message := fmt.Sprintf("Time set on %s", in.New)
+	message := fmt.Sprintf("Time set on %s", interval)
 
 	c.JSON(200, gin.H{"message": message})
 	return nil
 }
+
+// Handles requests sent to /exchanges.
+// Returns the state of each exchange (ex: outbound queue depth)
+// and the currency pairs it lists
+func (a *Api) exchangesHandler(c *gin.Context) error {
+	exchanges := []ExchangeOut{}
+
+	for _, status := range a.FetcherGroup.Status() {
+		exchanges = append(exchanges, ExchangeOut{Status: status, Pairs: a.FetcherGroup.Pairs(status.Label).Strings()})
+	}
+
+	c.JSON(200, gin.H{"exchanges": exchanges})
+	return nil
+}
+
+// Handles requests sent to /subscriptions.
+// Returns the subscriptions of every exchange
+func (a *Api) subscriptionsHandler(c *gin.Context) error {
+	return a.subscriptions(c, "")
+}
+
+// Handles requests sent to /subscriptions/{exchange}.
+// Returns the subscriptions of one exchange
+func (a *Api) exchangeSubscriptionsHandler(c *gin.Context, in *SubscriptionsIn) error {
+	return a.subscriptions(c, in.Exchange)
+}
+
+// Answers with the subscriptions of an exchange,
+// or of every exchange if `exchange` is empty
+func (a *Api) subscriptions(c *gin.Context, exchange string) error {
+	subscriptions, err := a.FetcherGroup.Subscriptions(exchange)
+
+	if err != nil {
+		return err
+	}
+
+	c.JSON(200, gin.H{"subscriptions": subscriptions})
+	return nil
+}
@@ -0,0 +1,172 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/fberrez/romantic-aggregator/aggregator"
+	"github.com/fberrez/romantic-aggregator/kafka"
+	"github.com/fberrez/romantic-aggregator/websocket"
+	"github.com/gin-gonic/gin"
+)
+
+// Status of a component or of the whole service
+const (
+	statusUp   string = "up"
+	statusDown string = "down"
+)
+
+const (
+	// Number of missed heartbeats after which the aggregator loop is blocked
+	missedHeartbeats = 3
+)
+
+// Result of the checks of one component
+type ComponentOut struct {
+	// Name of the component (ex: kafka, exchange:GDAX)
+	Name string `json:"name"`
+	// Up or down
+	Status string `json:"status"`
+	// Reason why the component is down
+	Reason string `json:"reason,omitempty"`
+	// Current state of the component
+	Details interface{} `json:"details,omitempty"`
+}
+
+// Result of the checks of every component
+type HealthOut struct {
+	// Up if every component is up
+	Status     string         `json:"status"`
+	Components []ComponentOut `json:"components"`
+}
+
+// Handles requests sent to /healthz.
+// The service is alive while the aggregator loop is not blocked.
+func (a *Api) healthHandler(c *gin.Context) error {
+	a.writeHealth(c, a.checkAggregator(time.Now()))
+	return nil
+}
+
+// Handles requests sent to /readyz.
+// The service is ready when Kafka, each exchange and the aggregator are up.
+func (a *Api) readyHandler(c *gin.Context) error {
+	now := time.Now()
+	components := []ComponentOut{a.checkProducer()}
+	components = append(components, a.checkExchanges(now)...)
+	components = append(components, a.checkAggregator(now))
+
+	a.writeHealth(c, components...)
+	return nil
+}
+
+// Writes the components with 200 if they are all up, 503 otherwise
+func (a *Api) writeHealth(c *gin.Context, components ...ComponentOut) {
+	out := &HealthOut{Status: statusUp, Components: components}
+	code := http.StatusOK
+
+	for _, component := range components {
+		if component.Status == statusDown {
+			out.Status = statusDown
+			code = http.StatusServiceUnavailable
+		}
+	}
+
+	c.JSON(code, out)
+}
+
+// Checks that the Kafka producer is running, connected to a broker,
+// and that the last message was produced
+func (a *Api) checkProducer() ComponentOut {
+	producer := a.kafkaProducer()
+
+	if producer == nil {
+		return ComponentOut{Name: "kafka", Status: statusDown, Reason: "not started"}
+	}
+
+	return checkProducer(producer.Status())
+}
+
+func checkProducer(status kafka.Status) ComponentOut {
+	component := ComponentOut{Name: "kafka", Status: statusUp, Details: status}
+
+	switch {
+	case !status.Running:
+		component.Status, component.Reason = statusDown, "not running"
+	// Nothing is sent to Kafka, there is no broker to check
+	case status.Discard:
+	case status.Brokers == 0:
+		component.Status, component.Reason = statusDown, "no broker connected"
+	// The last outcome tells whether the brokers are reachable
+	case status.LastError != nil && (status.LastSuccess == nil || status.LastError.After(*status.LastSuccess)):
+		component.Status, component.Reason = statusDown, "last message failed: "+status.Error
+	}
+
+	return component
+}
+
+// Checks that each exchange is connected and not stale
+func (a *Api) checkExchanges(now time.Time) []ComponentOut {
+	if a.FetcherGroup == nil {
+		return []ComponentOut{{Name: "exchanges", Status: statusDown, Reason: "not started"}}
+	}
+
+	components := []ComponentOut{}
+
+	for _, status := range a.FetcherGroup.Status() {
+		confirmed := a.FetcherGroup.Registry.Confirmed(status.Label)
+		components = append(components, checkExchange(status, confirmed, a.staleAfter, now))
+	}
+
+	failures := a.FetcherGroup.Failures()
+	names := []string{}
+
+	for name := range failures {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		components = append(components, ComponentOut{Name: "exchange:" + name, Status: statusDown, Reason: "not initialized: " + failures[name].Error()})
+	}
+
+	return components
+}
+
+func checkExchange(status websocket.Status, confirmed int, staleAfter time.Duration, now time.Time) ComponentOut {
+	component := ComponentOut{Name: "exchange:" + status.Label, Status: statusUp, Details: status}
+
+	switch {
+	case !status.Connected:
+		component.Status, component.Reason = statusDown, "not connected"
+	// An exchange without subscriptions may not send anything
+	case confirmed == 0:
+	case status.LastMessage == nil:
+		component.Status, component.Reason = statusDown, "no message received"
+	case staleAfter > 0 && now.Sub(*status.LastMessage) > staleAfter:
+		component.Status, component.Reason = statusDown, fmt.Sprintf("no message received for %s", now.Sub(*status.LastMessage).Truncate(time.Second))
+	}
+
+	return component
+}
+
+// Checks that the aggregator loop is progressing
+func (a *Api) checkAggregator(now time.Time) ComponentOut {
+	if a.aggregator == nil {
+		return ComponentOut{Name: "aggregator", Status: statusDown, Reason: "not started"}
+	}
+
+	return checkAggregator(a.aggregator.Status(), now)
+}
+
+func checkAggregator(status aggregator.Status, now time.Time) ComponentOut {
+	component := ComponentOut{Name: "aggregator", Status: statusUp, Details: status}
+
+	if blocked := now.Sub(status.LastLoop); blocked > missedHeartbeats*aggregator.HeartbeatPeriod {
+		component.Status, component.Reason = statusDown, fmt.Sprintf("loop blocked for %s", blocked.Truncate(time.Second))
+	}
+
+	return component
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fberrez/romantic-aggregator/aggregator"
+	"github.com/fberrez/romantic-aggregator/kafka"
+	"github.com/fberrez/romantic-aggregator/websocket"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckProducer(t *testing.T) {
+	before := time.Unix(1500000000, 0)
+	after := before.Add(time.Second)
+
+	tables := []struct {
+		status kafka.Status
+		result string
+	}{
+		{kafka.Status{Running: false}, statusDown},
+		{kafka.Status{Running: true}, statusDown},
+		{kafka.Status{Running: true, Brokers: 1}, statusUp},
+		{kafka.Status{Running: true, Brokers: 1, LastSuccess: &after, LastError: &before, Error: "timeout"}, statusUp},
+		{kafka.Status{Running: true, Brokers: 1, LastSuccess: &before, LastError: &after, Error: "timeout"}, statusDown},
+		{kafka.Status{Running: true, Brokers: 1, LastError: &before, Error: "timeout"}, statusDown},
+		{kafka.Status{Running: true, Discard: true}, statusUp},
+	}
+
+	for _, table := range tables {
+		assert.Equal(t, table.result, checkProducer(table.status).Status)
+	}
+}
+
+func TestCheckExchange(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+	recent := now.Add(-10 * time.Second)
+	old := now.Add(-2 * time.Minute)
+
+	tables := []struct {
+		status    websocket.Status
+		confirmed int
+		result    string
+	}{
+		{websocket.Status{Label: "GDAX", Connected: false}, 0, statusDown},
+		{websocket.Status{Label: "GDAX", Connected: true}, 0, statusUp},
+		{websocket.Status{Label: "GDAX", Connected: true}, 2, statusDown},
+		{websocket.Status{Label: "GDAX", Connected: true, LastMessage: &recent}, 2, statusUp},
+		{websocket.Status{Label: "GDAX", Connected: true, LastMessage: &old}, 2, statusDown},
+		{websocket.Status{Label: "GDAX", Connected: true, LastMessage: &old}, 0, statusUp},
+	}
+
+	for _, table := range tables {
+		component := checkExchange(table.status, table.confirmed, time.Minute, now)
+
+		assert.Equal(t, "exchange:GDAX", component.Name)
+		assert.Equal(t, table.result, component.Status)
+	}
+}
+
+func TestCheckAggregator(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+
+	assert.Equal(t, statusUp, checkAggregator(aggregator.Status{LastLoop: now.Add(-aggregator.HeartbeatPeriod)}, now).Status)
+	assert.Equal(t, statusDown, checkAggregator(aggregator.Status{LastLoop: now.Add(-time.Minute)}, now).Status)
+}
+
+func TestReadyHandler(t *testing.T) {
+	_, a, stop := newGrpcClient(t, nil)
+	defer stop()
+
+	a.staleAfter = time.Minute
+
+	tables := []struct {
+		handler    func(*gin.Context) error
+		code       int
+		components []string
+	}{
+		{a.healthHandler, http.StatusOK, []string{"aggregator"}},
+		// Kafka is not started and the fake exchange is not connected
+		{a.readyHandler, http.StatusServiceUnavailable, []string{"kafka", "exchange:GDAX", "aggregator"}},
+	}
+
+	for _, table := range tables {
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+
+		assert.Nil(t, table.handler(c))
+		assert.Equal(t, table.code, recorder.Code)
+
+		out := &HealthOut{}
+		assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), out))
+
+		names := []string{}
+		for _, component := range out.Components {
+			names = append(names, component.Name)
+		}
+
+		assert.Equal(t, table.components, names)
+	}
+}
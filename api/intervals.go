@@ -0,0 +1,133 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/fberrez/romantic-aggregator/aggregator"
+	"github.com/fberrez/romantic-aggregator/currency"
+	"github.com/gin-gonic/gin"
+	"github.com/juju/errors"
+)
+
+// Body of PUT /v1/intervals/{base}/{target}
+type PairIntervalsIn struct {
+	Base   string `path:"base" validate:"required"`
+	Target string `path:"target" validate:"required"`
+	// Aggregation intervals of the currency pair (ex: ["10s", "1m"]),
+	// the default interval if empty
+	Intervals []string `json:"intervals"`
+}
+
+// Answer to PUT /v1/intervals/{base}/{target}
+type PairIntervalsOut struct {
+	// Currency pair (ex: BTC-USD)
+	Pair string `json:"pair"`
+	// Aggregation intervals of the currency pair, empty if it uses the default interval
+	Intervals []string `json:"intervals"`
+}
+
+// Answer to GET /v1/intervals
+type IntervalsOut struct {
+	// Interval of the currency pairs which do not have their own intervals
+	Interval string `json:"interval"`
+	// Intervals of the other currency pairs (ex: {"BTC-USD": ["10s"]})
+	Pairs map[currency.Pair][]aggregator.Interval `json:"pairs"`
+}
+
+// Handles GET requests sent to /v1/intervals
+func (a *Api) intervalsHandler(c *gin.Context) error {
+	status := a.aggregator.Status()
+
+	c.JSON(http.StatusOK, &IntervalsOut{
+		Interval: status.Interval.String(),
+		Pairs:    status.Pairs,
+	})
+
+	return nil
+}
+
+// Handles PUT requests sent to /v1/intervals/{base}/{target}
+func (a *Api) pairIntervalsHandler(c *gin.Context, in *PairIntervalsIn) error {
+	out, err := a.setPairIntervals(in.Base, in.Target, in.Intervals)
+
+	if err != nil {
+		return err
+	}
+
+	c.JSON(http.StatusOK, out)
+	return nil
+}
+
+// Sets and saves the aggregation intervals of a subscribed currency pair.
+// Shared by the HTTP and gRPC transports.
+func (a *Api) setPairIntervals(base string, target string, values []string) (*PairIntervalsOut, error) {
+	pair, err := currency.FindCurrencyPair(base, target)
+
+	if err != nil {
+		return nil, err
+	}
+
+	intervals, err := parseIntervals(values)
+
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+
+	for _, interval := range intervals {
+		names = append(names, interval.String())
+	}
+
+	if err := a.saveIntervals(pair, names); err != nil {
+		return nil, err
+	}
+
+	a.aggregator.SetPairIntervals(pair, intervals)
+
+	return &PairIntervalsOut{Pair: pair.String(), Intervals: names}, nil
+}
+
+// Saves the intervals of a subscribed currency pair in the store.
+// The intervals of a currency pair which is being subscribed
+// are saved once an exchange confirms it (see saveConfirmed).
+func (a *Api) saveIntervals(pair currency.Pair, intervals []string) error {
+	a.intervalsMutex.Lock()
+	defer a.intervalsMutex.Unlock()
+
+	if !a.store.Has(pair.Base, pair.Quote) {
+		if !a.FetcherGroup.Registry.Pending(pair.String()) {
+			return errors.NotFoundf("subscription to %s", pair)
+		}
+
+		a.pendingIntervals[pair] = intervals
+		return nil
+	}
+
+	if err := a.store.SetIntervals(pair.Base, pair.Quote, intervals); err != nil {
+		log.WithField("error", err).Error("Cannot save the intervals")
+	}
+
+	return nil
+}
+
+// Parses the intervals, ignoring the duplicates
+func parseIntervals(values []string) ([]aggregator.Interval, error) {
+	intervals := []aggregator.Interval{}
+	seen := map[aggregator.Interval]bool{}
+
+	for _, value := range values {
+		interval, err := aggregator.ParseInterval(value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !seen[interval] {
+			seen[interval] = true
+			intervals = append(intervals, interval)
+		}
+	}
+
+	return intervals, nil
+}
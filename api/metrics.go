@@ -0,0 +1,81 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fberrez/romantic-aggregator/metrics"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// Route of the requests which do not match any route,
+	// so that unknown paths do not create new series
+	unmatchedRoute = "unmatched"
+
+	// Method of the requests which do not use a standard HTTP method
+	otherMethod = "other"
+)
+
+var (
+	// Methods counted under their own name
+	knownMethods = map[string]bool{
+		http.MethodGet:     true,
+		http.MethodHead:    true,
+		http.MethodPost:    true,
+		http.MethodPut:     true,
+		http.MethodPatch:   true,
+		http.MethodDelete:  true,
+		http.MethodConnect: true,
+		http.MethodOptions: true,
+		http.MethodTrace:   true,
+	}
+)
+
+// Counts the HTTP requests and measures their duration by route
+func instrument(c *gin.Context) {
+	start := time.Now()
+	c.Next()
+
+	route := c.FullPath()
+
+	if route == "" {
+		route = unmatchedRoute
+	}
+
+	method := c.Request.Method
+
+	if !knownMethods[method] {
+		method = otherMethod
+	}
+
+	metrics.HTTPRequests.WithLabelValues(method, route, strconv.Itoa(c.Writer.Status())).Inc()
+	metrics.HTTPDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+}
+
+// Counts the unary gRPC calls and measures their duration by method
+func instrumentUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	observeGrpc(info.FullMethod, start, err)
+
+	return resp, err
+}
+
+// Counts the streaming gRPC calls and measures their duration by method
+func instrumentStream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, stream)
+	observeGrpc(info.FullMethod, start, err)
+
+	return err
+}
+
+func observeGrpc(method string, start time.Time, err error) {
+	metrics.GRPCRequests.WithLabelValues(method, status.Code(err).String()).Inc()
+	metrics.GRPCDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+}
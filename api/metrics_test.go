@@ -0,0 +1,74 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fberrez/romantic-aggregator/config"
+	"github.com/fberrez/romantic-aggregator/metrics"
+	"github.com/fberrez/romantic-aggregator/rpc"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstrument(t *testing.T) {
+	engine := gin.New()
+	engine.Use(instrument)
+	engine.GET("/v1/tickers/:base/:target", func(c *gin.Context) { c.Status(http.StatusNotFound) })
+
+	tables := []struct {
+		method string
+		path   string
+		label  string
+		route  string
+		code   string
+	}{
+		{"GET", "/v1/tickers/BTC/USD", "GET", "/v1/tickers/:base/:target", "404"},
+		{"GET", "/v1/tickers/ETH/EUR", "GET", "/v1/tickers/:base/:target", "404"},
+		// Unknown paths share the same series
+		{"GET", "/random/path", "GET", unmatchedRoute, "404"},
+		// Unknown methods too
+		{"BREW", "/random/path", otherMethod, unmatchedRoute, "404"},
+		{"PROPFIND", "/v1/tickers/BTC/USD", otherMethod, unmatchedRoute, "404"},
+	}
+
+	for _, table := range tables {
+		counter := metrics.HTTPRequests.WithLabelValues(table.label, table.route, table.code)
+		before := testutil.ToFloat64(counter)
+
+		engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(table.method, table.path, nil))
+
+		assert.Equal(t, before+1, testutil.ToFloat64(counter))
+	}
+}
+
+func TestInstrumentGrpc(t *testing.T) {
+	client, _, stop := newGrpcClient(t, nil)
+	defer stop()
+
+	counter := metrics.GRPCRequests.WithLabelValues(rpc.Aggregator_SetIntervals_FullMethodName, "InvalidArgument")
+	before := testutil.ToFloat64(counter)
+
+	_, err := client.SetIntervals(context.Background(), &rpc.SetIntervalsRequest{Interval: "unknown"})
+	assert.NotNil(t, err)
+
+	assert.Equal(t, before+1, testutil.ToFloat64(counter))
+}
+
+func TestMetricsRoute(t *testing.T) {
+	cfg := config.Default()
+	cfg.API.Keys = []string{"front:reader:k3y"}
+	a := Initiliaze(cfg)
+
+	// The metrics are scraped without credentials, unlike the API
+	recorder := httptest.NewRecorder()
+	a.Fizz.Engine().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	a.Fizz.Engine().ServeHTTP(recorder, httptest.NewRequest("GET", "/exchanges", nil))
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
@@ -0,0 +1,20 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/fberrez/romantic-aggregator/currency"
+	"github.com/gin-gonic/gin"
+)
+
+// Answer to GET /v1/pairs
+type PairsOut struct {
+	// Every known currency pair with the exchanges which list it
+	Pairs []currency.Listing `json:"pairs"`
+}
+
+// Handles GET requests sent to /v1/pairs
+func (a *Api) pairsHandler(c *gin.Context) error {
+	c.JSON(http.StatusOK, &PairsOut{Pairs: currency.Catalog.Listings()})
+	return nil
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/fberrez/romantic-aggregator/aggregator"
+	"github.com/fberrez/romantic-aggregator/config"
+	"github.com/fberrez/romantic-aggregator/rates"
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// Path of GET /v1/rates/{base}/{quote}
+type RateIn struct {
+	Base  string `path:"base" validate:"required"`
+	Quote string `path:"quote" validate:"required"`
+}
+
+// Body of POST /v1/portfolio/value
+type PortfolioIn struct {
+	// Amount of each asset (ex: {"BTC": "0.5", "EUR": "1000"})
+	Holdings map[string]decimal.Decimal `json:"holdings" validate:"required"`
+
+	// Reporting currency (ex: EUR)
+	Currency string `json:"currency" validate:"required"`
+}
+
+// Initializes the cross rates computed from the last tickers of the aggregator,
+// and the fiat conversion table if a file is set
+func InitializeRates(cache *aggregator.Cache, settings config.Rates) *rates.Service {
+	var provider rates.Provider
+
+	if settings.FiatFile != "" {
+		provider = &rates.FileProvider{Path: settings.FiatFile}
+	}
+
+	log.WithFields(logrus.Fields{"bridges": settings.Bridges, "fiat_file": settings.FiatFile}).Debug("Rates initialized")
+
+	return rates.New(cache, settings.Bridges, provider)
+}
+
+// Handles GET requests sent to /v1/rates/{base}/{quote}
+func (a *Api) rateHandler(c *gin.Context, in *RateIn) error {
+	rate, err := a.rates.Rate(in.Base, in.Quote)
+
+	if err != nil {
+		return err
+	}
+
+	c.JSON(http.StatusOK, rate)
+	return nil
+}
+
+// Handles POST requests sent to /v1/portfolio/value
+func (a *Api) portfolioHandler(c *gin.Context, in *PortfolioIn) error {
+	valuation, err := a.rates.Value(in.Holdings, in.Currency)
+
+	if err != nil {
+		return err
+	}
+
+	c.JSON(http.StatusOK, valuation)
+	return nil
+}
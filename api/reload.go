@@ -0,0 +1,212 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/fberrez/romantic-aggregator/aggregator"
+	"github.com/fberrez/romantic-aggregator/config"
+	"github.com/fberrez/romantic-aggregator/currency"
+	"github.com/fberrez/romantic-aggregator/exchange"
+	"github.com/fberrez/romantic-aggregator/kafka"
+	"github.com/fberrez/romantic-aggregator/state"
+	"github.com/gin-gonic/gin"
+	"github.com/juju/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Answer to POST /v1/admin/reload
+type ReloadOut struct {
+	*config.Changes
+
+	// Changes which could not be applied, the previous settings are kept
+	Errors []string `json:"errors,omitempty"`
+}
+
+// Handles POST requests sent to /v1/admin/reload
+func (a *Api) reloadHandler(c *gin.Context) error {
+	out, err := a.Reload()
+
+	if err != nil {
+		return err
+	}
+
+	c.JSON(http.StatusOK, out)
+	return nil
+}
+
+// Loads the configuration again and applies its changes in place:
+// the logging, the interval bounds, the Kafka producer, the exchanges,
+// the initial subscriptions and the default interval.
+// The other sections are only applied after a restart.
+// Returns a NotValid error and keeps the current configuration if the new one is not valid.
+func (a *Api) Reload() (*ReloadOut, error) {
+	a.reloadMutex.Lock()
+	defer a.reloadMutex.Unlock()
+
+	next, err := a.config.Reload()
+
+	if err != nil {
+		return nil, errors.NewNotValid(err, "")
+	}
+
+	changes := config.Compare(a.config, next)
+	out := &ReloadOut{Changes: changes, Errors: []string{}}
+
+	if changes.Logging {
+		next.Logging.Apply()
+	}
+
+	if changes.IntervalBounds {
+		if err := aggregator.SetIntervalBounds(next.Intervals.Min, next.Intervals.Max); err != nil {
+			out.Errors = append(out.Errors, fmt.Sprintf("intervals: %s", err))
+		}
+	}
+
+	if changes.Kafka {
+		if err := a.replaceProducer(next.Sinks.Kafka); err != nil {
+			out.Errors = append(out.Errors, fmt.Sprintf("sinks.kafka: %s", err))
+		}
+	}
+
+	for _, name := range changes.RemovedExchanges {
+		if err := a.FetcherGroup.Remove(name); err != nil && !errors.IsNotFound(err) {
+			out.Errors = append(out.Errors, fmt.Sprintf("exchanges.%s: %s", strings.ToLower(name), err))
+		}
+	}
+
+	// The exchanges whose settings have changed reconnect with the new ones
+	for _, name := range changes.ChangedExchanges {
+		if err := a.FetcherGroup.Remove(name); err != nil && !errors.IsNotFound(err) {
+			out.Errors = append(out.Errors, fmt.Sprintf("exchanges.%s: %s", strings.ToLower(name), err))
+			continue
+		}
+
+		out.Errors = append(out.Errors, a.addExchange(name, next.Exchange(name))...)
+	}
+
+	for _, name := range changes.AddedExchanges {
+		out.Errors = append(out.Errors, a.addExchange(name, next.Exchange(name))...)
+	}
+
+	out.Errors = append(out.Errors, a.unsubscribe(changes.RemovedSubscriptions)...)
+
+	if len(changes.AddedSubscriptions) > 0 {
+		subscriptions, err := state.ParseSubscriptions(strings.Join(changes.AddedSubscriptions, ","))
+
+		if err != nil {
+			out.Errors = append(out.Errors, fmt.Sprintf("subscriptions: %s", err))
+		} else {
+			for _, failure := range a.restoreSubscriptions(subscriptions) {
+				out.Errors = append(out.Errors, "subscriptions: "+failure)
+			}
+		}
+	}
+
+	if changes.Interval && next.Intervals.Default != "" {
+		if _, err := a.setInterval(next.Intervals.Default); err != nil {
+			out.Errors = append(out.Errors, fmt.Sprintf("intervals.default: %s", err))
+		}
+	}
+
+	for _, section := range changes.RestartRequired {
+		log.WithField("section", section).Warning("The configuration has changed, restart to apply it")
+	}
+
+	// The sections which require a restart keep running with their current values
+	a.config = config.Applied(a.config, next)
+
+	log.WithFields(logrus.Fields{"changes": changes, "errors": out.Errors}).Info("Configuration reloaded")
+
+	return out, nil
+}
+
+// Applies the settings of an exchange and starts it.
+// It subscribes again to its currency pairs once added (see resubscribe).
+func (a *Api) addExchange(name string, settings *config.Exchange) []string {
+	configureExchange(name, settings)
+
+	if err := a.FetcherGroup.Add(name); err != nil {
+		return []string{fmt.Sprintf("exchanges.%s: %s", strings.ToLower(name), err)}
+	}
+
+	return nil
+}
+
+// Unsubscribes from the initial subscriptions which have been removed
+// from the configuration (ex: BTC-USD, ETH-EUR@GDAX).
+// Returns the subscriptions which failed.
+func (a *Api) unsubscribe(values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+
+	subscriptions, err := state.ParseSubscriptions(strings.Join(values, ","))
+
+	if err != nil {
+		return []string{fmt.Sprintf("subscriptions: %s", err)}
+	}
+
+	failed := []string{}
+
+	for _, sub := range subscriptions {
+		pair, err := currency.FindCurrencyPair(sub.Base, sub.Target)
+
+		if err != nil || !a.store.Has(pair.Base, pair.Quote) {
+			continue
+		}
+
+		request, err := a.FetcherGroup.SendMessage(exchange.Unsubscribe, currency.CurrencySlice{pair}, []string{"ticker"}, sub.Exchanges)
+
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("subscriptions: %s: %s", pair, err))
+			continue
+		}
+
+		a.saveUnsubscriptions(currency.CurrencySlice{pair}, request)
+	}
+
+	return failed
+}
+
+// Replaces the Kafka producer by one using the new settings.
+// The current producer is kept if the new one cannot connect.
+func (a *Api) replaceProducer(settings config.Kafka) error {
+	producer, err := newProducer(settings)
+
+	if err != nil {
+		return err
+	}
+
+	current := a.kafkaProducer()
+
+	// The aggregator keeps sending its messages to the same channel
+	producer.Channel = current.Channel
+
+	if err := current.Stop(); err != nil {
+		log.WithField("error", err).Error("Closing the previous Kafka producer")
+	}
+
+	a.producerMutex.Lock()
+	a.producer = producer
+	a.producerMutex.Unlock()
+
+	// The shutdown waits for the new producer like for the first one
+	a.waitGroup.Add(1)
+
+	go func() {
+		defer a.waitGroup.Done()
+		producer.Start()
+	}()
+
+	return nil
+}
+
+// Returns the current Kafka producer
+func (a *Api) kafkaProducer() *kafka.AggregatorProducer {
+	a.producerMutex.RLock()
+	defer a.producerMutex.RUnlock()
+
+	return a.producer
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fberrez/romantic-aggregator/config"
+	"github.com/juju/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reload")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "romantic.yaml")
+	assert.Nil(t, ioutil.WriteFile(path, []byte("sinks:\n  kafka:\n    address: kafka:9092\n"), 0644))
+
+	cfg, err := config.Load([]string{"-config", path})
+	assert.Nil(t, err)
+
+	a := &Api{config: cfg}
+
+	// An invalid configuration is rejected and the current one is kept
+	assert.Nil(t, ioutil.WriteFile(path, []byte("sinks:\n  kafka:\n    address: \"\"\n"), 0644))
+	_, err = a.Reload()
+	assert.True(t, errors.IsNotValid(err))
+	assert.Equal(t, cfg, a.config)
+
+	// The port is only applied after a restart
+	assert.Nil(t, ioutil.WriteFile(path, []byte("api:\n  port: \"8080\"\nsinks:\n  kafka:\n    address: kafka:9092\n"), 0644))
+	out, err := a.Reload()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"api"}, out.RestartRequired)
+	assert.Empty(t, out.Errors)
+	assert.Equal(t, cfg.API.Port, a.config.API.Port)
+
+	// The port still differs from the one running on the next reload
+	out, err = a.Reload()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"api"}, out.RestartRequired)
+	assert.Equal(t, cfg.API.Port, a.config.API.Port)
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fberrez/romantic-aggregator/currency"
+	"github.com/fberrez/romantic-aggregator/exchange"
+	"github.com/fberrez/romantic-aggregator/state"
+	"github.com/fberrez/romantic-aggregator/subscription"
+	"github.com/sirupsen/logrus"
+)
+
+// Opens the store which keeps the desired subscriptions and interval in `path`
+func InitializeStore(path string) *state.Store {
+	store, err := state.Open(path)
+
+	if err != nil {
+		log.WithField("error", err).Fatal("Cannot open the state file")
+	}
+
+	return store
+}
+
+// Restores the subscriptions and the interval saved in the store.
+// When no state has been saved yet, the initial subscriptions
+// (ex: BTC-USD, ETH-EUR@GDAX) and interval (ex: 5m) are read from the configuration.
+func (a *Api) restore() {
+	desired := a.store.State()
+
+	if !a.store.Exists() {
+		subscriptions, err := state.ParseSubscriptions(strings.Join(a.config.Subscriptions, ","))
+
+		if err != nil {
+			log.WithField("error", err).Fatal("Cannot read the initial subscriptions")
+		}
+
+		desired = state.State{Subscriptions: subscriptions, Interval: a.config.Intervals.Default}
+	}
+
+	a.restoreSubscriptions(desired.Subscriptions)
+
+	if desired.Interval != "" {
+		if _, err := a.setInterval(desired.Interval); err != nil {
+			log.WithField("error", err).Error("Cannot restore interval")
+		}
+	}
+}
+
+// Subscribes to the currency pairs of `subscriptions` and restores their intervals.
+// Returns the subscriptions which failed.
+func (a *Api) restoreSubscriptions(subscriptions []state.Subscription) []string {
+	failed := []string{}
+
+	for _, sub := range subscriptions {
+		fields := logrus.Fields{"base": sub.Base, "target": sub.Target, "exchanges": sub.Exchanges}
+		pair, err := currency.FindCurrencyPair(sub.Base, sub.Target)
+
+		if err != nil {
+			log.WithFields(fields).WithField("error", err).Error("Cannot restore subscription")
+			failed = append(failed, fmt.Sprintf("%s-%s: %s", sub.Base, sub.Target, err))
+			continue
+		}
+
+		// The subscription is saved once an exchange confirms it
+		if _, err := a.FetcherGroup.SendMessage(exchange.Subscribe, currency.CurrencySlice{pair}, []string{"ticker"}, sub.Exchanges); err != nil {
+			log.WithFields(fields).WithField("error", err).Error("Cannot restore subscription")
+			failed = append(failed, fmt.Sprintf("%s: %s", pair, err))
+			continue
+		}
+
+		log.WithFields(fields).Info("Subscription restored")
+
+		if len(sub.Intervals) > 0 {
+			if _, err := a.setPairIntervals(sub.Base, sub.Target, sub.Intervals); err != nil {
+				log.WithFields(fields).WithField("error", err).Error("Cannot restore the intervals")
+			}
+		}
+	}
+
+	return failed
+}
+
+// Saves the subscriptions in the store once the exchanges confirm them,
+// so that the rejected ones are not restored
+func (a *Api) saveConfirmed(action subscription.Action, entry exchange.Entry) {
+	if action != subscription.Subscribe || entry.Status != subscription.Confirmed {
+		return
+	}
+
+	pair, err := currency.ParseCurrencyPair(entry.Pair)
+
+	if err != nil {
+		log.WithFields(logrus.Fields{"pair": entry.Pair, "error": err}).Error("Cannot save the subscription")
+		return
+	}
+
+	a.intervalsMutex.Lock()
+	defer a.intervalsMutex.Unlock()
+
+	if err := a.store.Subscribe(pair.Base, pair.Quote, []string{entry.Exchange}); err != nil {
+		log.WithField("error", err).Error("Cannot save the subscriptions")
+		return
+	}
+
+	// The intervals set while the currency pair was being subscribed
+	if intervals, ok := a.pendingIntervals[pair]; ok {
+		delete(a.pendingIntervals, pair)
+
+		if err := a.store.SetIntervals(pair.Base, pair.Quote, intervals); err != nil {
+			log.WithField("error", err).Error("Cannot save the intervals")
+		}
+	}
+}
+
+// Removes the exchanges unsubscribed by the request from the store,
+// without waiting for the exchanges, so that they are not restored anyway.
+// Each currency pair is removed from the exchanges which list it.
+func (a *Api) saveUnsubscriptions(pairs currency.CurrencySlice, request *exchange.Request) {
+	for _, pair := range pairs {
+		exchanges := []string{}
+
+		for _, name := range request.Exchanges {
+			if a.FetcherGroup.Pairs(name).Contains(pair) {
+				exchanges = append(exchanges, name)
+			}
+		}
+
+		if err := a.store.Unsubscribe(pair.Base, pair.Quote, exchanges); err != nil {
+			log.WithField("error", err).Error("Cannot save the subscriptions")
+		}
+
+		// The intervals of a currency pair are forgotten with its last subscription
+		if !a.store.Has(pair.Base, pair.Quote) {
+			a.intervalsMutex.Lock()
+			delete(a.pendingIntervals, pair)
+			a.intervalsMutex.Unlock()
+
+			a.aggregator.SetPairIntervals(pair, nil)
+		}
+	}
+}
+
+// Saves the aggregation interval in the store
+func (a *Api) saveInterval(interval string) {
+	if err := a.store.SetInterval(interval); err != nil {
+		log.WithField("error", err).Error("Cannot save the interval")
+	}
+}
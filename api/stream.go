@@ -0,0 +1,249 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fberrez/romantic-aggregator/aggregator"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/juju/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Filters of /v1/stream and /v1/ws, as comma-separated lists
+type StreamIn struct {
+	// Exchanges of the tickers (ex: GDAX,Bitfinex)
+	Exchanges string `query:"exchanges"`
+	// Currency pairs (ex: BTC-USD,ETHEUR)
+	Symbols string `query:"symbols"`
+	// Intervals of the aggregates (ex: 1m,5m)
+	Intervals string `query:"intervals"`
+	// Types of records (ticker, aggregate)
+	Types string `query:"types"`
+}
+
+// Filter sent by a websocket client to replace its filter
+type StreamFilterIn struct {
+	Exchanges []string `json:"exchanges"`
+	Symbols   []string `json:"symbols"`
+	Intervals []string `json:"intervals"`
+	Types     []string `json:"types"`
+}
+
+// Notice sent to a client which is too slow to receive every record
+type DroppedOut struct {
+	Type    string `json:"type"`
+	Dropped uint64 `json:"dropped"`
+}
+
+const (
+	// Number of records buffered for each client
+	streamBuffer = 256
+
+	// Maximum duration of a websocket write
+	writeWait = 10 * time.Second
+
+	// Interval between two websocket pings
+	pingPeriod = 30 * time.Second
+)
+
+var (
+	upgrader = websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		// The front-end may be served from another origin
+		CheckOrigin: func(r *http.Request) bool { return true },
+	}
+)
+
+// Handles requests sent to /v1/stream.
+// Sends the records matching the filters as Server-Sent Events
+func (a *Api) sseHandler(c *gin.Context, in *StreamIn) error {
+	filter, err := parseFilter(StreamFilterIn{
+		Exchanges: split(in.Exchanges),
+		Symbols:   split(in.Symbols),
+		Intervals: split(in.Intervals),
+		Types:     split(in.Types),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	stream := a.aggregator.Stream.Add(filter, streamBuffer)
+	defer a.aggregator.Stream.Remove(stream)
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	var dropped uint64
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case record, ok := <-stream.C:
+			if !ok {
+				return false
+			}
+
+			if current := stream.Dropped(); current > dropped {
+				dropped = current
+				c.SSEvent("dropped", &DroppedOut{Type: "dropped", Dropped: dropped})
+			}
+
+			c.SSEvent(string(record.Type), record)
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+
+	return nil
+}
+
+// Handles requests sent to /v1/ws.
+// Sends the records matching the filters as websocket JSON messages.
+// The client can replace its filter by sending a StreamFilterIn.
+func (a *Api) wsHandler(c *gin.Context, in *StreamIn) error {
+	filter, err := parseFilter(StreamFilterIn{
+		Exchanges: split(in.Exchanges),
+		Symbols:   split(in.Symbols),
+		Intervals: split(in.Intervals),
+		Types:     split(in.Types),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+
+	if err != nil {
+		// The upgrader has already answered the client
+		log.WithField("error", err).Debug("Upgrading to websocket")
+		return nil
+	}
+
+	defer conn.Close()
+
+	stream := a.aggregator.Stream.Add(filter, streamBuffer)
+	defer a.aggregator.Stream.Remove(stream)
+
+	closed := make(chan struct{})
+	go readFilters(conn, stream, closed)
+
+	ticker := time.NewTicker(pingPeriod)
+	defer ticker.Stop()
+
+	var dropped uint64
+
+	for {
+		select {
+		case record, ok := <-stream.C:
+			if !ok {
+				return nil
+			}
+
+			if current := stream.Dropped(); current > dropped {
+				dropped = current
+
+				if err := writeJSON(conn, &DroppedOut{Type: "dropped", Dropped: dropped}); err != nil {
+					return nil
+				}
+			}
+
+			if err := writeJSON(conn, record); err != nil {
+				return nil
+			}
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return nil
+			}
+		case <-closed:
+			return nil
+		}
+	}
+}
+
+// Reads the filters sent by a websocket client until the connection is closed
+func readFilters(conn *websocket.Conn, stream *aggregator.Stream, closed chan struct{}) {
+	defer close(closed)
+
+	for {
+		in := StreamFilterIn{}
+
+		if err := conn.ReadJSON(&in); err != nil {
+			if _, ok := err.(*websocket.CloseError); ok {
+				return
+			}
+
+			// Anything else than a JSON message ends the connection
+			log.WithField("error", err).Debug("Reading websocket filter")
+			return
+		}
+
+		filter, err := parseFilter(in)
+
+		if err != nil {
+			log.WithFields(logrus.Fields{"error": err, "filter": in}).Debug("Invalid websocket filter")
+			continue
+		}
+
+		stream.SetFilter(filter)
+	}
+}
+
+// Writes a JSON message to a websocket client
+func writeJSON(conn *websocket.Conn, v interface{}) error {
+	conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return conn.WriteJSON(v)
+}
+
+// Converts the filters of a client to an aggregator filter
+func parseFilter(in StreamFilterIn) (aggregator.Filter, error) {
+	filter := aggregator.Filter{Exchanges: in.Exchanges}
+
+	for _, symbol := range in.Symbols {
+		filter.Symbols = append(filter.Symbols, strings.ToUpper(strings.Replace(symbol, "-", "", 1)))
+	}
+
+	for _, raw := range in.Intervals {
+		interval, err := aggregator.ParseInterval(raw)
+
+		if err != nil {
+			return filter, err
+		}
+
+		filter.Intervals = append(filter.Intervals, interval)
+	}
+
+	for _, raw := range in.Types {
+		recordType := aggregator.RecordType(raw)
+
+		if recordType != aggregator.TickerRecord && recordType != aggregator.AggregateRecord {
+			return filter, errors.NotValidf("record type %s", raw)
+		}
+
+		filter.Types = append(filter.Types, recordType)
+	}
+
+	return filter, nil
+}
+
+// Splits a comma-separated list, ignoring the empty values
+func split(value string) []string {
+	values := []string{}
+
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+
+	return values
+}
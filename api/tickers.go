@@ -0,0 +1,73 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/fberrez/romantic-aggregator/aggregator"
+	"github.com/fberrez/romantic-aggregator/currency"
+	"github.com/gin-gonic/gin"
+	"github.com/juju/errors"
+)
+
+type TickerIn struct {
+	Base   string `path:"base" validate:"required"`
+	Target string `path:"target" validate:"required"`
+}
+
+type ExchangeTickersIn struct {
+	Exchange string `path:"exchange" validate:"required"`
+}
+
+// Last values received from the exchanges and sent to Kafka
+type TickersOut struct {
+	// Last ticker received from each exchange
+	Tickers []aggregator.CachedTicker `json:"tickers"`
+	// Last aggregate sent to Kafka for each interval
+	Aggregates []aggregator.CachedAggregate `json:"aggregates"`
+}
+
+// Handles requests sent to /v1/tickers.
+// Returns the last values of every currency pair
+func (a *Api) tickersHandler(c *gin.Context) error {
+	c.JSON(http.StatusOK, &TickersOut{
+		Tickers:    a.aggregator.Cache.Tickers("", currency.Pair{}),
+		Aggregates: a.aggregator.Cache.Aggregates(currency.Pair{}),
+	})
+
+	return nil
+}
+
+// Handles requests sent to /v1/tickers/{base}/{target}.
+// Returns the last values of one currency pair
+func (a *Api) tickerHandler(c *gin.Context, in *TickerIn) error {
+	pair, err := currency.FindCurrencyPair(in.Base, in.Target)
+
+	if err != nil {
+		return err
+	}
+
+	out := &TickersOut{
+		Tickers:    a.aggregator.Cache.Tickers("", pair),
+		Aggregates: a.aggregator.Cache.Aggregates(pair),
+	}
+
+	if len(out.Tickers) == 0 && len(out.Aggregates) == 0 {
+		return errors.NotFoundf("ticker of %s", pair)
+	}
+
+	c.JSON(http.StatusOK, out)
+	return nil
+}
+
+// Handles requests sent to /v1/exchanges/{exchange}/tickers.
+// Returns the last tickers received from one exchange
+func (a *Api) exchangeTickersHandler(c *gin.Context, in *ExchangeTickersIn) error {
+	names, err := a.FetcherGroup.Targets(nil, []string{in.Exchange})
+
+	if err != nil {
+		return err
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tickers": a.aggregator.Cache.Tickers(names[0], currency.Pair{})})
+	return nil
+}
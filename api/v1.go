@@ -0,0 +1,509 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/fberrez/romantic-aggregator/aggregator"
+	"github.com/fberrez/romantic-aggregator/currency"
+	"github.com/fberrez/romantic-aggregator/exchange"
+	"github.com/fberrez/romantic-aggregator/subscription"
+	"github.com/gin-gonic/gin"
+	"github.com/juju/errors"
+	"github.com/loopfz/gadgeto/tonic"
+	"github.com/wI2L/fizz"
+)
+
+// Body of POST /v1/subscriptions
+type SubscribeBody struct {
+	// Currency pairs (ex: ["BTC-USD", "ETH-EUR"])
+	Pairs []string `json:"pairs" validate:"required"`
+	// Channels (ex: ["ticker"]), ticker by default
+	Channels []string `json:"channels"`
+	// Exchanges (ex: ["GDAX"]), every exchange listing the currency pairs by default
+	Exchanges []string `json:"exchanges"`
+	// Aggregation interval (ex: 90s, 5m, 2h30m, 1d, 1w, 1M), unchanged if empty
+	Interval string `json:"interval"`
+	// Aggregation intervals of these currency pairs (ex: ["10s", "1m"]), unchanged if empty
+	Intervals []string `json:"intervals"`
+	// Waits for the acknowledgement of the exchanges before answering
+	Wait bool `query:"wait" default:"true"`
+}
+
+// Body of DELETE /v1/subscriptions
+type UnsubscribeBody struct {
+	// Currency pairs (ex: ["BTC-USD", "ETH-EUR"])
+	Pairs []string `json:"pairs" validate:"required"`
+	// Channels (ex: ["ticker"]), ticker by default
+	Channels []string `json:"channels"`
+	// Exchanges (ex: ["GDAX"]), every exchange listing the currency pairs by default
+	Exchanges []string `json:"exchanges"`
+	// Waits for the acknowledgement of the exchanges before answering
+	Wait bool `query:"wait" default:"true"`
+}
+
+// Body of PUT /v1/interval
+type IntervalIn struct {
+	// Aggregation interval (ex: 90s, 5m, 2h30m, 1d, 1w, 1M)
+	Interval string `json:"interval" validate:"required"`
+}
+
+// Answer to a (un)subscription sent to /v1/subscriptions
+type SubscriptionsOut struct {
+	// Id of the request, which can be consulted on /v1/requests/{id}
+	Request string `json:"request"`
+	// Confirmed once every exchange has confirmed the request
+	Status subscription.Status `json:"status"`
+	// Outcome on each exchange
+	Outcomes []exchange.Outcome `json:"outcomes"`
+}
+
+// Answer to PUT /v1/interval
+type IntervalOut struct {
+	Interval string `json:"interval"`
+}
+
+// Body of every error answered by the API
+type ErrorOut struct {
+	Error ErrorBody `json:"error"`
+}
+
+type ErrorBody struct {
+	// Kind of error (ex: not_found)
+	Code string `json:"code"`
+	// Description of the error
+	Message string `json:"message"`
+}
+
+var (
+	// Channels which can be subscribed
+	supportedChannels = []string{"ticker"}
+)
+
+// Registers the routes of the versioned API
+// `reader` and `admin` are the middlewares which check the role of the principal.
+func (a *Api) registerV1(f *fizz.Fizz, reader gin.HandlerFunc, admin gin.HandlerFunc) {
+	errors404 := fizz.Response("404", "Unknown currency pair, exchange or subscription", ErrorOut{}, nil)
+	errors400 := fizz.Response("400", "Invalid body", ErrorOut{}, nil)
+	errors401 := fizz.Response("401", "Missing or invalid credentials", ErrorOut{}, nil)
+	errors403 := fizz.Response("403", "The admin role is required", ErrorOut{}, nil)
+
+	v1 := f.Group("/v1", "v1", "Control API")
+
+	v1.POST("/subscriptions", []fizz.OperationOption{
+		fizz.ID("subscribe"),
+		fizz.Summary("Subscribe to currency pairs"),
+		fizz.Description("Sends the subscriptions to every targeted exchange and sets the aggregation interval."),
+		fizz.Response("201", "Every exchange confirmed the subscriptions", SubscriptionsOut{}, nil),
+		fizz.Response("202", "Some subscriptions are pending or rejected", SubscriptionsOut{}, nil),
+		fizz.Response("409", "Every subscription already exists", ErrorOut{}, nil),
+		errors400,
+		errors404,
+		errors401,
+		errors403,
+	}, a.audit, admin, tonic.Handler(a.v1SubscribeHandler, 202))
+
+	v1.DELETE("/subscriptions", []fizz.OperationOption{
+		fizz.ID("unsubscribe"),
+		fizz.Summary("Unsubscribe from currency pairs"),
+		fizz.Description("Sends the unsubscriptions to every targeted exchange."),
+		fizz.Response("200", "Every exchange confirmed the unsubscriptions", SubscriptionsOut{}, nil),
+		fizz.Response("202", "Some unsubscriptions are pending or rejected", SubscriptionsOut{}, nil),
+		errors400,
+		errors404,
+		errors401,
+		errors403,
+	}, a.audit, admin, tonic.Handler(a.v1UnsubscribeHandler, 202))
+
+	v1.GET("/subscriptions", []fizz.OperationOption{
+		fizz.ID("listSubscriptions"),
+		fizz.Summary("List the subscriptions of every exchange"),
+	}, reader, tonic.Handler(a.subscriptionsHandler, 200))
+
+	v1.GET("/subscriptions/:exchange", []fizz.OperationOption{
+		fizz.ID("listExchangeSubscriptions"),
+		fizz.Summary("List the subscriptions of an exchange"),
+		errors404,
+	}, reader, tonic.Handler(a.exchangeSubscriptionsHandler, 200))
+
+	v1.PUT("/interval", []fizz.OperationOption{
+		fizz.ID("setInterval"),
+		fizz.Summary("Set the aggregation interval"),
+		errors400,
+		errors401,
+		errors403,
+	}, a.audit, admin, tonic.Handler(a.v1IntervalHandler, 200))
+
+	v1.POST("/admin/reload", []fizz.OperationOption{
+		fizz.ID("reloadConfiguration"),
+		fizz.Summary("Load the configuration again and apply its changes"),
+		fizz.Description("The logging, the interval bounds, the Kafka producer, the exchanges, the initial subscriptions and the default interval are applied in place. The other changes require a restart."),
+		errors400,
+		errors401,
+		errors403,
+	}, a.audit, admin, tonic.Handler(a.reloadHandler, 200))
+
+	v1.GET("/intervals", []fizz.OperationOption{
+		fizz.ID("listIntervals"),
+		fizz.Summary("Get the default interval and the intervals of each currency pair"),
+	}, reader, tonic.Handler(a.intervalsHandler, 200))
+
+	v1.PUT("/intervals/:base/:target", []fizz.OperationOption{
+		fizz.ID("setPairIntervals"),
+		fizz.Summary("Set the aggregation intervals of a subscribed currency pair"),
+		fizz.Description("An empty list of intervals restores the default interval."),
+		errors400,
+		errors404,
+		errors401,
+		errors403,
+	}, a.audit, admin, tonic.Handler(a.pairIntervalsHandler, 200))
+
+	v1.GET("/requests/:id", []fizz.OperationOption{
+		fizz.ID("getRequest"),
+		fizz.Summary("Get the outcome of a (un)subscription on each exchange"),
+		errors404,
+	}, reader, tonic.Handler(a.requestHandler, 200))
+
+	v1.GET("/exchanges", []fizz.OperationOption{
+		fizz.ID("listExchanges"),
+		fizz.Summary("Get the state of each exchange"),
+	}, reader, tonic.Handler(a.exchangesHandler, 200))
+
+	v1.GET("/pairs", []fizz.OperationOption{
+		fizz.ID("listPairs"),
+		fizz.Summary("Get the known currency pairs and the exchanges which list them"),
+	}, reader, tonic.Handler(a.pairsHandler, 200))
+
+	v1.GET("/drivers", []fizz.OperationOption{
+		fizz.ID("listDrivers"),
+		fizz.Summary("Get the state of each exchange driver"),
+	}, reader, tonic.Handler(a.driversHandler, 200))
+
+	v1.POST("/drivers/:exchange/:action", []fizz.OperationOption{
+		fizz.ID("changeDriver"),
+		fizz.Summary("Start, stop or restart an exchange driver"),
+		fizz.Description("A stopped driver forgets its subscriptions, which are made again when it starts. A driver which fails to start is retried in the background."),
+		errors400,
+		errors404,
+		errors401,
+		errors403,
+		fizz.Response("409", "The driver is already running or stopped", ErrorOut{}, nil),
+	}, a.audit, admin, tonic.Handler(a.driverHandler, 200))
+
+	v1.GET("/exchanges/:exchange/tickers", []fizz.OperationOption{
+		fizz.ID("listExchangeTickers"),
+		fizz.Summary("Get the last tickers received from an exchange"),
+		errors404,
+	}, reader, tonic.Handler(a.exchangeTickersHandler, 200))
+
+	v1.GET("/stream", []fizz.OperationOption{
+		fizz.ID("stream"),
+		fizz.Summary("Stream the tickers and aggregates as Server-Sent Events"),
+		fizz.Description("Each event is named after the record type (ticker, aggregate). A dropped event tells how many records were dropped because the client was too slow."),
+		errors400,
+	}, reader, tonic.Handler(a.sseHandler, 200))
+
+	v1.GET("/ws", []fizz.OperationOption{
+		fizz.ID("websocket"),
+		fizz.Summary("Stream the tickers and aggregates through a websocket"),
+		fizz.Description("The client can replace its filters by sending them as a JSON message."),
+		errors400,
+	}, reader, tonic.Handler(a.wsHandler, 101))
+
+	v1.GET("/tickers", []fizz.OperationOption{
+		fizz.ID("listTickers"),
+		fizz.Summary("Get the last tickers and aggregates of every currency pair"),
+		fizz.Response("200", "Last values, with their age in seconds", TickersOut{}, nil),
+	}, reader, tonic.Handler(a.tickersHandler, 200))
+
+	v1.GET("/tickers/:base/:target", []fizz.OperationOption{
+		fizz.ID("getTicker"),
+		fizz.Summary("Get the last tickers and aggregates of a currency pair"),
+		fizz.Response("200", "Last values, with their age in seconds", TickersOut{}, nil),
+		errors404,
+	}, reader, tonic.Handler(a.tickerHandler, 200))
+
+	v1.GET("/rates/:base/:quote", []fizz.OperationOption{
+		fizz.ID("getRate"),
+		fizz.Summary("Get the rate of a currency in another one"),
+		fizz.Description("The rate comes from the last ticker of the currency pair or of its inverse. Otherwise it is synthesized through the bridge currencies, then converted with the fiat table, and its sources are listed."),
+		errors400,
+		fizz.Response("404", "No rate can be computed", ErrorOut{}, nil),
+	}, reader, tonic.Handler(a.rateHandler, 200))
+
+	v1.POST("/portfolio/value", []fizz.OperationOption{
+		fizz.ID("valuePortfolio"),
+		fizz.Summary("Get the value of a portfolio in a reporting currency"),
+		fizz.Description("The assets which cannot be converted are listed as missing."),
+		errors400,
+	}, reader, tonic.Handler(a.portfolioHandler, 200))
+}
+
+// Handles POST requests sent to /v1/subscriptions
+func (a *Api) v1SubscribeHandler(c *gin.Context, in *SubscribeBody) error {
+	out, err := a.subscribeMany(in)
+
+	if err != nil {
+		return err
+	}
+
+	status := http.StatusAccepted
+
+	if out.Status == subscription.Confirmed {
+		status = http.StatusCreated
+	}
+
+	c.JSON(status, out)
+	return nil
+}
+
+// Handles DELETE requests sent to /v1/subscriptions
+func (a *Api) v1UnsubscribeHandler(c *gin.Context, in *UnsubscribeBody) error {
+	out, err := a.unsubscribeMany(in)
+
+	if err != nil {
+		return err
+	}
+
+	status := http.StatusAccepted
+
+	if out.Status == subscription.Confirmed {
+		status = http.StatusOK
+	}
+
+	c.JSON(status, out)
+	return nil
+}
+
+// Handles PUT requests sent to /v1/interval
+func (a *Api) v1IntervalHandler(c *gin.Context, in *IntervalIn) error {
+	interval, err := a.setInterval(in.Interval)
+
+	if err != nil {
+		return err
+	}
+
+	c.JSON(http.StatusOK, &IntervalOut{Interval: interval.String()})
+	return nil
+}
+
+// Subscribes to the currency pairs and sets the aggregation interval.
+// Shared by the HTTP and gRPC transports.
+func (a *Api) subscribeMany(in *SubscribeBody) (*SubscriptionsOut, error) {
+	if in.Interval != "" {
+		if _, err := aggregator.ParseInterval(in.Interval); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := parseIntervals(in.Intervals); err != nil {
+		return nil, err
+	}
+
+	pairs, channels, targets, err := a.parseSubscriptions(in.Pairs, in.Channels, in.Exchanges)
+
+	if err != nil {
+		return nil, err
+	}
+
+	subscribed, total := a.countSubscribed(pairs, channels, targets)
+
+	switch {
+	case total == 0:
+		// None of the targeted exchanges lists the currency pairs
+		return nil, errors.NotFoundf("currency pairs %v on %v", in.Pairs, targets)
+	case subscribed == total:
+		return nil, errors.AlreadyExistsf("subscriptions to %v on %v", in.Pairs, targets)
+	}
+
+	request, err := a.FetcherGroup.SendMessage(exchange.Subscribe, pairs, channels, targets)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if in.Interval != "" {
+		if _, err := a.setInterval(in.Interval); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(in.Intervals) > 0 {
+		for _, pair := range pairs {
+			// The pairs which are not subscribed on any exchange are skipped
+			if _, err := a.setPairIntervals(pair.Base, pair.Quote, in.Intervals); err != nil && !errors.IsNotFound(err) {
+				return nil, err
+			}
+		}
+	}
+
+	if in.Wait {
+		request.Wait(ackWait)
+	}
+
+	return a.subscriptionsOut(request), nil
+}
+
+// Unsubscribes from the currency pairs.
+// Shared by the HTTP and gRPC transports.
+func (a *Api) unsubscribeMany(in *UnsubscribeBody) (*SubscriptionsOut, error) {
+	pairs, channels, targets, err := a.parseSubscriptions(in.Pairs, in.Channels, in.Exchanges)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if subscribed, _ := a.countSubscribed(pairs, channels, targets); subscribed == 0 {
+		return nil, errors.NotFoundf("subscriptions to %v on %v", in.Pairs, targets)
+	}
+
+	request, err := a.FetcherGroup.SendMessage(exchange.Unsubscribe, pairs, channels, targets)
+
+	if err != nil {
+		return nil, err
+	}
+
+	a.saveUnsubscriptions(pairs, request)
+
+	if in.Wait {
+		request.Wait(ackWait)
+	}
+
+	return a.subscriptionsOut(request), nil
+}
+
+// Sets and saves the aggregation interval (ex: 5m).
+// Shared by the HTTP and gRPC transports.
+func (a *Api) setInterval(value string) (aggregator.Interval, error) {
+	interval, err := aggregator.ParseInterval(value)
+
+	if err != nil {
+		return interval, err
+	}
+
+	a.aggregator.SetInterval(interval)
+	a.saveInterval(interval.String())
+
+	return interval, nil
+}
+
+// Validates the currency pairs, channels and exchanges of a (un)subscription.
+// Returns the targeted exchanges.
+func (a *Api) parseSubscriptions(rawPairs []string, channels []string, exchanges []string) (currency.CurrencySlice, []string, []string, error) {
+	if len(rawPairs) == 0 {
+		return nil, nil, nil, errors.NotValidf("empty list of currency pairs")
+	}
+
+	pairs := currency.CurrencySlice{}
+
+	for _, raw := range rawPairs {
+		pair, err := currency.ParseCurrencyPair(raw)
+
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		if !pairs.Contains(pair) {
+			pairs = append(pairs, pair)
+		}
+	}
+
+	if len(channels) == 0 {
+		channels = supportedChannels
+	}
+
+	for _, channel := range channels {
+		if !contains(supportedChannels, channel) {
+			return nil, nil, nil, errors.NotValidf("channel %s", channel)
+		}
+	}
+
+	targets, err := a.FetcherGroup.Targets(pairs, exchanges)
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return pairs, channels, targets, nil
+}
+
+// Returns the number of (exchange, currency pair, channel) which are subscribed,
+// among the `total` which are listed by the targeted exchanges
+func (a *Api) countSubscribed(pairs currency.CurrencySlice, channels []string, targets []string) (subscribed int, total int) {
+	for _, name := range targets {
+		for _, pair := range a.FetcherGroup.Pairs(name).Intersect(pairs) {
+			for _, channel := range channels {
+				total++
+
+				if a.FetcherGroup.Registry.Has(name, pair.String(), channel) {
+					subscribed++
+				}
+			}
+		}
+	}
+
+	return subscribed, total
+}
+
+// Builds the answer to a (un)subscription
+func (a *Api) subscriptionsOut(request *exchange.Request) *SubscriptionsOut {
+	out := &SubscriptionsOut{
+		Request:  request.Id,
+		Status:   subscription.Confirmed,
+		Outcomes: a.FetcherGroup.Tracker.Outcomes(request),
+	}
+
+	// A rejection prevails over a pending outcome,
+	// which prevails over a confirmation
+	for _, outcome := range out.Outcomes {
+		switch {
+		case outcome.Status == subscription.Rejected:
+			out.Status = subscription.Rejected
+		case outcome.Status == subscription.Pending && out.Status == subscription.Confirmed:
+			out.Status = subscription.Pending
+		}
+	}
+
+	return out
+}
+
+// Answers every error with its matching status code and an ErrorOut body.
+// The errors which are not expected are internal ones.
+func errorHook(c *gin.Context, err error) (int, interface{}) {
+	status, code := http.StatusInternalServerError, "internal"
+
+	switch {
+	case isBindError(err):
+		status, code = http.StatusBadRequest, "bad_request"
+	case errors.IsNotFound(err):
+		status, code = http.StatusNotFound, "not_found"
+	case errors.IsAlreadyExists(err):
+		status, code = http.StatusConflict, "already_exists"
+	case errors.IsNotValid(err):
+		status, code = http.StatusBadRequest, "not_valid"
+	case errors.IsUnauthorized(err):
+		status, code = http.StatusUnauthorized, "unauthorized"
+	}
+
+	return status, &ErrorOut{Error: ErrorBody{Code: code, Message: err.Error()}}
+}
+
+// Returns true if the input of a request cannot be bound or is not valid
+func isBindError(err error) bool {
+	switch errors.Cause(err).(type) {
+	case tonic.BindError, *tonic.BindError:
+		return true
+	}
+
+	return false
+}
+
+// Returns true if `values` contains `value`
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fberrez/romantic-aggregator/exchange"
+	"github.com/fberrez/romantic-aggregator/subscription"
+	"github.com/gin-gonic/gin"
+	"github.com/juju/errors"
+	"github.com/loopfz/gadgeto/tonic"
+	"github.com/stretchr/testify/assert"
+	"github.com/wI2L/fizz"
+)
+
+// Returns the versioned API of `a`, without authentication
+func newV1Engine(a *Api) *fizz.Fizz {
+	tonic.SetErrorHook(errorHook)
+
+	f := fizz.NewFromEngine(gin.New())
+	allow := func(c *gin.Context) { c.Next() }
+	a.registerV1(f, allow, allow)
+
+	return f
+}
+
+func TestV1Subscriptions(t *testing.T) {
+	a, stop := newTestApi(t, map[string]exchange.Fetcher{
+		"GDAX":   &fakeFetcher{acks: make(chan subscription.Ack, 16)},
+		"Silent": &fakeFetcher{silent: true},
+	})
+	defer stop()
+
+	f := newV1Engine(a)
+
+	tables := []struct {
+		method string
+		path   string
+		body   string
+		status int
+		code   string
+	}{
+		{"POST", "/v1/subscriptions", `{"pairs": ["BTC-USD"], "exchanges": ["GDAX"]}`, http.StatusCreated, ""},
+		{"POST", "/v1/subscriptions", `{"pairs": ["BTC-USD"], "exchanges": ["GDAX"]}`, http.StatusConflict, "already_exists"},
+		// The silent exchange never acknowledges the subscription
+		{"POST", "/v1/subscriptions?wait=false", `{"pairs": ["ETH-EUR"], "exchanges": ["Silent"]}`, http.StatusAccepted, ""},
+		// Known currency pair which is not listed by the targeted exchange
+		{"POST", "/v1/subscriptions", `{"pairs": ["LTC-EUR"], "exchanges": ["GDAX"]}`, http.StatusNotFound, "not_found"},
+		{"POST", "/v1/subscriptions", `{"pairs": ["LTC-EUR"]}`, http.StatusNotFound, "not_found"},
+		{"POST", "/v1/subscriptions", `{"pairs": ["BTC-USD"], "exchanges": ["Kraken"]}`, http.StatusNotFound, "not_found"},
+		{"POST", "/v1/subscriptions", `{"pairs": ["BTC-USD"], "channels": ["trades"]}`, http.StatusBadRequest, "not_valid"},
+		{"DELETE", "/v1/subscriptions", `{"pairs": ["BTC-USD"], "exchanges": ["GDAX"]}`, http.StatusOK, ""},
+		{"DELETE", "/v1/subscriptions", `{"pairs": ["BTC-USD"], "exchanges": ["GDAX"]}`, http.StatusNotFound, "not_found"},
+	}
+
+	for _, table := range tables {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(table.method, table.path, strings.NewReader(table.body))
+		request.Header.Set("Content-Type", "application/json")
+		f.ServeHTTP(recorder, request)
+
+		assert.Equal(t, table.status, recorder.Code, "%s %s %s: %s", table.method, table.path, table.body, recorder.Body)
+
+		if table.code == "" {
+			continue
+		}
+
+		out := &ErrorOut{}
+		assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), out))
+		assert.Equal(t, table.code, out.Error.Code, "%s %s %s", table.method, table.path, table.body)
+	}
+}
+
+func TestErrorHook(t *testing.T) {
+	tables := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{errors.NotFoundf("exchange Kraken"), http.StatusNotFound, "not_found"},
+		{errors.AlreadyExistsf("subscription"), http.StatusConflict, "already_exists"},
+		{errors.NotValidf("channel trades"), http.StatusBadRequest, "not_valid"},
+		{errors.Unauthorizedf("key"), http.StatusUnauthorized, "unauthorized"},
+		{errors.Annotate(errors.NotFoundf("pair"), "tried to subscribe"), http.StatusNotFound, "not_found"},
+		{tonic.BindError{}, http.StatusBadRequest, "bad_request"},
+		{errors.New("connection reset"), http.StatusInternalServerError, "internal"},
+	}
+
+	for _, table := range tables {
+		status, body := errorHook(nil, table.err)
+
+		assert.Equal(t, table.status, status, "%s", table.err)
+		assert.Equal(t, table.code, body.(*ErrorOut).Error.Code, "%s", table.err)
+		assert.Equal(t, table.err.Error(), body.(*ErrorOut).Error.Message)
+	}
+}
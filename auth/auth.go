@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+// Role determines the routes a principal can call
+type Role string
+
+const (
+	// Can query the subscriptions and the tickers
+	Reader Role = "reader"
+	// Can also change the subscriptions and the intervals
+	Admin Role = "admin"
+)
+
+// Principal is the identity behind a request
+type Principal struct {
+	Name string `json:"name"`
+	Role Role   `json:"role"`
+}
+
+// Authenticator identifies the principal which sent a request.
+// It returns ErrNoCredentials when the request does not contain its credentials,
+// so that the next Authenticator of a Chain can try.
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+// Chain tries each Authenticator until one of them finds credentials
+type Chain []Authenticator
+
+var (
+	ErrNoCredentials = errors.Unauthorizedf("missing credentials")
+)
+
+// Returns the role matching `value` (ex: admin)
+func ParseRole(value string) (Role, error) {
+	switch role := Role(strings.ToLower(value)); role {
+	case Reader, Admin:
+		return role, nil
+	}
+
+	return "", errors.NotValidf("role %s", value)
+}
+
+// Returns true if the role grants the permissions of `required`
+func (r Role) Allows(required Role) bool {
+	return r == Admin || r == required
+}
+
+// Returns the principal found by the first Authenticator
+// which finds credentials in the request
+func (c Chain) Authenticate(r *http.Request) (*Principal, error) {
+	for _, authenticator := range c {
+		principal, err := authenticator.Authenticate(r)
+
+		if err == ErrNoCredentials {
+			continue
+		}
+
+		return principal, err
+	}
+
+	return nil, ErrNoCredentials
+}
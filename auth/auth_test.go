@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseKeys(t *testing.T) {
+	tables := []struct {
+		value  string
+		result map[string]Principal
+		err    string
+	}{
+		{"", map[string]Principal{}, ""},
+		{"front:reader:abc, core:ADMIN:d:e", map[string]Principal{
+			"abc": {Name: "front", Role: Reader},
+			"d:e": {Name: "core", Role: Admin},
+		}, ""},
+		{"front:reader", nil, "notValid"},
+		{"front:owner:abc", nil, "notValid"},
+		{":reader:abc", nil, "notValid"},
+	}
+
+	for _, table := range tables {
+		result, err := ParseKeys(table.value)
+
+		assert.Equal(t, table.result, result)
+
+		switch table.err {
+		case "notValid":
+			assert.True(t, errors.IsNotValid(err))
+		case "":
+			assert.Nil(t, err)
+		}
+	}
+}
+
+func TestRole(t *testing.T) {
+	assert.True(t, Admin.Allows(Admin))
+	assert.True(t, Admin.Allows(Reader))
+	assert.True(t, Reader.Allows(Reader))
+	assert.False(t, Reader.Allows(Admin))
+}
+
+func TestKeyAuthenticator(t *testing.T) {
+	authenticator := NewKeyAuthenticator(map[string]Principal{"abc": {Name: "front", Role: Reader}})
+
+	tables := []struct {
+		header string
+		value  string
+		url    string
+		result *Principal
+		err    string
+	}{
+		{"Authorization", "Bearer abc", "/", &Principal{Name: "front", Role: Reader}, ""},
+		{"X-API-Key", "abc", "/", &Principal{Name: "front", Role: Reader}, ""},
+		{"", "", "/?api_key=abc", &Principal{Name: "front", Role: Reader}, ""},
+		{"X-API-Key", "abd", "/", nil, "unauthorized"},
+		{"", "", "/", nil, "noCredentials"},
+	}
+
+	for _, table := range tables {
+		r := httptest.NewRequest("GET", table.url, nil)
+
+		if table.header != "" {
+			r.Header.Set(table.header, table.value)
+		}
+
+		result, err := authenticator.Authenticate(r)
+
+		assert.Equal(t, table.result, result)
+
+		switch table.err {
+		case "unauthorized":
+			assert.True(t, errors.IsUnauthorized(err))
+		case "noCredentials":
+			assert.Equal(t, ErrNoCredentials, err)
+		case "":
+			assert.Nil(t, err)
+		}
+	}
+}
+
+func TestHMACAuthenticator(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+	authenticator := NewHMACAuthenticator(map[string]Secret{"core": {Principal{Name: "core", Role: Admin}, "s3cr3t"}})
+	authenticator.now = func() time.Time { return now }
+
+	body := []byte(`{"pairs": ["BTC-USD"]}`)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	old := strconv.FormatInt(now.Add(-10*time.Minute).Unix(), 10)
+
+	tables := []struct {
+		keyId     string
+		timestamp string
+		signature string
+		result    *Principal
+		err       string
+	}{
+		{"core", timestamp, Sign("s3cr3t", "POST", "/v1/subscriptions?wait=false", timestamp, body), &Principal{Name: "core", Role: Admin}, ""},
+		{"core", timestamp, Sign("s3cr3t", "POST", "/v1/subscriptions", timestamp, body), nil, "unauthorized"},
+		{"core", old, Sign("s3cr3t", "POST", "/v1/subscriptions?wait=false", old, body), nil, "unauthorized"},
+		{"core", timestamp, Sign("other", "POST", "/v1/subscriptions?wait=false", timestamp, body), nil, "unauthorized"},
+		{"front", timestamp, "", nil, "unauthorized"},
+		{"", "", "", nil, "noCredentials"},
+	}
+
+	for _, table := range tables {
+		r := httptest.NewRequest("POST", "/v1/subscriptions?wait=false", bytes.NewReader(body))
+		r.Header.Set("X-Key-Id", table.keyId)
+		r.Header.Set("X-Timestamp", table.timestamp)
+		r.Header.Set("X-Signature", table.signature)
+
+		result, err := authenticator.Authenticate(r)
+
+		assert.Equal(t, table.result, result)
+
+		switch table.err {
+		case "unauthorized":
+			assert.True(t, errors.IsUnauthorized(err))
+		case "noCredentials":
+			assert.Equal(t, ErrNoCredentials, err)
+		case "":
+			assert.Nil(t, err)
+
+			// The body can still be read by the handlers
+			read, _ := ioutil.ReadAll(r.Body)
+			assert.Equal(t, body, read)
+		}
+	}
+
+	// A signed request cannot be replayed while its timestamp is valid
+	signature := Sign("s3cr3t", "GET", "/v1/tickers", timestamp, []byte{})
+	replay := func() error {
+		r := httptest.NewRequest("GET", "/v1/tickers", nil)
+		r.Header.Set("X-Key-Id", "core")
+		r.Header.Set("X-Timestamp", timestamp)
+		r.Header.Set("X-Signature", signature)
+
+		_, err := authenticator.Authenticate(r)
+		return err
+	}
+
+	assert.Nil(t, replay())
+	assert.True(t, errors.IsUnauthorized(replay()))
+
+	// The signatures are forgotten once their timestamp is rejected
+	now = now.Add(6 * time.Minute)
+	assert.True(t, errors.IsUnauthorized(replay()))
+
+	timestamp = strconv.FormatInt(now.Unix(), 10)
+	signature = Sign("s3cr3t", "GET", "/v1/tickers", timestamp, []byte{})
+	assert.Nil(t, replay())
+	assert.Equal(t, 1, len(authenticator.seen))
+}
+
+func TestChain(t *testing.T) {
+	chain := Chain{
+		NewKeyAuthenticator(map[string]Principal{"abc": {Name: "front", Role: Reader}}),
+		NewHMACAuthenticator(map[string]Secret{}),
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	_, err := chain.Authenticate(r)
+	assert.Equal(t, ErrNoCredentials, err)
+
+	r.Header.Set("X-Key-Id", "unknown")
+	_, err = chain.Authenticate(r)
+	assert.True(t, errors.IsUnauthorized(err))
+
+	r = httptest.NewRequest("GET", "/", nil)
+	r.Header = http.Header{"X-Api-Key": []string{"abc"}}
+	principal, err := chain.Authenticate(r)
+	assert.Nil(t, err)
+	assert.Equal(t, "front", principal.Name)
+}
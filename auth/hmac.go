@@ -0,0 +1,167 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+// HMACAuthenticator identifies the principals by the signature of their requests.
+// A signed request contains the headers:
+//   - X-Key-Id: id of the secret
+//   - X-Timestamp: unix time of the request, in seconds
+//   - X-Signature: hex encoded HMAC-SHA256 of the payload, computed by Sign
+type HMACAuthenticator struct {
+	secrets map[string]Secret
+
+	// Maximum difference between the timestamp of a request and the current time
+	maxSkew time.Duration
+
+	// Returns the current time, replaced by the tests
+	now func() time.Time
+
+	// Signatures already accepted, by key id and signature,
+	// with the time after which their timestamp is rejected anyway
+	seen  map[seenKey]time.Time
+	mutex sync.Mutex
+}
+
+type seenKey struct {
+	keyId     string
+	signature string
+}
+
+// Secret shared with a principal
+type Secret struct {
+	Principal
+	Secret string
+}
+
+const (
+	defaultMaxSkew = 5 * time.Minute
+)
+
+// Initializes an authenticator with the secrets, by key id
+func NewHMACAuthenticator(secrets map[string]Secret) *HMACAuthenticator {
+	return &HMACAuthenticator{
+		secrets: secrets,
+		maxSkew: defaultMaxSkew,
+		now:     time.Now,
+		seen:    map[seenKey]time.Time{},
+	}
+}
+
+// Parses the secrets declared in the configuration,
+// a comma-separated list of "name:role:secret", where the name is the key id
+func ParseSecrets(value string) (map[string]Secret, error) {
+	entries, err := parseEntries(value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	secrets := map[string]Secret{}
+
+	for _, entry := range entries {
+		secrets[entry.Name] = entry
+	}
+
+	return secrets, nil
+}
+
+// Returns the signature of a request: the hex encoded HMAC-SHA256 of
+// "METHOD\nPATH?QUERY\nTIMESTAMP\nhex(SHA256(BODY))"
+func Sign(secret string, method string, uri string, timestamp string, body []byte) string {
+	hash := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(method + "\n" + uri + "\n" + timestamp + "\n" + hex.EncodeToString(hash[:])))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (h *HMACAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	keyId := r.Header.Get("X-Key-Id")
+	signature := r.Header.Get("X-Signature")
+	timestamp := r.Header.Get("X-Timestamp")
+
+	if keyId == "" && signature == "" {
+		return nil, ErrNoCredentials
+	}
+
+	secret, ok := h.secrets[keyId]
+
+	if !ok {
+		return nil, errors.Unauthorizedf("unknown key id %s", keyId)
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+
+	if err != nil {
+		return nil, errors.Unauthorizedf("invalid timestamp %q", timestamp)
+	}
+
+	// Prevents the replay of old requests
+	if skew := h.now().Sub(time.Unix(seconds, 0)); math.Abs(float64(skew)) > float64(h.maxSkew) {
+		return nil, errors.Unauthorizedf("timestamp is more than %s away", h.maxSkew)
+	}
+
+	body := []byte{}
+
+	if r.Body != nil {
+		body, err = ioutil.ReadAll(r.Body)
+
+		if err != nil {
+			return nil, errors.Annotate(err, "tried to read the body of a signed request")
+		}
+
+		// The handlers read the body again
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	expected := Sign(secret.Secret, r.Method, r.URL.RequestURI(), timestamp, body)
+
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return nil, errors.Unauthorizedf("invalid signature")
+	}
+
+	// Prevents the replay of a request while its timestamp is valid
+	if !h.remember(keyId, signature, time.Unix(seconds, 0).Add(h.maxSkew)) {
+		return nil, errors.Unauthorizedf("signature already used")
+	}
+
+	return &Principal{Name: secret.Name, Role: secret.Role}, nil
+}
+
+// Records a signature until `expiry`, after which its timestamp is rejected.
+// Returns false if the signature has already been recorded.
+func (h *HMACAuthenticator) remember(keyId string, signature string, expiry time.Time) bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	now := h.now()
+
+	for key, until := range h.seen {
+		if now.After(until) {
+			delete(h.seen, key)
+		}
+	}
+
+	key := seenKey{keyId, signature}
+
+	if _, ok := h.seen[key]; ok {
+		return false
+	}
+
+	h.seen[key] = expiry
+
+	return true
+}
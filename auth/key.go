@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+// KeyAuthenticator identifies the principals by static API keys,
+// sent as "Authorization: Bearer <key>", "X-API-Key: <key>",
+// or as the api_key query parameter for the clients which cannot set headers
+// (ex: a browser EventSource)
+type KeyAuthenticator struct {
+	keys map[string]Principal
+}
+
+// Initializes an authenticator with the keys of each principal
+func NewKeyAuthenticator(keys map[string]Principal) *KeyAuthenticator {
+	return &KeyAuthenticator{keys: keys}
+}
+
+// Parses the keys declared in the configuration,
+// a comma-separated list of "name:role:key" (ex: "front:reader:s3cr3t")
+func ParseKeys(value string) (map[string]Principal, error) {
+	entries, err := parseEntries(value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	keys := map[string]Principal{}
+
+	for _, entry := range entries {
+		keys[entry.Secret] = entry.Principal
+	}
+
+	return keys, nil
+}
+
+// Parses a comma-separated list of "name:role:secret"
+func parseEntries(value string) ([]Secret, error) {
+	entries := []Secret{}
+
+	for _, raw := range strings.Split(value, ",") {
+		if raw = strings.TrimSpace(raw); raw == "" {
+			continue
+		}
+
+		fields := strings.SplitN(raw, ":", 3)
+
+		if len(fields) != 3 || fields[0] == "" || fields[2] == "" {
+			return nil, errors.NotValidf("credentials of %q, expected name:role:secret", fields[0])
+		}
+
+		role, err := ParseRole(fields[1])
+
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, Secret{Principal: Principal{Name: fields[0], Role: role}, Secret: fields[2]})
+	}
+
+	return entries, nil
+}
+
+func (k *KeyAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	key := r.URL.Query().Get("api_key")
+
+	if header := r.Header.Get("X-API-Key"); header != "" {
+		key = header
+	}
+
+	if authorization := r.Header.Get("Authorization"); strings.HasPrefix(authorization, "Bearer ") {
+		key = strings.TrimPrefix(authorization, "Bearer ")
+	}
+
+	if key == "" {
+		return nil, ErrNoCredentials
+	}
+
+	return k.Lookup(key)
+}
+
+// Returns the principal owning the key
+func (k *KeyAuthenticator) Lookup(key string) (*Principal, error) {
+	for candidate, principal := range k.keys {
+		if subtle.ConstantTimeCompare([]byte(candidate), []byte(key)) == 1 {
+			return &Principal{Name: principal.Name, Role: principal.Role}, nil
+		}
+	}
+
+	return nil, errors.Unauthorizedf("invalid API key")
+}
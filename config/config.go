@@ -0,0 +1,359 @@
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/fberrez/romantic-aggregator/aggregator"
+	"github.com/fberrez/romantic-aggregator/rates"
+	"github.com/fberrez/romantic-aggregator/transport"
+	"github.com/fberrez/romantic-aggregator/websocket"
+	"github.com/juju/errors"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+// Configuration of the aggregator.
+// It is read from a YAML or TOML file, then overridden by the environment
+// variables and finally by the command line flags.
+type Config struct {
+	// DEV or PROD, determines the default logging
+	Environment string `yaml:"environment" toml:"environment" json:"environment"`
+
+	Logging Logging `yaml:"logging" toml:"logging" json:"logging"`
+	API     API     `yaml:"api" toml:"api" json:"api"`
+	Sinks   Sinks   `yaml:"sinks" toml:"sinks" json:"sinks"`
+
+	// Settings of each exchange, by label (ex: gdax, bitfinex)
+	Exchanges map[string]*Exchange `yaml:"exchanges" toml:"exchanges" json:"exchanges"`
+
+	Intervals Intervals `yaml:"intervals" toml:"intervals" json:"intervals"`
+
+	// Subscriptions made when no state has been saved yet (ex: BTC-USD, ETH-EUR@GDAX)
+	Subscriptions []string `yaml:"subscriptions" toml:"subscriptions" json:"subscriptions"`
+
+	State     State     `yaml:"state" toml:"state" json:"state"`
+	Recording Recording `yaml:"recording" toml:"recording" json:"recording"`
+	Health    Health    `yaml:"health" toml:"health" json:"health"`
+	Rates     Rates     `yaml:"rates" toml:"rates" json:"rates"`
+
+	// Command line arguments the configuration has been loaded with
+	args []string
+}
+
+// Logging settings
+type Logging struct {
+	// debug, info, warning or error
+	Level string `yaml:"level" toml:"level" json:"level"`
+
+	// text or json
+	Format string `yaml:"format" toml:"format" json:"format"`
+}
+
+// Settings of the HTTP and gRPC servers
+type API struct {
+	// Port of the HTTP API
+	Port string `yaml:"port" toml:"port" json:"port"`
+
+	// Port of the gRPC API
+	GrpcPort string `yaml:"grpc_port" toml:"grpc_port" json:"grpc_port"`
+
+	// API keys, as "name:role:key" (ex: front:reader:s3cr3t)
+	Keys []string `yaml:"keys" toml:"keys" json:"-"`
+
+	// HMAC secrets, as "name:role:secret"
+	HMACSecrets []string `yaml:"hmac_secrets" toml:"hmac_secrets" json:"-"`
+}
+
+// Destinations of the aggregates
+type Sinks struct {
+	Kafka Kafka `yaml:"kafka" toml:"kafka" json:"kafka"`
+
+	// Prices and volumes written as JSON numbers (ex: 0.1) or strings (ex: "0.1"),
+	// in the Kafka messages and the API
+	Decimals string `yaml:"decimals" toml:"decimals" json:"decimals"`
+}
+
+// Settings of the Kafka producer
+type Kafka struct {
+	// Address of the broker (ex: 127.0.0.1:9092)
+	Address string `yaml:"address" toml:"address" json:"address"`
+
+	// Topic which receives the aggregates
+	Topic string `yaml:"topic" toml:"topic" json:"topic"`
+
+	// Number of connection attempts after the first one failed
+	Retries int `yaml:"retries" toml:"retries" json:"retries"`
+
+	// Delay before the first retry, doubled after each attempt
+	RetryDelay Duration `yaml:"retry_delay" toml:"retry_delay" json:"retry_delay"`
+
+	// True to discard the aggregates instead of connecting to Kafka
+	// (ex: to replay a recording offline)
+	Discard bool `yaml:"discard" toml:"discard" json:"discard"`
+}
+
+// Settings of an exchange. The zero values keep the defaults of its driver.
+type Exchange struct {
+	// False to skip the exchange, true by default
+	Enabled *bool `yaml:"enabled" toml:"enabled" json:"enabled,omitempty"`
+
+	// URL of the websocket (ex: wss://ws-feed.pro.coinbase.com/)
+	Endpoint string `yaml:"endpoint" toml:"endpoint" json:"endpoint,omitempty"`
+
+	// URL of an HTTP CONNECT or SOCKS5 proxy
+	Proxy string `yaml:"proxy" toml:"proxy" json:"proxy,omitempty"`
+
+	// PEM file which contains the root certificates to trust
+	RootCAs string `yaml:"root_cas" toml:"root_cas" json:"root_cas,omitempty"`
+
+	// Timeout of the websocket handshake and of the HTTP requests
+	Timeout Duration `yaml:"timeout" toml:"timeout" json:"timeout,omitempty"`
+
+	// Number of messages sent to the websocket per second, 0 disables the limitation
+	RateLimit *float64 `yaml:"rate_limit" toml:"rate_limit" json:"rate_limit,omitempty"`
+
+	// Number of messages which can be sent at once
+	Burst int `yaml:"burst" toml:"burst" json:"burst,omitempty"`
+
+	// Number of messages waiting to be sent
+	QueueSize int `yaml:"queue_size" toml:"queue_size" json:"queue_size,omitempty"`
+}
+
+// Aggregation intervals (ex: 90s, 5m, 1d, 1M)
+type Intervals struct {
+	// Interval used when no state has been saved yet, the aggregator default if empty
+	Default string `yaml:"default" toml:"default" json:"default"`
+
+	// Bounds of the intervals accepted by the API
+	Min string `yaml:"min" toml:"min" json:"min"`
+	Max string `yaml:"max" toml:"max" json:"max"`
+}
+
+// Settings of the store of the desired subscriptions and intervals
+type State struct {
+	// JSON file which keeps the state
+	File string `yaml:"file" toml:"file" json:"file"`
+}
+
+// Recording and replay of the websocket frames
+type Recording struct {
+	// Gzip compressed file which receives every frame, disabled if empty
+	RecordFile string `yaml:"record_file" toml:"record_file" json:"record_file,omitempty"`
+
+	// Recording replayed instead of opening the websockets, disabled if empty
+	ReplayFile string `yaml:"replay_file" toml:"replay_file" json:"replay_file,omitempty"`
+
+	// Divides the recorded delays between frames, 0 replays as fast as possible
+	ReplaySpeed float64 `yaml:"replay_speed" toml:"replay_speed" json:"replay_speed"`
+}
+
+// Settings of the health checks
+type Health struct {
+	// Duration without any frame after which an exchange is not ready
+	StaleAfter Duration `yaml:"stale_after" toml:"stale_after" json:"stale_after"`
+}
+
+// Settings of the cross rates and of the fiat conversion
+type Rates struct {
+	// Currencies used to synthesize a cross rate, in order of preference (ex: USD, EUR)
+	Bridges []string `yaml:"bridges" toml:"bridges" json:"bridges"`
+
+	// JSON file which contains the fiat conversion table, disabled if empty
+	FiatFile string `yaml:"fiat_file" toml:"fiat_file" json:"fiat_file,omitempty"`
+
+	// Delay between two reads of the fiat conversion table, 0 reads it once
+	Refresh Duration `yaml:"refresh" toml:"refresh" json:"refresh"`
+}
+
+// Duration written as a string (ex: 10s, 2m) in the configuration files
+type Duration struct {
+	time.Duration
+}
+
+// Parses a duration written as a string (ex: 10s)
+func (d *Duration) UnmarshalText(text []byte) error {
+	duration, err := time.ParseDuration(string(text))
+
+	if err != nil {
+		return errors.NotValidf("duration %q", text)
+	}
+
+	d.Duration = duration
+	return nil
+}
+
+// Writes the duration as a string (ex: 10s)
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// Names of the environments
+const (
+	Development string = "DEV"
+	Production  string = "PROD"
+)
+
+// Returns the configuration used when nothing is set
+func Default() *Config {
+	return &Config{
+		Environment: Development,
+		API: API{
+			Port:     "4242",
+			GrpcPort: "4243",
+		},
+		Sinks: Sinks{
+			Kafka: Kafka{
+				Topic:      "romantic-aggregator",
+				Retries:    5,
+				RetryDelay: Duration{2 * time.Second},
+			},
+			Decimals: "number",
+		},
+		Exchanges: map[string]*Exchange{},
+		Intervals: Intervals{
+			Min: aggregator.DefaultMinInterval.String(),
+			Max: aggregator.DefaultMaxInterval.String(),
+		},
+		Subscriptions: []string{},
+		State:         State{File: "romantic-state.json"},
+		Recording:     Recording{ReplaySpeed: 1},
+		Health:        Health{StaleAfter: Duration{time.Minute}},
+		Rates: Rates{
+			Bridges: append([]string{}, rates.DefaultBridges...),
+			Refresh: Duration{time.Hour},
+		},
+	}
+}
+
+// Loads the configuration: the defaults are overridden by the file set by
+// the -config flag or CONFIG_FILE, then by the environment, then by the flags.
+// Returns every invalid setting at once.
+func Load(args []string) (*Config, error) {
+	flags, err := parseFlags(args)
+
+	if err != nil {
+		return nil, err
+	}
+
+	config := Default()
+	path := flags.path()
+
+	if path != "" {
+		if err := config.ReadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	problems := config.applyEnv(environ())
+	flags.apply(config)
+	problems = append(problems, config.Validate()...)
+
+	if len(problems) > 0 {
+		return nil, &ValidationError{Path: path, Problems: problems}
+	}
+
+	config.args = args
+
+	return config, nil
+}
+
+// Loads the configuration again, with the same command line arguments
+func (c *Config) Reload() (*Config, error) {
+	return Load(c.args)
+}
+
+// Reads a YAML (.yaml, .yml) or TOML (.toml) file.
+// The unknown keys are rejected.
+func (c *Config) ReadFile(path string) error {
+	content, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return errors.Annotatef(err, "tried to read the configuration")
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		if err := yaml.UnmarshalStrict(content, c); err != nil {
+			return errors.Annotatef(err, "tried to read %s", path)
+		}
+	case ".toml":
+		metadata, err := toml.NewDecoder(bytes.NewReader(content)).Decode(c)
+
+		if err != nil {
+			return errors.Annotatef(err, "tried to read %s", path)
+		}
+
+		if undecoded := metadata.Undecoded(); len(undecoded) > 0 {
+			return errors.NotValidf("%s: unknown key %s", path, undecoded[0])
+		}
+	default:
+		return errors.NotSupportedf("configuration file %s, expected .yaml, .yml or .toml", path)
+	}
+
+	return nil
+}
+
+// Returns the settings of an exchange (case insensitive),
+// an empty Exchange if it is not configured
+func (c *Config) Exchange(label string) *Exchange {
+	for name, exchange := range c.Exchanges {
+		if strings.EqualFold(name, label) && exchange != nil {
+			return exchange
+		}
+	}
+
+	return &Exchange{}
+}
+
+// Sets the level and the format of the logs, written to stdout
+func (l Logging) Apply() {
+	// The level has been validated with the configuration
+	level, _ := logrus.ParseLevel(l.Level)
+	logrus.SetLevel(level)
+
+	// Output to stdout instead of the default stderr
+	logrus.SetOutput(os.Stdout)
+
+	if l.Format == "json" {
+		logrus.SetFormatter(&logrus.JSONFormatter{})
+	} else {
+		logrus.SetFormatter(&logrus.TextFormatter{})
+	}
+}
+
+// Returns false if the exchange is disabled by the configuration
+func (e *Exchange) IsEnabled() bool {
+	return e.Enabled == nil || *e.Enabled
+}
+
+// Returns the connection settings of the exchange
+func (e *Exchange) Transport() *transport.Options {
+	return &transport.Options{
+		Proxy:   e.Proxy,
+		RootCAs: e.RootCAs,
+		Timeout: e.Timeout.Duration,
+	}
+}
+
+// Returns the websocket settings of the exchange.
+// The endpoint must have been validated.
+func (e *Exchange) Websocket() websocket.Settings {
+	settings := websocket.Settings{
+		Rate:      e.RateLimit,
+		Burst:     e.Burst,
+		QueueSize: e.QueueSize,
+	}
+
+	if e.Endpoint != "" {
+		settings.Endpoint, _ = url.Parse(e.Endpoint)
+	}
+
+	return settings
+}
@@ -0,0 +1,225 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const yamlConfig = `
+environment: PROD
+api:
+  port: "8080"
+  keys: ["front:reader:s3cr3t"]
+sinks:
+  kafka:
+    address: kafka:9092
+    retry_delay: 500ms
+exchanges:
+  gdax:
+    endpoint: wss://ws-feed-public.sandbox.pro.coinbase.com/
+    rate_limit: 0
+    queue_size: 128
+  bitfinex:
+    enabled: false
+intervals:
+  default: 5m
+subscriptions: [BTC-USD, ETH-EUR@GDAX]
+`
+
+const tomlConfig = `
+environment = "PROD"
+subscriptions = ["BTC-USD", "ETH-EUR@GDAX"]
+
+[api]
+port = "8080"
+keys = ["front:reader:s3cr3t"]
+
+[sinks.kafka]
+address = "kafka:9092"
+retry_delay = "500ms"
+
+[exchanges.gdax]
+endpoint = "wss://ws-feed-public.sandbox.pro.coinbase.com/"
+rate_limit = 0.0
+queue_size = 128
+
+[exchanges.bitfinex]
+enabled = false
+
+[intervals]
+default = "5m"
+`
+
+// Writes a configuration file in a temporary directory
+func writeConfig(t *testing.T, name string, content string) (string, func()) {
+	dir, err := ioutil.TempDir("", "config")
+	assert.Nil(t, err)
+
+	path := filepath.Join(dir, name)
+	assert.Nil(t, ioutil.WriteFile(path, []byte(content), 0644))
+
+	return path, func() { os.RemoveAll(dir) }
+}
+
+func TestLoad(t *testing.T) {
+	for _, name := range []string{"romantic.yaml", "romantic.toml"} {
+		content := yamlConfig
+
+		if strings.HasSuffix(name, ".toml") {
+			content = tomlConfig
+		}
+
+		path, remove := writeConfig(t, name, content)
+		defer remove()
+
+		config, err := Load([]string{"-config", path})
+		assert.Nil(t, err, name)
+
+		if err != nil {
+			continue
+		}
+
+		assert.Equal(t, "8080", config.API.Port)
+		assert.Equal(t, "4243", config.API.GrpcPort)
+		assert.Equal(t, []string{"front:reader:s3cr3t"}, config.API.Keys)
+		assert.Equal(t, Kafka{Address: "kafka:9092", Topic: "romantic-aggregator", Retries: 5, RetryDelay: Duration{500 * time.Millisecond}}, config.Sinks.Kafka)
+		assert.Equal(t, Logging{Level: "info", Format: "json"}, config.Logging)
+		assert.Equal(t, "5m", config.Intervals.Default)
+		assert.Equal(t, []string{"BTC-USD", "ETH-EUR@GDAX"}, config.Subscriptions)
+
+		gdax := config.Exchange("GDAX")
+		assert.True(t, gdax.IsEnabled())
+		assert.Equal(t, 0.0, *gdax.Websocket().Rate)
+		assert.Equal(t, 128, gdax.Websocket().QueueSize)
+		assert.Equal(t, "ws-feed-public.sandbox.pro.coinbase.com", gdax.Websocket().Endpoint.Host)
+		assert.False(t, config.Exchange("Bitfinex").IsEnabled())
+	}
+}
+
+func TestLoadOverrides(t *testing.T) {
+	path, remove := writeConfig(t, "romantic.yaml", yamlConfig)
+	defer remove()
+
+	os.Setenv("CONFIG_FILE", path)
+	os.Setenv("API_PORT", "9090")
+	os.Setenv("KAFKA_TOPIC", "from-env")
+	os.Setenv("GDAX_BURST", "4")
+	defer os.Unsetenv("CONFIG_FILE")
+	defer os.Unsetenv("API_PORT")
+	defer os.Unsetenv("KAFKA_TOPIC")
+	defer os.Unsetenv("GDAX_BURST")
+
+	// The flags override the environment, which overrides the file
+	config, err := Load([]string{"-kafka-topic", "from-flag", "-log-level", "debug"})
+	assert.Nil(t, err)
+	assert.Equal(t, "9090", config.API.Port)
+	assert.Equal(t, "from-flag", config.Sinks.Kafka.Topic)
+	assert.Equal(t, "debug", config.Logging.Level)
+	assert.Equal(t, "json", config.Logging.Format)
+
+	// The variables of an exchange keep the settings of the file
+	gdax := config.Exchange("gdax")
+	assert.Equal(t, 4, gdax.Burst)
+	assert.Equal(t, 128, gdax.QueueSize)
+}
+
+func TestLoadErrors(t *testing.T) {
+	tables := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{"romantic.yaml", "api:\n  prot: 80\n", "field prot not found"},
+		{"romantic.toml", "[api]\nprot = \"80\"\n", "unknown key api.prot"},
+		{"romantic.json", "{}", "expected .yaml, .yml or .toml"},
+		{"romantic.yaml", "health:\n  stale_after: 10\n", "duration \"10\" not valid"},
+		{"romantic.yaml", "sinks:\n  kafka:\n    address: kafka:9092\nexchanges:\n  gdax:\n    burst: -1\n", "exchanges.gdax.burst: must be positive, got -1"},
+	}
+
+	for _, table := range tables {
+		path, remove := writeConfig(t, table.name, table.content)
+
+		_, err := Load([]string{"-config", path})
+		remove()
+
+		if assert.NotNil(t, err, table.content) {
+			assert.Contains(t, err.Error(), table.err)
+		}
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	config := Default()
+	problems := config.applyEnv(map[string]string{
+		"KAFKA_RETRIES":        "many",
+		"REPLAY_SPEED":         "fast",
+		"EXCHANGE_STALE_AFTER": "1",
+		"BITFINEX_ENABLED":     "no",
+		"GDAX_RATE_LIMIT":      "2.5",
+		"SUBSCRIPTIONS":        "BTC-USD, ETH-EUR@GDAX,",
+		"KAFKA_DISCARD":        "true",
+	})
+
+	assert.Equal(t, []string{
+		`KAFKA_RETRIES: "many" is not an integer`,
+		`REPLAY_SPEED: "fast" is not a number`,
+		`EXCHANGE_STALE_AFTER: "1" is not a duration (ex: 30s, 2m)`,
+		`BITFINEX_ENABLED: "no" is not a boolean`,
+	}, problems)
+
+	assert.Equal(t, 2.5, *config.Exchange("GDAX").RateLimit)
+	assert.Equal(t, []string{"BTC-USD", "ETH-EUR@GDAX"}, config.Subscriptions)
+	assert.True(t, config.Sinks.Kafka.Discard)
+}
+
+func TestValidate(t *testing.T) {
+	disabled := false
+
+	tables := []struct {
+		change   func(c *Config)
+		problems []string
+	}{
+		{func(c *Config) {}, nil},
+		{func(c *Config) { c.Sinks.Kafka.Address = "" }, []string{"sinks.kafka.address: required (ex: 127.0.0.1:9092), also set by KAFKA_ADDRESS or -kafka-address"}},
+		{func(c *Config) { c.Sinks.Kafka.Address = ""; c.Sinks.Kafka.Discard = true }, nil},
+		{func(c *Config) { c.Environment = "STAGING" }, []string{`environment: "STAGING" is neither DEV nor PROD`}},
+		{func(c *Config) { c.Logging.Level = "verbose" }, []string{`logging.level: "verbose" is not a level, expected debug, info, warning or error`}},
+		{func(c *Config) { c.API.Port = "http" }, []string{`api.port: "http" is not a port number`}},
+		{func(c *Config) { c.API.GrpcPort = c.API.Port }, []string{"api.grpc_port: same port as the HTTP API (4242)"}},
+		{func(c *Config) { c.API.Keys = []string{"front:root:s3cr3t"} }, []string{"api.keys: role root not valid"}},
+		{func(c *Config) { c.API.HMACSecrets = []string{"core:admin:s3cr3t"} }, []string{"api.keys: required with api.hmac_secrets to authenticate the gRPC calls"}},
+		{func(c *Config) { c.Exchanges["kraken"] = &Exchange{} }, []string{"exchanges.kraken: unknown exchange, expected one of bitfinex, gdax"}},
+		{func(c *Config) { c.Exchanges["gdax"] = &Exchange{Endpoint: "https://api.pro.coinbase.com"} }, []string{`exchanges.gdax.endpoint: "https://api.pro.coinbase.com" is not a websocket URL (ex: wss://host/path)`}},
+		{func(c *Config) { c.Exchanges["gdax"] = &Exchange{Proxy: "ftp://10.0.0.1"} }, []string{`exchanges.gdax: proxy scheme "ftp" not supported`}},
+		{func(c *Config) {
+			c.Exchanges["GDAX"] = &Exchange{Enabled: &disabled}
+			c.Exchanges["bitfinex"] = &Exchange{Enabled: &disabled}
+		}, []string{"exchanges: every exchange is disabled"}},
+		{func(c *Config) { c.Intervals.Min = "1h"; c.Intervals.Max = "1m" }, []string{"intervals: interval bounds 1h greater than 1m not valid"}},
+		{func(c *Config) { c.Intervals.Default = "2y" }, []string{`intervals.default: interval 2y: unknown unit "y" not valid`}},
+		{func(c *Config) { c.Intervals.Default = "13M" }, []string{"intervals.default: 13M is not between 1s and 12M"}},
+		{func(c *Config) { c.Subscriptions = []string{"BTCUSD"} }, []string{`subscriptions: subscription "BTCUSD", expected BASE-TARGET[@exchange] not valid`}},
+		{func(c *Config) {
+			c.Subscriptions = []string{"BTC-USD@Kraken", "ETH-EUR@bitfinex"}
+			c.Exchanges["bitfinex"] = &Exchange{Enabled: &disabled}
+		}, []string{"subscriptions: BTC-USD@Kraken: unknown exchange", "subscriptions: ETH-EUR@bitfinex: the exchange is disabled"}},
+		{func(c *Config) { c.Recording.ReplaySpeed = -1 }, []string{"recording.replay_speed: must be positive, got -1"}},
+		{func(c *Config) { c.Sinks.Decimals = "float" }, []string{`sinks.decimals: "float" is neither number nor string`}},
+		{func(c *Config) { c.Rates.Bridges = []string{"usd", "U$D"} }, []string{`rates.bridges: "U$D" is not a currency code`}},
+		{func(c *Config) { c.Rates.Refresh = Duration{-time.Minute} }, []string{"rates.refresh: must be positive, got -1m0s"}},
+	}
+
+	for _, table := range tables {
+		config := Default()
+		config.Sinks.Kafka.Address = "127.0.0.1:9092"
+		table.change(config)
+
+		assert.Equal(t, table.problems, config.Validate())
+	}
+}
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Differences between two configurations, applied in place by a reload
+type Changes struct {
+	// Exchanges which have been enabled
+	AddedExchanges []string `json:"added_exchanges"`
+
+	// Exchanges which have been disabled
+	RemovedExchanges []string `json:"removed_exchanges"`
+
+	// Enabled exchanges whose settings have changed, which must reconnect
+	ChangedExchanges []string `json:"changed_exchanges"`
+
+	// Initial subscriptions which have been added or removed (ex: BTC-USD@GDAX)
+	AddedSubscriptions   []string `json:"added_subscriptions"`
+	RemovedSubscriptions []string `json:"removed_subscriptions"`
+
+	// True if the default interval has changed
+	Interval bool `json:"interval"`
+
+	// True if the bounds of the intervals have changed
+	IntervalBounds bool `json:"interval_bounds"`
+
+	// True if the settings of the Kafka producer have changed
+	Kafka bool `json:"kafka"`
+
+	// True if the logging settings have changed
+	Logging bool `json:"logging"`
+
+	// Sections which have changed but are only applied after a restart
+	// (ex: api, state)
+	RestartRequired []string `json:"restart_required"`
+}
+
+// Sections which are only applied after a restart
+var restartSections = []struct {
+	name string
+
+	// Returns a pointer to the section
+	field func(c *Config) interface{}
+}{
+	{"environment", func(c *Config) interface{} { return &c.Environment }},
+	{"api", func(c *Config) interface{} { return &c.API }},
+	{"sinks.decimals", func(c *Config) interface{} { return &c.Sinks.Decimals }},
+	{"state", func(c *Config) interface{} { return &c.State }},
+	{"recording", func(c *Config) interface{} { return &c.Recording }},
+	{"health", func(c *Config) interface{} { return &c.Health }},
+	{"rates", func(c *Config) interface{} { return &c.Rates }},
+}
+
+// Returns the differences between the current configuration and the new one
+func Compare(current *Config, next *Config) *Changes {
+	changes := &Changes{
+		AddedExchanges:       []string{},
+		RemovedExchanges:     []string{},
+		ChangedExchanges:     []string{},
+		AddedSubscriptions:   []string{},
+		RemovedSubscriptions: []string{},
+		RestartRequired:      []string{},
+	}
+
+	for _, label := range driverNames() {
+		before, after := current.Exchange(label), next.Exchange(label)
+
+		switch {
+		case !before.IsEnabled() && after.IsEnabled():
+			changes.AddedExchanges = append(changes.AddedExchanges, label)
+		case before.IsEnabled() && !after.IsEnabled():
+			changes.RemovedExchanges = append(changes.RemovedExchanges, label)
+		case before.IsEnabled() && !before.sameSettings(after):
+			changes.ChangedExchanges = append(changes.ChangedExchanges, label)
+		}
+	}
+
+	changes.AddedSubscriptions = missing(next.Subscriptions, current.Subscriptions)
+	changes.RemovedSubscriptions = missing(current.Subscriptions, next.Subscriptions)
+
+	changes.Interval = current.Intervals.Default != next.Intervals.Default
+	changes.IntervalBounds = current.Intervals.Min != next.Intervals.Min || current.Intervals.Max != next.Intervals.Max
+	changes.Kafka = current.Sinks.Kafka != next.Sinks.Kafka
+	changes.Logging = current.Logging != next.Logging
+
+	for _, section := range restartSections {
+		if !reflect.DeepEqual(section.field(current), section.field(next)) {
+			changes.RestartRequired = append(changes.RestartRequired, section.name)
+		}
+	}
+
+	return changes
+}
+
+// Returns the configuration running once `next` has been applied in place:
+// the sections which are only applied after a restart keep their `current` values,
+// so that the next reload still reports them.
+func Applied(current *Config, next *Config) *Config {
+	applied := *next
+
+	for _, section := range restartSections {
+		reflect.ValueOf(section.field(&applied)).Elem().Set(reflect.ValueOf(section.field(current)).Elem())
+	}
+
+	return &applied
+}
+
+// Returns false if the settings other than Enabled are different
+func (e *Exchange) sameSettings(other *Exchange) bool {
+	a, b := *e, *other
+	a.Enabled, b.Enabled = nil, nil
+
+	return reflect.DeepEqual(a, b)
+}
+
+// Returns the subscriptions of `values` which are not in `others`,
+// whatever their case
+func missing(values []string, others []string) []string {
+	known := map[string]bool{}
+
+	for _, other := range others {
+		known[strings.ToUpper(strings.TrimSpace(other))] = true
+	}
+
+	result := []string{}
+
+	for _, value := range values {
+		if !known[strings.ToUpper(strings.TrimSpace(value))] {
+			result = append(result, value)
+		}
+	}
+
+	sort.Strings(result)
+
+	return result
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompare(t *testing.T) {
+	disabled := false
+	rate := 2.0
+
+	current := Default()
+	current.Sinks.Kafka.Address = "127.0.0.1:9092"
+	current.Exchanges["bitfinex"] = &Exchange{Enabled: &disabled}
+	current.Subscriptions = []string{"BTC-USD", "ETH-EUR@GDAX"}
+	assert.Empty(t, current.Validate())
+
+	next := Default()
+	next.Sinks.Kafka.Address = "127.0.0.1:9092"
+	next.Exchanges["GDAX"] = &Exchange{RateLimit: &rate}
+	next.Subscriptions = []string{"btc-usd", "LTC-EUR"}
+	next.Intervals.Default = "5m"
+	next.API.Port = "8080"
+	assert.Empty(t, next.Validate())
+
+	assert.Equal(t, &Changes{
+		AddedExchanges:       []string{"Bitfinex"},
+		RemovedExchanges:     []string{},
+		ChangedExchanges:     []string{"GDAX"},
+		AddedSubscriptions:   []string{"LTC-EUR"},
+		RemovedSubscriptions: []string{"ETH-EUR@GDAX"},
+		Interval:             true,
+		RestartRequired:      []string{"api"},
+	}, Compare(current, next))
+
+	// Nothing changes when the configuration is the same
+	assert.Equal(t, &Changes{
+		AddedExchanges:       []string{},
+		RemovedExchanges:     []string{},
+		ChangedExchanges:     []string{},
+		AddedSubscriptions:   []string{},
+		RemovedSubscriptions: []string{},
+		RestartRequired:      []string{},
+	}, Compare(next, next))
+}
+
+func TestApplied(t *testing.T) {
+	current := Default()
+	current.Sinks.Kafka.Address = "127.0.0.1:9092"
+
+	next := Default()
+	next.Sinks.Kafka.Address = "kafka:9092"
+	next.API.Port = "8080"
+	next.Rates.Bridges = []string{"EUR"}
+	next.Intervals.Default = "5m"
+
+	applied := Applied(current, next)
+
+	// The sections applied in place are replaced
+	assert.Equal(t, "kafka:9092", applied.Sinks.Kafka.Address)
+	assert.Equal(t, "5m", applied.Intervals.Default)
+
+	// The others keep running with their current values
+	assert.Equal(t, current.API, applied.API)
+	assert.Equal(t, current.Rates, applied.Rates)
+	assert.Equal(t, []string{"api", "rates"}, Compare(applied, next).RestartRequired)
+
+	// The new configuration is not modified
+	assert.Equal(t, "8080", next.API.Port)
+}
@@ -0,0 +1,238 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fberrez/romantic-aggregator/exchange"
+)
+
+// Returns the environment variables by name
+func environ() map[string]string {
+	env := map[string]string{}
+
+	for _, entry := range os.Environ() {
+		if i := strings.Index(entry, "="); i > 0 {
+			env[entry[:i]] = entry[i+1:]
+		}
+	}
+
+	return env
+}
+
+// Overrides the configuration with the environment variables which are set.
+// Returns the variables which cannot be parsed.
+func (c *Config) applyEnv(env map[string]string) []string {
+	p := &envParser{env: env}
+
+	p.string("ENVIRONMENT", &c.Environment)
+	p.string("LOG_LEVEL", &c.Logging.Level)
+	p.string("LOG_FORMAT", &c.Logging.Format)
+
+	p.string("API_PORT", &c.API.Port)
+	p.string("GRPC_PORT", &c.API.GrpcPort)
+	p.list("API_KEYS", &c.API.Keys)
+	p.list("API_HMAC_SECRETS", &c.API.HMACSecrets)
+
+	p.string("KAFKA_ADDRESS", &c.Sinks.Kafka.Address)
+	p.string("KAFKA_TOPIC", &c.Sinks.Kafka.Topic)
+	p.int("KAFKA_RETRIES", &c.Sinks.Kafka.Retries)
+	p.duration("KAFKA_RETRY_DELAY", &c.Sinks.Kafka.RetryDelay)
+	p.flag("KAFKA_DISCARD", &c.Sinks.Kafka.Discard)
+	p.string("DECIMALS", &c.Sinks.Decimals)
+
+	p.string("INTERVAL", &c.Intervals.Default)
+	p.string("INTERVAL_MIN", &c.Intervals.Min)
+	p.string("INTERVAL_MAX", &c.Intervals.Max)
+	p.list("SUBSCRIPTIONS", &c.Subscriptions)
+
+	p.string("STATE_FILE", &c.State.File)
+	p.string("RECORD_FILE", &c.Recording.RecordFile)
+	p.string("REPLAY_FILE", &c.Recording.ReplayFile)
+	p.float("REPLAY_SPEED", &c.Recording.ReplaySpeed)
+	p.duration("EXCHANGE_STALE_AFTER", &c.Health.StaleAfter)
+	p.list("RATES_BRIDGES", &c.Rates.Bridges)
+	p.string("FIAT_RATES_FILE", &c.Rates.FiatFile)
+	p.duration("FIAT_RATES_REFRESH", &c.Rates.Refresh)
+
+	for _, label := range driverNames() {
+		prefix := strings.ToUpper(label) + "_"
+
+		// The exchange is only added to the configuration if one of its variables is set
+		e := *c.Exchange(label)
+
+		p.bool(prefix+"ENABLED", &e.Enabled)
+		p.string(prefix+"ENDPOINT", &e.Endpoint)
+		p.string(prefix+"PROXY", &e.Proxy)
+		p.string(prefix+"ROOT_CAS", &e.RootCAs)
+		p.duration(prefix+"TIMEOUT", &e.Timeout)
+		p.optionalFloat(prefix+"RATE_LIMIT", &e.RateLimit)
+		p.int(prefix+"BURST", &e.Burst)
+		p.int(prefix+"QUEUE_SIZE", &e.QueueSize)
+
+		if p.changed {
+			c.setExchange(label, &e)
+			p.changed = false
+		}
+	}
+
+	return p.problems
+}
+
+// Replaces the settings of an exchange, whatever the case of its key
+func (c *Config) setExchange(label string, e *Exchange) {
+	if c.Exchanges == nil {
+		c.Exchanges = map[string]*Exchange{}
+	}
+
+	for name := range c.Exchanges {
+		if strings.EqualFold(name, label) {
+			delete(c.Exchanges, name)
+		}
+	}
+
+	c.Exchanges[strings.ToLower(label)] = e
+}
+
+// Returns the names of the exchange drivers, sorted alphabetically
+func driverNames() []string {
+	names := []string{}
+
+	for name := range exchange.Drivers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Reads the environment variables and keeps the ones which cannot be parsed
+type envParser struct {
+	env      map[string]string
+	problems []string
+
+	// True when a variable has been read since it was reset
+	changed bool
+}
+
+// Returns the value of a variable, false if it is not set
+func (p *envParser) lookup(name string) (string, bool) {
+	value, ok := p.env[name]
+
+	if ok {
+		p.changed = true
+	}
+
+	return value, ok
+}
+
+func (p *envParser) invalid(name string, value string, expected string) {
+	p.problems = append(p.problems, fmt.Sprintf("%s: %q is not %s", name, value, expected))
+}
+
+func (p *envParser) string(name string, target *string) {
+	if value, ok := p.lookup(name); ok {
+		*target = value
+	}
+}
+
+// Reads a comma-separated list
+func (p *envParser) list(name string, target *[]string) {
+	value, ok := p.lookup(name)
+
+	if !ok {
+		return
+	}
+
+	*target = []string{}
+
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*target = append(*target, item)
+		}
+	}
+}
+
+func (p *envParser) int(name string, target *int) {
+	if value, ok := p.lookup(name); ok {
+		n, err := strconv.Atoi(value)
+
+		if err != nil {
+			p.invalid(name, value, "an integer")
+			return
+		}
+
+		*target = n
+	}
+}
+
+func (p *envParser) float(name string, target *float64) {
+	if value, ok := p.lookup(name); ok {
+		n, err := strconv.ParseFloat(value, 64)
+
+		if err != nil {
+			p.invalid(name, value, "a number")
+			return
+		}
+
+		*target = n
+	}
+}
+
+func (p *envParser) optionalFloat(name string, target **float64) {
+	if value, ok := p.lookup(name); ok {
+		n, err := strconv.ParseFloat(value, 64)
+
+		if err != nil {
+			p.invalid(name, value, "a number")
+			return
+		}
+
+		*target = &n
+	}
+}
+
+func (p *envParser) bool(name string, target **bool) {
+	if value, ok := p.lookup(name); ok {
+		b, err := strconv.ParseBool(value)
+
+		if err != nil {
+			p.invalid(name, value, "a boolean")
+			return
+		}
+
+		*target = &b
+	}
+}
+
+// Reads a boolean which is false when it is not set
+func (p *envParser) flag(name string, target *bool) {
+	if value, ok := p.lookup(name); ok {
+		b, err := strconv.ParseBool(value)
+
+		if err != nil {
+			p.invalid(name, value, "a boolean")
+			return
+		}
+
+		*target = b
+	}
+}
+
+func (p *envParser) duration(name string, target *Duration) {
+	if value, ok := p.lookup(name); ok {
+		d, err := time.ParseDuration(value)
+
+		if err != nil {
+			p.invalid(name, value, "a duration (ex: 30s, 2m)")
+			return
+		}
+
+		target.Duration = d
+	}
+}
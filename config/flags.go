@@ -0,0 +1,79 @@
+package config
+
+import (
+	"flag"
+	"os"
+)
+
+// Command line flags, which override the file and the environment
+type flags struct {
+	set *flag.FlagSet
+
+	config        string
+	port          string
+	grpcPort      string
+	kafkaAddress  string
+	kafkaTopic    string
+	interval      string
+	subscriptions string
+	stateFile     string
+	logLevel      string
+	logFormat     string
+}
+
+// Parses the command line flags
+func parseFlags(args []string) (*flags, error) {
+	f := &flags{set: flag.NewFlagSet("romantic-aggregator", flag.ContinueOnError)}
+
+	f.set.StringVar(&f.config, "config", "", "YAML or TOML configuration file (CONFIG_FILE)")
+	f.set.StringVar(&f.port, "port", "", "port of the HTTP API (API_PORT)")
+	f.set.StringVar(&f.grpcPort, "grpc-port", "", "port of the gRPC API (GRPC_PORT)")
+	f.set.StringVar(&f.kafkaAddress, "kafka-address", "", "address of the Kafka broker (KAFKA_ADDRESS)")
+	f.set.StringVar(&f.kafkaTopic, "kafka-topic", "", "topic which receives the aggregates (KAFKA_TOPIC)")
+	f.set.StringVar(&f.interval, "interval", "", "initial aggregation interval (INTERVAL)")
+	f.set.StringVar(&f.subscriptions, "subscriptions", "", "initial subscriptions, ex: BTC-USD,ETH-EUR@GDAX (SUBSCRIPTIONS)")
+	f.set.StringVar(&f.stateFile, "state-file", "", "file which keeps the subscriptions (STATE_FILE)")
+	f.set.StringVar(&f.logLevel, "log-level", "", "debug, info, warning or error (LOG_LEVEL)")
+	f.set.StringVar(&f.logFormat, "log-format", "", "text or json (LOG_FORMAT)")
+
+	if err := f.set.Parse(args); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Returns the path of the configuration file, empty if there is none
+func (f *flags) path() string {
+	if f.config != "" {
+		return f.config
+	}
+
+	return os.Getenv("CONFIG_FILE")
+}
+
+// Overrides the configuration with the flags which are set
+func (f *flags) apply(c *Config) {
+	env := map[string]string{}
+
+	// The flags are read like the environment variables they override
+	names := map[string]string{
+		"port":          "API_PORT",
+		"grpc-port":     "GRPC_PORT",
+		"kafka-address": "KAFKA_ADDRESS",
+		"kafka-topic":   "KAFKA_TOPIC",
+		"interval":      "INTERVAL",
+		"subscriptions": "SUBSCRIPTIONS",
+		"state-file":    "STATE_FILE",
+		"log-level":     "LOG_LEVEL",
+		"log-format":    "LOG_FORMAT",
+	}
+
+	f.set.Visit(func(set *flag.Flag) {
+		if name, ok := names[set.Name]; ok {
+			env[name] = set.Value.String()
+		}
+	})
+
+	c.applyEnv(env)
+}
@@ -0,0 +1,282 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fberrez/romantic-aggregator/aggregator"
+	"github.com/fberrez/romantic-aggregator/auth"
+	"github.com/fberrez/romantic-aggregator/currency"
+	"github.com/fberrez/romantic-aggregator/state"
+	"github.com/sirupsen/logrus"
+)
+
+// Returned by Load when some settings are not valid
+type ValidationError struct {
+	// Configuration file, empty if there is none
+	Path string
+
+	// Each invalid setting, prefixed by its key or environment variable
+	// (ex: "sinks.kafka.address: required")
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	source := "configuration"
+
+	if e.Path != "" {
+		source = "configuration " + e.Path
+	}
+
+	return fmt.Sprintf("invalid %s: %s", source, strings.Join(e.Problems, "; "))
+}
+
+// Completes the settings which depend on the others and checks them.
+// Returns the invalid settings, prefixed by their key.
+func (c *Config) Validate() []string {
+	v := &validator{}
+
+	switch c.Environment {
+	case Development:
+		c.Logging.defaults("debug", "text")
+	case Production:
+		c.Logging.defaults("info", "json")
+	default:
+		v.add("environment", "%q is neither %s nor %s", c.Environment, Development, Production)
+	}
+
+	if c.Logging.Level != "" {
+		if _, err := logrus.ParseLevel(c.Logging.Level); err != nil {
+			v.add("logging.level", "%q is not a level, expected debug, info, warning or error", c.Logging.Level)
+		}
+	}
+
+	if c.Logging.Format != "" && c.Logging.Format != "text" && c.Logging.Format != "json" {
+		v.add("logging.format", "%q is neither text nor json", c.Logging.Format)
+	}
+
+	if c.Sinks.Decimals != "number" && c.Sinks.Decimals != "string" {
+		v.add("sinks.decimals", "%q is neither number nor string", c.Sinks.Decimals)
+	}
+
+	v.port("api.port", c.API.Port)
+	v.port("api.grpc_port", c.API.GrpcPort)
+
+	if c.API.Port != "" && c.API.Port == c.API.GrpcPort {
+		v.add("api.grpc_port", "same port as the HTTP API (%s)", c.API.Port)
+	}
+
+	if _, err := auth.ParseKeys(strings.Join(c.API.Keys, ",")); err != nil {
+		v.add("api.keys", "%s", err)
+	}
+
+	if _, err := auth.ParseSecrets(strings.Join(c.API.HMACSecrets, ",")); err != nil {
+		v.add("api.hmac_secrets", "%s", err)
+	}
+
+	// The gRPC calls cannot be signed, they are authenticated by the API keys only
+	if len(c.API.HMACSecrets) > 0 && len(c.API.Keys) == 0 {
+		v.add("api.keys", "required with api.hmac_secrets to authenticate the gRPC calls")
+	}
+
+	c.Sinks.Kafka.validate(v)
+	c.validateExchanges(v)
+	c.Intervals.validate(v)
+	c.validateSubscriptions(v)
+
+	if c.State.File == "" {
+		v.add("state.file", "required")
+	}
+
+	if c.Recording.ReplaySpeed < 0 {
+		v.add("recording.replay_speed", "must be positive, got %v", c.Recording.ReplaySpeed)
+	}
+
+	if c.Health.StaleAfter.Duration < 0 {
+		v.add("health.stale_after", "must be positive, got %s", c.Health.StaleAfter)
+	}
+
+	c.Rates.validate(v)
+
+	return v.problems
+}
+
+// Sets the level and the format which are not set
+func (l *Logging) defaults(level string, format string) {
+	if l.Level == "" {
+		l.Level = level
+	}
+
+	if l.Format == "" {
+		l.Format = format
+	}
+}
+
+func (r *Rates) validate(v *validator) {
+	for _, bridge := range r.Bridges {
+		if !currency.IsCode(strings.ToUpper(bridge)) {
+			v.add("rates.bridges", "%q is not a currency code", bridge)
+		}
+	}
+
+	if r.Refresh.Duration < 0 {
+		v.add("rates.refresh", "must be positive, got %s", r.Refresh)
+	}
+}
+
+func (k *Kafka) validate(v *validator) {
+	if k.Address == "" && !k.Discard {
+		v.add("sinks.kafka.address", "required (ex: 127.0.0.1:9092), also set by KAFKA_ADDRESS or -kafka-address")
+	}
+
+	if k.Topic == "" {
+		v.add("sinks.kafka.topic", "required")
+	}
+
+	if k.Retries < 0 {
+		v.add("sinks.kafka.retries", "must be positive, got %d", k.Retries)
+	}
+
+	if k.RetryDelay.Duration <= 0 {
+		v.add("sinks.kafka.retry_delay", "must be greater than 0, got %s", k.RetryDelay)
+	}
+}
+
+func (c *Config) validateExchanges(v *validator) {
+	names := []string{}
+
+	for name := range c.Exchanges {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		e, key := c.Exchanges[name], "exchanges."+name
+
+		if _, ok := driverName(name); !ok {
+			v.add(key, "unknown exchange, expected one of %s", strings.ToLower(strings.Join(driverNames(), ", ")))
+			continue
+		}
+
+		if e == nil {
+			continue
+		}
+
+		if e.Endpoint != "" {
+			endpoint, err := url.Parse(e.Endpoint)
+
+			if err != nil || (endpoint.Scheme != "ws" && endpoint.Scheme != "wss") || endpoint.Host == "" {
+				v.add(key+".endpoint", "%q is not a websocket URL (ex: wss://host/path)", e.Endpoint)
+			}
+		}
+
+		if err := e.Transport().Validate(); err != nil {
+			v.add(key, "%s", err)
+		}
+
+		if e.Timeout.Duration < 0 {
+			v.add(key+".timeout", "must be positive, got %s", e.Timeout)
+		}
+
+		if e.RateLimit != nil && *e.RateLimit < 0 {
+			v.add(key+".rate_limit", "must be positive, got %v", *e.RateLimit)
+		}
+
+		if e.Burst < 0 {
+			v.add(key+".burst", "must be positive, got %d", e.Burst)
+		}
+
+		if e.QueueSize < 0 {
+			v.add(key+".queue_size", "must be positive, got %d", e.QueueSize)
+		}
+	}
+
+	enabled := 0
+
+	for _, label := range driverNames() {
+		if c.Exchange(label).IsEnabled() {
+			enabled++
+		}
+	}
+
+	if enabled == 0 {
+		v.add("exchanges", "every exchange is disabled")
+	}
+}
+
+func (i *Intervals) validate(v *validator) {
+	min, max, err := aggregator.ParseIntervalBounds(i.Min, i.Max)
+
+	if err != nil {
+		v.add("intervals", "%s", err)
+		return
+	}
+
+	if i.Default == "" {
+		return
+	}
+
+	var interval aggregator.Interval
+
+	if err := interval.UnmarshalText([]byte(i.Default)); err != nil {
+		v.add("intervals.default", "%s", err)
+		return
+	}
+
+	if interval.Approx() < min.Approx() || interval.Approx() > max.Approx() {
+		v.add("intervals.default", "%s is not between %s and %s", i.Default, min, max)
+	}
+}
+
+func (c *Config) validateSubscriptions(v *validator) {
+	subscriptions, err := state.ParseSubscriptions(strings.Join(c.Subscriptions, ","))
+
+	if err != nil {
+		v.add("subscriptions", "%s", err)
+		return
+	}
+
+	for _, sub := range subscriptions {
+		for _, name := range sub.Exchanges {
+			label, ok := driverName(name)
+
+			if !ok {
+				v.add("subscriptions", "%s-%s@%s: unknown exchange", sub.Base, sub.Target, name)
+			} else if !c.Exchange(label).IsEnabled() {
+				v.add("subscriptions", "%s-%s@%s: the exchange is disabled", sub.Base, sub.Target, name)
+			}
+		}
+	}
+}
+
+// Returns the name of the driver matching `name` (case insensitive)
+func driverName(name string) (string, bool) {
+	for _, label := range driverNames() {
+		if strings.EqualFold(label, name) {
+			return label, true
+		}
+	}
+
+	return "", false
+}
+
+// Collects the invalid settings
+type validator struct {
+	problems []string
+}
+
+func (v *validator) add(key string, format string, args ...interface{}) {
+	v.problems = append(v.problems, key+": "+fmt.Sprintf(format, args...))
+}
+
+func (v *validator) port(key string, value string) {
+	port, err := strconv.Atoi(value)
+
+	if err != nil || port < 1 || port > 65535 {
+		v.add(key, "%q is not a port number", value)
+	}
+}
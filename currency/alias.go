@@ -0,0 +1,59 @@
+package currency
+
+import (
+	"strings"
+)
+
+var (
+	// Codes which name the same asset on every exchange, by alias (ex: XBT is BTC)
+	commonAliases = map[string]string{
+		"XBT": "BTC",
+	}
+
+	// Codes used by an exchange instead of the canonical ones,
+	// by exchange then by canonical code (ex: USDT is UST on Bitfinex)
+	nativeCodes = map[string]map[string]string{
+		"Bitfinex": {
+			"DASH":  "DSH",
+			"DATA":  "DAT",
+			"IOTA":  "IOT",
+			"MANA":  "MNA",
+			"QTUM":  "QTM",
+			"USDT":  "UST",
+			"YOYOW": "YYW",
+		},
+	}
+)
+
+// Returns the canonical code of an asset named `code` by an exchange
+// (ex: UST on Bitfinex is USDT, XBT is BTC)
+func Canonical(exchange string, code string) string {
+	code = strings.ToUpper(code)
+
+	for canonical, native := range nativeCodes[exchange] {
+		if native == code {
+			return canonical
+		}
+	}
+
+	if canonical, ok := commonAliases[code]; ok {
+		return canonical
+	}
+
+	return code
+}
+
+// Returns the code of an asset on an exchange (ex: USDT is UST on Bitfinex)
+func Native(exchange string, code string) string {
+	if native, ok := nativeCodes[exchange][code]; ok {
+		return native
+	}
+
+	return code
+}
+
+// Returns the canonical currency pair of the codes used by an exchange
+// (ex: BTC and UST on Bitfinex is BTC-USDT)
+func FindNativePair(exchange string, base string, target string) (Pair, error) {
+	return FindCurrencyPair(Canonical(exchange, base), Canonical(exchange, target))
+}
@@ -0,0 +1,58 @@
+package currency
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAliases(t *testing.T) {
+	tables := []struct {
+		exchange  string
+		native    string
+		canonical string
+	}{
+		{"Bitfinex", "UST", "USDT"},
+		{"Bitfinex", "DSH", "DASH"},
+		{"Bitfinex", "IOT", "IOTA"},
+		{"Bitfinex", "BTC", "BTC"},
+		{"GDAX", "USDT", "USDT"},
+		{"GDAX", "DASH", "DASH"},
+	}
+
+	for _, table := range tables {
+		assert.Equal(t, table.canonical, Canonical(table.exchange, table.native))
+		assert.Equal(t, table.native, Native(table.exchange, table.canonical))
+	}
+
+	// The common aliases are only read
+	assert.Equal(t, "BTC", Canonical("Kraken", "xbt"))
+	assert.Equal(t, "BTC", Native("Kraken", "BTC"))
+}
+
+func TestNativeSymbols(t *testing.T) {
+	tables := []struct {
+		pair     Pair
+		bitfinex string
+		gdax     string
+	}{
+		{BTCUSD, "BTCUSD", "BTC-USD"},
+		{Pair{"BTC", "USDT"}, "BTCUST", "BTC-USDT"},
+		{Pair{"IOTA", "USD"}, "IOTUSD", "IOTA-USD"},
+		{Pair{"TESTBTC", "TESTUSD"}, "TESTBTC:TESTUSD", "TESTBTC-TESTUSD"},
+	}
+
+	for _, table := range tables {
+		bitfinex, err := table.pair.ToBitfinex()
+		assert.Nil(t, err)
+		assert.Equal(t, table.bitfinex, bitfinex)
+
+		gdax, err := table.pair.ToGDAX()
+		assert.Nil(t, err)
+		assert.Equal(t, table.gdax, gdax)
+	}
+
+	pair, err := FindNativePair("Kraken", "XBT", "USD")
+	assert.Nil(t, err)
+	assert.Equal(t, BTCUSD, pair)
+}
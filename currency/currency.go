@@ -6,52 +6,83 @@ import (
 	"github.com/juju/errors"
 )
 
-type currencyPair struct {
-	firstCurrency  string `json:"firstCurrency"`
-	secondCurrency string `json:"secondCurrency"`
-}
-
-type CurrencySlice []*currencyPair
+// Currency pairs, ordered as listed
+type CurrencySlice []Pair
 
 var (
-	BCHBTC *currencyPair = &currencyPair{"BCH", "BTC"}
-	BCHUSD *currencyPair = &currencyPair{"BCH", "USD"}
-	BTCEUR *currencyPair = &currencyPair{"BTC", "EUR"}
-	BTCGBP *currencyPair = &currencyPair{"BTC", "GBP"}
-	BTCUSD *currencyPair = &currencyPair{"BTC", "USD"}
-	ETHBTC *currencyPair = &currencyPair{"ETH", "BTC"}
-	ETHEUR *currencyPair = &currencyPair{"ETH", "EUR"}
-	ETHUSD *currencyPair = &currencyPair{"ETH", "USD"}
-	LTCBTC *currencyPair = &currencyPair{"LTC", "BTC"}
-	LTCEUR *currencyPair = &currencyPair{"LTC", "EUR"}
+	BCHBTC Pair = Pair{"BCH", "BTC"}
+	BCHUSD Pair = Pair{"BCH", "USD"}
+	BTCEUR Pair = Pair{"BTC", "EUR"}
+	BTCGBP Pair = Pair{"BTC", "GBP"}
+	BTCUSD Pair = Pair{"BTC", "USD"}
+	ETHBTC Pair = Pair{"ETH", "BTC"}
+	ETHEUR Pair = Pair{"ETH", "EUR"}
+	ETHUSD Pair = Pair{"ETH", "USD"}
+	LTCBTC Pair = Pair{"LTC", "BTC"}
+	LTCEUR Pair = Pair{"LTC", "EUR"}
 
 	AllCurrencies CurrencySlice = CurrencySlice{BCHBTC, BCHUSD, BTCEUR, BTCGBP, BTCUSD, ETHBTC, ETHEUR, ETHUSD, LTCBTC, LTCEUR}
 )
 
 // Tries to find a currency pair composed by `base` and `target`
-func FindCurrencyPair(base string, target string) (*currencyPair, error) {
+// among the pairs known by the Catalog
+func FindCurrencyPair(base string, target string) (Pair, error) {
 	if base == "" || target == "" {
-		return nil, errors.NotValidf("To find a currency pair, the base and the target cannot be nil")
+		return Pair{}, errors.NotValidf("To find a currency pair, the base and the target cannot be nil")
 	}
 
-	for _, currencyPair := range AllCurrencies {
+	if pair, ok := Catalog.Find(base, target); ok {
+		return pair, nil
+	}
 
-		if currencyPair.firstCurrency == base && currencyPair.secondCurrency == target {
-			return currencyPair, nil
-		}
+	return Pair{}, errors.NotFoundf("Currency Pair %v-%v not found.", base, target)
+}
+
+// Tries to find the currency pair formatted as "BASE-TARGET", "BASE/TARGET" or "BASETARGET"
+// (ex: BTC-USD) among the pairs known by the Catalog
+func ParseCurrencyPair(pair string) (Pair, error) {
+	p, err := ParsePair(pair)
 
+	if err != nil {
+		return Pair{}, err
 	}
 
-	return nil, errors.NotFoundf("Currency Pair %v-%v not found.", base, target)
+	return FindCurrencyPair(p.Base, p.Quote)
 }
 
-// Formats a currency pair to GDAX
-func (c *currencyPair) ToGDAX() (string, error) {
-	if c.firstCurrency == "" || c.secondCurrency == "" {
-		return "", errors.NotValidf("A currency pair must be correctly initiliazed: base and target cannot be nil")
+// Returns the currency pairs as "BASE-TARGET" (ex: BTC-USD)
+func (c CurrencySlice) Strings() []string {
+	result := []string{}
+
+	for _, cp := range c {
+		result = append(result, cp.String())
 	}
 
-	return fmt.Sprintf("%v-%v", c.firstCurrency, c.secondCurrency), nil
+	return result
+}
+
+// Returns true if the slice contains the currency pair
+func (c CurrencySlice) Contains(pair Pair) bool {
+	for _, cp := range c {
+		if cp == pair {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns the currency pairs which are in both slices
+func (c CurrencySlice) Intersect(other CurrencySlice) CurrencySlice {
+	result := CurrencySlice{}
+
+	for _, cp := range other {
+		if c.Contains(cp) && !result.Contains(cp) {
+			result = append(result, cp)
+		}
+	}
+
+	return result
 }
 
 // Formats a currency slice to GDAX
@@ -71,15 +102,6 @@ func (c CurrencySlice) ToGDAX() ([]string, error) {
 	return result, nil
 }
 
-// Formats a currency pair to Bitfinex
-func (c *currencyPair) ToBitfinex() (string, error) {
-	if c.firstCurrency == "" || c.secondCurrency == "" {
-		return "", errors.NotValidf("A currency pair must be correctly initiliazed: base and target cannot be nil")
-	}
-
-	return fmt.Sprintf("%v%v", c.firstCurrency, c.secondCurrency), nil
-}
-
 // Formats a currency slice to Bitfinex
 func (c CurrencySlice) ToBitfinex() ([]string, error) {
 	result := []string{}
@@ -104,11 +126,11 @@ func (c CurrencySlice) ToString() string {
 	for _, cp := range c {
 
 		if text == "" {
-			text = fmt.Sprintf("%s", *cp)
+			text = fmt.Sprintf("{%s %s}", cp.Base, cp.Quote)
 			continue
 		}
 
-		text = fmt.Sprintf("%s - %s", text, *cp)
+		text = fmt.Sprintf("%s - {%s %s}", text, cp.Base, cp.Quote)
 	}
 
 	return text
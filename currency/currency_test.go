@@ -11,14 +11,14 @@ func TestFindCurrencyPair(t *testing.T) {
 	tables := []struct {
 		base   string
 		target string
-		result *currencyPair
+		result Pair
 		err    string
 	}{
-		{"BTC", "GBP", &currencyPair{"BTC", "GBP"}, ""},
-		{"BTC", "", nil, "notValid"},
-		{"", "EUR", nil, "notValid"},
-		{"", "", nil, "notValid"},
-		{"LTC", "GBP", nil, "notFound"},
+		{"BTC", "GBP", Pair{"BTC", "GBP"}, ""},
+		{"BTC", "", Pair{}, "notValid"},
+		{"", "EUR", Pair{}, "notValid"},
+		{"", "", Pair{}, "notValid"},
+		{"LTC", "GBP", Pair{}, "notFound"},
 	}
 
 	for _, table := range tables {
@@ -37,6 +37,39 @@ func TestFindCurrencyPair(t *testing.T) {
 	}
 }
 
+func TestParseCurrencyPair(t *testing.T) {
+	tables := []struct {
+		pair   string
+		result Pair
+		err    string
+	}{
+		{"BTC-GBP", BTCGBP, ""},
+		{"eth-eur", ETHEUR, ""},
+		{"BTCGBP", BTCGBP, ""},
+		{"btc/gbp", BTCGBP, ""},
+		{"BTCG", Pair{}, "notValid"},
+		{"BTC-GBP-EUR", Pair{}, "notValid"},
+		{"BTC-GBP/EUR", Pair{}, "notValid"},
+		{"-EUR", Pair{}, "notValid"},
+		{"LTC-GBP", Pair{}, "notFound"},
+	}
+
+	for _, table := range tables {
+		result, err := ParseCurrencyPair(table.pair)
+
+		assert.Equal(t, table.result, result)
+
+		switch table.err {
+		case "notFound":
+			assert.True(t, errors.IsNotFound(err))
+		case "notValid":
+			assert.True(t, errors.IsNotValid(err))
+		case "":
+			assert.Nil(t, err)
+		}
+	}
+}
+
 func TestToGDAX(t *testing.T) {
 	tables := []struct {
 		slice  CurrencySlice
@@ -44,10 +77,10 @@ func TestToGDAX(t *testing.T) {
 		err    string
 	}{
 		{CurrencySlice{BCHBTC, BTCEUR, BTCGBP}, []string{"BCH-BTC", "BTC-EUR", "BTC-GBP"}, ""},
-		{CurrencySlice{BCHBTC, BTCEUR, &currencyPair{"BTC", "LTC"}}, []string{"BCH-BTC", "BTC-EUR", "BTC-LTC"}, ""},
-		{CurrencySlice{&currencyPair{"BTC", "LTC"}}, []string{"BTC-LTC"}, ""},
-		{CurrencySlice{&currencyPair{"", ""}}, []string{}, "notValid"},
-		{CurrencySlice{BTCEUR, &currencyPair{"", ""}}, []string{"BTC-EUR"}, "notValid"},
+		{CurrencySlice{BCHBTC, BTCEUR, Pair{"BTC", "LTC"}}, []string{"BCH-BTC", "BTC-EUR", "BTC-LTC"}, ""},
+		{CurrencySlice{Pair{"BTC", "LTC"}}, []string{"BTC-LTC"}, ""},
+		{CurrencySlice{Pair{"", ""}}, []string{}, "notValid"},
+		{CurrencySlice{BTCEUR, Pair{"", ""}}, []string{"BTC-EUR"}, "notValid"},
 	}
 
 	for _, table := range tables {
@@ -69,10 +102,11 @@ func TestToBitfinex(t *testing.T) {
 		err    string
 	}{
 		{CurrencySlice{BCHBTC, BTCEUR, BTCGBP}, []string{"BCHBTC", "BTCEUR", "BTCGBP"}, ""},
-		{CurrencySlice{BCHBTC, BTCEUR, &currencyPair{"BTC", "LTC"}}, []string{"BCHBTC", "BTCEUR", "BTCLTC"}, ""},
-		{CurrencySlice{&currencyPair{"BTC", "LTC"}}, []string{"BTCLTC"}, ""},
-		{CurrencySlice{&currencyPair{"", ""}}, []string{}, "notValid"},
-		{CurrencySlice{BTCEUR, &currencyPair{"", ""}}, []string{"BTCEUR"}, "notValid"},
+		{CurrencySlice{BCHBTC, BTCEUR, Pair{"BTC", "LTC"}}, []string{"BCHBTC", "BTCEUR", "BTCLTC"}, ""},
+		{CurrencySlice{Pair{"BTC", "LTC"}}, []string{"BTCLTC"}, ""},
+		{CurrencySlice{Pair{"DOGE", "USD"}, Pair{"MATIC", "USDT"}}, []string{"DOGE:USD", "MATIC:UST"}, ""},
+		{CurrencySlice{Pair{"", ""}}, []string{}, "notValid"},
+		{CurrencySlice{BTCEUR, Pair{"", ""}}, []string{"BTCEUR"}, "notValid"},
 	}
 
 	for _, table := range tables {
@@ -87,16 +121,21 @@ func TestToBitfinex(t *testing.T) {
 	}
 }
 
+func TestSymbol(t *testing.T) {
+	assert.Equal(t, "BTCUSD", BTCUSD.Symbol())
+	assert.Equal(t, "MATICUSDT", (Pair{"MATIC", "USDT"}).Symbol())
+}
+
 func TestToString(t *testing.T) {
 	tables := []struct {
 		slice  CurrencySlice
 		result string
 	}{
 		{CurrencySlice{BCHBTC, BTCEUR, BTCGBP}, "{BCH BTC} - {BTC EUR} - {BTC GBP}"},
-		{CurrencySlice{BCHBTC, BTCEUR, &currencyPair{"BTC", "LTC"}}, "{BCH BTC} - {BTC EUR} - {BTC LTC}"},
-		{CurrencySlice{&currencyPair{"BTC", "LTC"}}, "{BTC LTC}"},
-		{CurrencySlice{&currencyPair{"", ""}}, "{ }"},
-		{CurrencySlice{BTCEUR, &currencyPair{"", ""}}, "{BTC EUR} - { }"},
+		{CurrencySlice{BCHBTC, BTCEUR, Pair{"BTC", "LTC"}}, "{BCH BTC} - {BTC EUR} - {BTC LTC}"},
+		{CurrencySlice{Pair{"BTC", "LTC"}}, "{BTC LTC}"},
+		{CurrencySlice{Pair{"", ""}}, "{ }"},
+		{CurrencySlice{BTCEUR, Pair{"", ""}}, "{BTC EUR} - { }"},
 	}
 
 	for _, table := range tables {
@@ -106,3 +145,22 @@ func TestToString(t *testing.T) {
 	}
 
 }
+
+func TestIntersect(t *testing.T) {
+	tables := []struct {
+		slice  CurrencySlice
+		other  CurrencySlice
+		result CurrencySlice
+	}{
+		{CurrencySlice{BCHBTC, BTCEUR, BTCGBP}, CurrencySlice{BTCEUR, LTCEUR}, CurrencySlice{BTCEUR}},
+		{CurrencySlice{BCHBTC, BTCEUR}, CurrencySlice{Pair{"BTC", "EUR"}, BTCEUR}, CurrencySlice{Pair{"BTC", "EUR"}}},
+		{CurrencySlice{BCHBTC}, CurrencySlice{LTCEUR}, CurrencySlice{}},
+		{CurrencySlice{}, CurrencySlice{BCHBTC}, CurrencySlice{}},
+	}
+
+	for _, table := range tables {
+		result := table.slice.Intersect(table.other)
+
+		assert.Equal(t, table.result, result)
+	}
+}
@@ -0,0 +1,60 @@
+package currency
+
+import (
+	"strconv"
+	"sync/atomic"
+
+	"github.com/shopspring/decimal"
+)
+
+// Decimal written as a JSON number (ex: 0.1),
+// or as a JSON string (ex: "0.1") once SetDecimalStrings is called
+type Decimal struct {
+	decimal.Decimal
+}
+
+var (
+	// 1 when the decimals are written as JSON strings
+	decimalStrings int32
+)
+
+// Writes the prices and sizes as JSON strings (ex: "0.1") instead of numbers,
+// so that consumers which read numbers as floats do not lose their precision.
+func SetDecimalStrings(enabled bool) {
+	value := int32(0)
+
+	if enabled {
+		value = 1
+	}
+
+	atomic.StoreInt32(&decimalStrings, value)
+}
+
+// Wraps a decimal so that it is written as configured by SetDecimalStrings
+func NewDecimal(value decimal.Decimal) Decimal {
+	return Decimal{Decimal: value}
+}
+
+// Writes the decimal as a JSON number or string, depending on SetDecimalStrings
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	if atomic.LoadInt32(&decimalStrings) == 1 {
+		return []byte(strconv.Quote(d.String())), nil
+	}
+
+	return []byte(d.String()), nil
+}
+
+// Rounds the decimal to the nearest multiple of `increment`, unchanged if the increment is 0
+func (d Decimal) RoundTo(increment decimal.Decimal) Decimal {
+	return NewDecimal(RoundTo(d.Decimal, increment))
+}
+
+// Rounds a value to the nearest multiple of `increment` (ex: 0.01 or 0.005),
+// unchanged if the increment is 0
+func RoundTo(value decimal.Decimal, increment decimal.Decimal) decimal.Decimal {
+	if increment.Sign() <= 0 {
+		return value
+	}
+
+	return value.Div(increment).Round(0).Mul(increment)
+}
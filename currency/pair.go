@@ -0,0 +1,169 @@
+package currency
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/shopspring/decimal"
+)
+
+// Currency pair, with canonical codes (ex: BTC-USD).
+// It is comparable and can be used as a map key.
+// It is marshalled as "BASE-QUOTE", in JSON as well.
+type Pair struct {
+	// Currency which is bought or sold (ex: BTC)
+	Base string
+
+	// Currency in which the price is given (ex: USD)
+	Quote string
+}
+
+// Status of a market on an exchange
+type MarketStatus string
+
+const (
+	// The currency pair can be traded
+	Online MarketStatus = "online"
+
+	// The currency pair is listed but cannot be traded
+	Halted MarketStatus = "halted"
+
+	// The currency pair is no longer listed (ex: delisted, internal)
+	Offline MarketStatus = "offline"
+)
+
+// Metadata of a currency pair on an exchange.
+// The sizes are 0 when the exchange does not publish them.
+type Market struct {
+	Pair     Pair   `json:"pair"`
+	Exchange string `json:"exchange"`
+
+	// Smallest increment of the price, in quote currency (ex: 0.01 USD)
+	TickSize Decimal `json:"tick_size"`
+
+	// Smallest increment of the size of an order, in base currency (ex: 0.00000001 BTC)
+	LotSize Decimal `json:"lot_size"`
+
+	// Smallest value of an order, in quote currency (ex: 1 USD)
+	MinNotional Decimal `json:"min_notional"`
+
+	Status MarketStatus `json:"status"`
+}
+
+// Builds a currency pair which is not known yet.
+// The currencies must be written with 2 to 10 uppercase letters or digits.
+func NewCurrencyPair(base string, target string) (Pair, error) {
+	if !currencyCode.MatchString(base) || !currencyCode.MatchString(target) {
+		return Pair{}, errors.NotValidf("currency pair %s-%s", base, target)
+	}
+
+	return Pair{base, target}, nil
+}
+
+// Parses a currency pair formatted as "BASE-QUOTE", "BASE/QUOTE" or "BASEQUOTE"
+// (ex: BTC-USD, btc/usd, BTCUSD), whatever its case.
+// A "BASEQUOTE" symbol is read as a pair known by the Catalog,
+// or as two 3 letters codes.
+func ParsePair(text string) (Pair, error) {
+	text = strings.ToUpper(strings.TrimSpace(text))
+
+	if i := strings.IndexAny(text, "-/"); i >= 0 {
+		if strings.ContainsAny(text[i+1:], "-/") {
+			return Pair{}, errors.NotValidf("currency pair %q, expected BASE-QUOTE", text)
+		}
+
+		return NewCurrencyPair(text[:i], text[i+1:])
+	}
+
+	if pair, ok := Catalog.FindSymbol(text); ok {
+		return pair, nil
+	}
+
+	if len(text) == 6 {
+		return NewCurrencyPair(text[:3], text[3:])
+	}
+
+	return Pair{}, errors.NotValidf("currency pair %q, expected BASE-QUOTE", text)
+}
+
+// Returns the symbol of the currency pair used by the aggregator (ex: BTCUSD, DOGEUSDT)
+func (p Pair) Symbol() string {
+	return p.Base + p.Quote
+}
+
+// Returns the currency pair as "BASE-QUOTE" (ex: BTC-USD)
+func (p Pair) String() string {
+	return fmt.Sprintf("%v-%v", p.Base, p.Quote)
+}
+
+// Returns true if the currency pair has not been set
+func (p Pair) IsZero() bool {
+	return p == Pair{}
+}
+
+// Marshals the currency pair as "BASE-QUOTE" (ex: BTC-USD)
+func (p Pair) MarshalText() ([]byte, error) {
+	if p.IsZero() {
+		return []byte{}, nil
+	}
+
+	return []byte(p.String()), nil
+}
+
+// Parses a currency pair formatted as "BASE-QUOTE", "BASE/QUOTE" or "BASEQUOTE"
+func (p *Pair) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*p = Pair{}
+		return nil
+	}
+
+	pair, err := ParsePair(string(text))
+
+	if err != nil {
+		return err
+	}
+
+	*p = pair
+	return nil
+}
+
+// Returns the markets of the currency pair known by the Catalog, by exchange
+func (p Pair) Markets() map[string]Market {
+	return Catalog.Markets(p)
+}
+
+// Formats a currency pair to GDAX, with its codes (ex: BTC-USD)
+func (p Pair) ToGDAX() (string, error) {
+	if p.Base == "" || p.Quote == "" {
+		return "", errors.NotValidf("A currency pair must be correctly initiliazed: base and target cannot be nil")
+	}
+
+	return fmt.Sprintf("%v-%v", Native("GDAX", p.Base), Native("GDAX", p.Quote)), nil
+}
+
+// Formats a currency pair to Bitfinex, with its codes (ex: BTCUSD, BTCUST for BTC-USDT).
+// The codes which are not 3 letters long are separated by a colon (ex: DOGE:USD).
+func (p Pair) ToBitfinex() (string, error) {
+	if p.Base == "" || p.Quote == "" {
+		return "", errors.NotValidf("A currency pair must be correctly initiliazed: base and target cannot be nil")
+	}
+
+	base, quote := Native("Bitfinex", p.Base), Native("Bitfinex", p.Quote)
+
+	if len(base) != 3 || len(quote) != 3 {
+		return fmt.Sprintf("%v:%v", base, quote), nil
+	}
+
+	return fmt.Sprintf("%v%v", base, quote), nil
+}
+
+// Rounds a price to the nearest tick of the market, unchanged if the tick size is unknown
+func (m Market) RoundPrice(price decimal.Decimal) decimal.Decimal {
+	return RoundTo(price, m.TickSize.Decimal)
+}
+
+// Rounds a size to the nearest lot of the market, unchanged if the lot size is unknown
+func (m Market) RoundSize(size decimal.Decimal) decimal.Decimal {
+	return RoundTo(size, m.LotSize.Decimal)
+}
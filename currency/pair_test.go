@@ -0,0 +1,133 @@
+package currency
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/juju/errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCurrencyPair(t *testing.T) {
+	tables := []struct {
+		base   string
+		target string
+		err    bool
+	}{
+		{"SOL", "USD", false},
+		{"sol", "USD", true},
+		{"SHIB", "USD", false},
+		{"MATIC", "USDT", false},
+		{"1INCH", "EUR", false},
+		{"B", "USD", true},
+		{"BTC-USD", "EUR", true},
+		{"BTC", "", true},
+	}
+
+	for _, table := range tables {
+		pair, err := NewCurrencyPair(table.base, table.target)
+
+		if table.err {
+			assert.True(t, errors.IsNotValid(err), table.base)
+			continue
+		}
+
+		assert.Equal(t, Pair{table.base, table.target}, pair)
+	}
+}
+
+func TestParsePair(t *testing.T) {
+	catalog := Catalog
+	defer func() { Catalog = catalog }()
+
+	Catalog = NewRegistry()
+	Catalog.Set("GDAX", []Market{{Pair: Pair{"DOGE", "USDT"}, Status: Online}})
+
+	tables := []struct {
+		text   string
+		result Pair
+		err    bool
+	}{
+		{"BTC-USD", BTCUSD, false},
+		{"btc/usd", BTCUSD, false},
+		{"BTCUSD", BTCUSD, false},
+		{" MATIC-USDT ", Pair{"MATIC", "USDT"}, false},
+		{"DOGEUSDT", Pair{"DOGE", "USDT"}, false},
+		{"MATICUSDT", Pair{}, true},
+		{"BTC-", Pair{}, true},
+		{"BTC-USD-EUR", Pair{}, true},
+		{"", Pair{}, true},
+	}
+
+	for _, table := range tables {
+		result, err := ParsePair(table.text)
+		assert.Equal(t, table.result, result, table.text)
+		assert.Equal(t, table.err, errors.IsNotValid(err), table.text)
+	}
+}
+
+func TestPairMarshalling(t *testing.T) {
+	out, err := json.Marshal(map[Pair]CurrencySlice{BTCUSD: {BTCUSD, Pair{"MATIC", "USDT"}}})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"BTC-USD":["BTC-USD","MATIC-USDT"]}`, string(out))
+
+	in := map[Pair]Pair{}
+	assert.Nil(t, json.Unmarshal([]byte(`{"btc/usd": "ETHEUR", "DOGE-USDT": ""}`), &in))
+	assert.Equal(t, map[Pair]Pair{BTCUSD: ETHEUR, {"DOGE", "USDT"}: {}}, in)
+
+	var pair Pair
+	assert.True(t, errors.IsNotValid(json.Unmarshal([]byte(`"BTC-"`), &pair)))
+
+	text, err := Pair{"SOL", "EUR"}.MarshalText()
+	assert.Nil(t, err)
+	assert.Equal(t, "SOL-EUR", string(text))
+}
+
+func TestRoundTo(t *testing.T) {
+	tables := []struct {
+		value     string
+		increment string
+		result    string
+	}{
+		{"0.30000000000000004", "0.01", "0.3"},
+		{"12345.678", "0.01", "12345.68"},
+		{"12345.678", "0.005", "12345.68"},
+		{"12345.676", "0.005", "12345.675"},
+		{"0.00001234567", "0.00000001", "0.00001235"},
+		{"1.23456", "0", "1.23456"},
+	}
+
+	for _, table := range tables {
+		result := RoundTo(decimal.RequireFromString(table.value), decimal.RequireFromString(table.increment))
+		assert.Equal(t, table.result, result.String(), table.value)
+	}
+
+	market := Market{TickSize: NewDecimal(decimal.RequireFromString("0.5")), LotSize: NewDecimal(decimal.RequireFromString("0.1"))}
+	assert.Equal(t, "10.5", market.RoundPrice(decimal.RequireFromString("10.4")).String())
+	assert.Equal(t, "2.3", market.RoundSize(decimal.RequireFromString("2.26")).String())
+}
+
+func TestDecimalStrings(t *testing.T) {
+	defer SetDecimalStrings(false)
+
+	market := Market{Pair: BTCUSD, TickSize: NewDecimal(decimal.RequireFromString("0.01"))}
+
+	out, err := json.Marshal(market)
+	assert.Nil(t, err)
+	assert.Contains(t, string(out), `"tick_size":0.01`)
+
+	SetDecimalStrings(true)
+	out, err = json.Marshal(market)
+	assert.Nil(t, err)
+	assert.Contains(t, string(out), `"tick_size":"0.01"`)
+
+	// The other decimals are written as the decimal package does by default
+	out, err = json.Marshal(market.TickSize.Decimal)
+	assert.Nil(t, err)
+	assert.Equal(t, `"0.01"`, string(out))
+
+	var decoded Market
+	assert.Nil(t, json.Unmarshal([]byte(`{"tick_size": 0.5}`), &decoded))
+	assert.Equal(t, "0.5", decoded.TickSize.String())
+}
@@ -0,0 +1,224 @@
+package currency
+
+import (
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Currency pairs known by the aggregator and the markets of each exchange.
+// It starts with AllCurrencies, then learns the product catalog of each exchange.
+type Registry struct {
+	mutex sync.RWMutex
+
+	// Known currency pairs, a pair is never forgotten
+	pairs map[Pair]bool
+
+	// Markets of each exchange, by currency pair
+	markets map[string]map[Pair]Market
+
+	// Currency pairs which can be traded on each exchange
+	listings map[string]CurrencySlice
+
+	// Date and time of the last catalog of each exchange
+	updated map[string]time.Time
+}
+
+// Listing of a currency pair
+type Listing struct {
+	// Currency pair (ex: BTC-USD)
+	Pair Pair `json:"pair"`
+	// Exchanges which list the currency pair, empty if it is only known
+	Exchanges []string `json:"exchanges"`
+	// Markets of the currency pair on each exchange, sorted by exchange
+	Markets []Market `json:"markets"`
+}
+
+var (
+	// Registry consulted by FindCurrencyPair
+	Catalog *Registry = NewRegistry()
+
+	// A currency is written with 2 to 10 uppercase letters or digits (ex: BTC, MATIC, 1INCH)
+	currencyCode *regexp.Regexp = regexp.MustCompile(`^[A-Z0-9]{2,10}$`)
+)
+
+// Returns true if `code` is written like a currency code (ex: BTC, 1INCH)
+func IsCode(code string) bool {
+	return currencyCode.MatchString(code)
+}
+
+// Initializes a registry which knows AllCurrencies
+func NewRegistry() *Registry {
+	r := &Registry{
+		pairs:    map[Pair]bool{},
+		markets:  map[string]map[Pair]Market{},
+		listings: map[string]CurrencySlice{},
+		updated:  map[string]time.Time{},
+	}
+
+	for _, pair := range AllCurrencies {
+		r.pairs[pair] = true
+	}
+
+	return r
+}
+
+// Returns the currency pair composed by `base` and `target`, false if it is unknown
+func (r *Registry) Find(base string, target string) (Pair, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	pair := Pair{base, target}
+	return pair, r.pairs[pair]
+}
+
+// Returns the known currency pair whose symbol is `symbol` (ex: DOGEUSDT),
+// false if there is none.
+// The first pair in alphabetical order is returned if several pairs match.
+func (r *Registry) FindSymbol(symbol string) (Pair, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	found := Pair{}
+
+	for pair := range r.pairs {
+		if pair.Symbol() == symbol && (found.IsZero() || pair.String() < found.String()) {
+			found = pair
+		}
+	}
+
+	return found, !found.IsZero()
+}
+
+// Replaces the markets of an exchange.
+// Returns the currency pairs which can be traded, in the order of the markets.
+func (r *Registry) Set(exchange string, markets []Market) CurrencySlice {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	listing := CurrencySlice{}
+	byPair := map[Pair]Market{}
+
+	for _, market := range markets {
+		market.Exchange = exchange
+		byPair[market.Pair] = market
+
+		if market.Status != Online {
+			continue
+		}
+
+		r.pairs[market.Pair] = true
+
+		if !listing.Contains(market.Pair) {
+			listing = append(listing, market.Pair)
+		}
+	}
+
+	r.markets[exchange] = byPair
+	r.listings[exchange] = listing
+	r.updated[exchange] = time.Now()
+
+	return listing
+}
+
+// Returns the currency pairs which can be traded on an exchange,
+// false if its catalog has not been loaded yet
+func (r *Registry) Listed(exchange string) (CurrencySlice, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	listing, ok := r.listings[exchange]
+	return listing, ok
+}
+
+// Returns the markets of a currency pair, by exchange
+func (r *Registry) Markets(pair Pair) map[string]Market {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	markets := map[string]Market{}
+
+	for exchange, byPair := range r.markets {
+		if market, ok := byPair[pair]; ok {
+			markets[exchange] = market
+		}
+	}
+
+	return markets
+}
+
+// Returns the finest tick and lot sizes of the markets of a currency pair,
+// 0 if no exchange publishes them
+func (r *Registry) Increments(pair Pair) (decimal.Decimal, decimal.Decimal) {
+	tick, lot := decimal.Zero, decimal.Zero
+
+	for _, market := range r.Markets(pair) {
+		if market.TickSize.Sign() > 0 && (tick.IsZero() || market.TickSize.LessThan(tick)) {
+			tick = market.TickSize.Decimal
+		}
+
+		if market.LotSize.Sign() > 0 && (lot.IsZero() || market.LotSize.LessThan(lot)) {
+			lot = market.LotSize.Decimal
+		}
+	}
+
+	return tick, lot
+}
+
+// Returns the date and time of the last catalog of an exchange,
+// the zero time if it has not been loaded yet
+func (r *Registry) Updated(exchange string) time.Time {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.updated[exchange]
+}
+
+// Returns every known currency pair with the exchanges which list it
+// and its markets, sorted by currency pair
+func (r *Registry) Listings() []Listing {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	exchanges := map[Pair][]string{}
+
+	for exchange, listing := range r.listings {
+		for _, pair := range listing {
+			exchanges[pair] = append(exchanges[pair], exchange)
+		}
+	}
+
+	listings := []Listing{}
+
+	for pair := range r.pairs {
+		listed := exchanges[pair]
+
+		if listed == nil {
+			listed = []string{}
+		}
+
+		markets := []Market{}
+
+		for _, byPair := range r.markets {
+			if market, ok := byPair[pair]; ok {
+				markets = append(markets, market)
+			}
+		}
+
+		sort.Strings(listed)
+		sort.Slice(markets, func(i, j int) bool {
+			return markets[i].Exchange < markets[j].Exchange
+		})
+
+		listings = append(listings, Listing{Pair: pair, Exchanges: listed, Markets: markets})
+	}
+
+	sort.Slice(listings, func(i, j int) bool {
+		return listings[i].Pair.String() < listings[j].Pair.String()
+	})
+
+	return listings
+}
@@ -0,0 +1,80 @@
+package currency
+
+import (
+	"testing"
+
+	"github.com/juju/errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	solusd := Pair{"SOL", "USD"}
+	cent := decimal.RequireFromString("0.01")
+
+	_, ok := r.Find("SOL", "USD")
+	assert.False(t, ok)
+
+	_, ok = r.Listed("GDAX")
+	assert.False(t, ok)
+
+	// The pairs which cannot be traded are not listed
+	listing := r.Set("GDAX", []Market{
+		{Pair: BTCUSD, TickSize: NewDecimal(cent), Status: Online},
+		{Pair: solusd, Status: Online},
+		{Pair: solusd, Status: Online},
+		{Pair: Pair{"XRP", "USD"}, Status: Offline},
+	})
+	assert.Equal(t, CurrencySlice{BTCUSD, solusd}, listing)
+
+	r.Set("Bitfinex", []Market{{Pair: solusd, Status: Online}})
+
+	pair, ok := r.Find("SOL", "USD")
+	assert.True(t, ok)
+	assert.Equal(t, solusd, pair)
+	assert.False(t, r.Updated("GDAX").IsZero())
+
+	_, ok = r.Find("XRP", "USD")
+	assert.False(t, ok)
+
+	assert.Equal(t, map[string]Market{
+		"GDAX": {Pair: BTCUSD, Exchange: "GDAX", TickSize: NewDecimal(cent), Status: Online},
+	}, r.Markets(BTCUSD))
+
+	tick, lot := r.Increments(BTCUSD)
+	assert.Equal(t, cent, tick)
+	assert.True(t, lot.IsZero())
+
+	listings := r.Listings()
+	assert.Len(t, listings, len(AllCurrencies)+1)
+	assert.Contains(t, listings, Listing{
+		Pair:      solusd,
+		Exchanges: []string{"Bitfinex", "GDAX"},
+		Markets:   []Market{{Pair: solusd, Exchange: "Bitfinex", Status: Online}, {Pair: solusd, Exchange: "GDAX", Status: Online}},
+	})
+	assert.Contains(t, listings, Listing{Pair: LTCEUR, Exchanges: []string{}, Markets: []Market{}})
+
+	// A pair which is no longer listed is still known
+	r.Set("GDAX", []Market{{Pair: BTCUSD, Status: Online}})
+	r.Set("Bitfinex", []Market{{Pair: BTCUSD, Status: Online}})
+	assert.Contains(t, r.Listings(), Listing{Pair: solusd, Exchanges: []string{}, Markets: []Market{}})
+
+	pair, ok = r.FindSymbol("SOLUSD")
+	assert.True(t, ok)
+	assert.Equal(t, solusd, pair)
+}
+
+func TestFindCurrencyPairCatalog(t *testing.T) {
+	catalog := Catalog
+	defer func() { Catalog = catalog }()
+
+	Catalog = NewRegistry()
+	_, err := FindCurrencyPair("SOL", "USD")
+	assert.True(t, errors.IsNotFound(err))
+
+	Catalog.Set("GDAX", []Market{{Pair: Pair{"SOL", "USD"}, Status: Online}})
+	pair, err := ParseCurrencyPair("sol-usd")
+	assert.Nil(t, err)
+	assert.Equal(t, "SOL-USD", pair.String())
+}
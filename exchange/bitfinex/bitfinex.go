@@ -2,6 +2,8 @@ package bitfinex
 
 import (
 	"encoding/json"
+	"fmt"
+	"io/ioutil"
 	"net/url"
 	"reflect"
 	"strconv"
@@ -9,8 +11,12 @@ import (
 
 	"github.com/fberrez/romantic-aggregator/aggregator"
 	"github.com/fberrez/romantic-aggregator/currency"
+	"github.com/fberrez/romantic-aggregator/metrics"
+	"github.com/fberrez/romantic-aggregator/subscription"
+	"github.com/fberrez/romantic-aggregator/transport"
 	"github.com/fberrez/romantic-aggregator/websocket"
 	"github.com/juju/errors"
+	"github.com/shopspring/decimal"
 	"github.com/sirupsen/logrus"
 )
 
@@ -24,11 +30,25 @@ const (
 
 	Subscribed   string = "subscribed"
 	Unsubscribed string = "unsubscribed"
+	Error        string = "error"
+
+	// Number of acknowledgements waiting to be processed
+	ackBuffer = 64
 )
 
 var (
+	// Currency pairs listed by Bitfinex until its product catalog is loaded
+	pairs currency.CurrencySlice = currency.CurrencySlice{currency.BCHBTC, currency.BCHUSD, currency.BTCEUR, currency.BTCGBP, currency.BTCUSD, currency.ETHBTC, currency.ETHEUR, currency.ETHUSD, currency.LTCBTC}
+
+	// Each symbol and channel is a distinct message:
+	// they are spread out to avoid being disconnected for flooding
+	limits websocket.Limits = websocket.Limits{Rate: 5, Burst: 10, QueueSize: 256}
+
 	uri url.URL       = url.URL{Scheme: "wss", Host: "api.bitfinex.com", Path: "/ws/2"}
 	log *logrus.Entry = logrus.WithFields(logrus.Fields{"element": "exchange", "label": "Bitfinex"})
+
+	// Currency pairs which can be traded on Bitfinex
+	pairsURL string = "https://api-pub.bitfinex.com/v2/conf/pub:list:pair:exchange"
 )
 
 // Initializes the Bitfinex struct
@@ -37,10 +57,20 @@ func (b *Bitfinex) Initialize(aggregatorChan chan aggregator.SimpleTicker) error
 
 	b.AggregatorChannel = aggregatorChan
 	b.Proxy = &websocket.Proxy{
-		Label: "Bitfinex",
+		Label:  "Bitfinex",
+		Limits: limits,
 	}
 
-	b.InterruptChannel = make(chan bool)
+	b.InterruptChannel = make(chan bool, 1)
+	b.AckChannel = make(chan subscription.Ack, ackBuffer)
+
+	client, err := transport.NewHTTPClient("Bitfinex")
+
+	if err != nil {
+		return errors.Annotate(err, "tried to build the bitfinex http client")
+	}
+
+	b.httpClient = client
 
 	return b.Proxy.Initialize(uri)
 }
@@ -73,6 +103,7 @@ func (b *Bitfinex) ListenResponse() {
 			_, err := b.makeResponse(response)
 
 			if err != nil {
+				metrics.ParseErrors.WithLabelValues("Bitfinex").Inc()
 				log.WithFields(logrus.Fields{
 					"action": "listening to the responses sent by websocket",
 				}).Errorf("%v", err)
@@ -122,7 +153,9 @@ func (b *Bitfinex) NewMessage(isSubscription bool, symbols []string, channels []
 			}
 
 			log.WithFields(logrus.Fields{"message": message}).Debugf("Sending new message to websocket")
-			b.Proxy.MessageChannel <- messageByte
+			if err := b.Proxy.Send(messageByte); err != nil {
+				return errors.Annotatef(err, "message %v", message)
+			}
 		}
 	}
 
@@ -167,34 +200,40 @@ func (b *Bitfinex) makeTickerResponse(data []byte) (*TickerResponse, error) {
 		return nil, nil
 	}
 
+	channelId, err := strconv.Atoi(response[0])
+
+	if err != nil {
+		return nil, errors.NotSupportedf("cannot parse the channel id %v: %v", response[0], err)
+	}
+
 	// Array which will contain the strings which were in the splitted array,
-	// converted to float64
-	responseFloats := []float64{}
+	// converted to decimals as written by Bitfinex
+	responseDecimals := []decimal.Decimal{}
 
-	// Converts each strings to float64
-	for _, resp := range response {
-		respFloats, err := strconv.ParseFloat(resp, 64)
+	// Converts each strings to decimal
+	for _, resp := range response[1:] {
+		respDecimal, err := decimal.NewFromString(resp)
 
 		if err != nil {
-			return nil, errors.NotSupportedf("cannot parse %v to float64: %v", resp, err)
+			return nil, errors.NotSupportedf("cannot parse %v to decimal: %v", resp, err)
 		}
 
-		responseFloats = append(responseFloats, respFloats)
+		responseDecimals = append(responseDecimals, respDecimal)
 	}
 
 	// Builds the Ticker Response
 	tickerResponse := &TickerResponse{
-		ChannelId:       int(responseFloats[0]),
-		Bid:             responseFloats[1],
-		BidSize:         responseFloats[2],
-		Ask:             responseFloats[3],
-		AskSize:         responseFloats[4],
-		DailyChange:     responseFloats[5],
-		DailyChangePrec: responseFloats[6],
-		LastPrice:       responseFloats[7],
-		Volume:          responseFloats[8],
-		High:            responseFloats[9],
-		Low:             responseFloats[10],
+		ChannelId:       channelId,
+		Bid:             responseDecimals[0],
+		BidSize:         responseDecimals[1],
+		Ask:             responseDecimals[2],
+		AskSize:         responseDecimals[3],
+		DailyChange:     responseDecimals[4],
+		DailyChangePrec: responseDecimals[5],
+		LastPrice:       responseDecimals[6],
+		Volume:          responseDecimals[7],
+		High:            responseDecimals[8],
+		Low:             responseDecimals[9],
 	}
 
 	if _, err := b.parseAndSendTickerResponseToAggregator(tickerResponse); err != nil {
@@ -212,13 +251,23 @@ func (b *Bitfinex) parseAndSendTickerResponseToAggregator(t *TickerResponse) (*a
 		return nil, errors.Annotate(err, "tried to send a ticker response to aggregator")
 	}
 
+	base, target := splitSymbol(symbol)
+	pair, err := currency.FindNativePair("Bitfinex", base, target)
+
+	if err != nil {
+		return nil, errors.Annotatef(err, "tried to find the currency pair of %s", symbol)
+	}
+
 	aggregatorTicker := &aggregator.SimpleTicker{
-		Exchange: "Bitfinex",
-		Symbol:   symbol,
-		Price:    t.LastPrice,
-		Bid:      t.Bid,
-		Ask:      t.Ask,
-		Volume:   t.Volume,
+		Exchange:     "Bitfinex",
+		Symbol:       pair.Symbol(),
+		Base:         pair.Base,
+		Quote:        pair.Quote,
+		NativeSymbol: symbol,
+		Price:        currency.NewDecimal(t.LastPrice),
+		Bid:          currency.NewDecimal(t.Bid),
+		Ask:          currency.NewDecimal(t.Ask),
+		Volume:       currency.NewDecimal(t.Volume),
 	}
 
 	b.AggregatorChannel <- *aggregatorTicker
@@ -254,6 +303,14 @@ func (b *Bitfinex) manageSubscriptions(data []byte) (interface{}, error) {
 		}
 
 		b.manageUnsubscribe(response)
+	case Error:
+		response := ErrorResponse{}
+
+		if err = json.Unmarshal(data, &response); err != nil {
+			return nil, errors.Annotatef(err, "tried to unmarshal an error response %v", string(data))
+		}
+
+		b.manageError(response)
 	}
 
 	log.WithFields(logrus.Fields{"subscriptions": b.Subscriptions}).Debugf("Current Subscriptions")
@@ -265,6 +322,14 @@ func (b *Bitfinex) manageSubscriptions(data []byte) (interface{}, error) {
 // and updates the subscriptions in the proxy side
 func (b *Bitfinex) manageSubscribe(subscribeResponse SubscribeResponse) error {
 	b.Subscriptions = append(b.Subscriptions, subscribeResponse)
+
+	subscription.Send(b.AckChannel, subscription.Ack{
+		Exchange: "Bitfinex",
+		Action:   subscription.Subscribe,
+		Symbol:   subscribeResponse.Pair,
+		Channel:  subscribeResponse.Channel,
+	})
+
 	return b.updateSubscriptions()
 }
 
@@ -275,6 +340,13 @@ func (b *Bitfinex) manageUnsubscribe(unsubscribeResponse UnsubscribeResponse) er
 	for i, sub := range b.Subscriptions {
 		if sub.ChanId == unsubscribeResponse.ChanId {
 			b.Subscriptions = append(b.Subscriptions[:i], b.Subscriptions[i+1:]...)
+
+			subscription.Send(b.AckChannel, subscription.Ack{
+				Exchange: "Bitfinex",
+				Action:   subscription.Unsubscribe,
+				Symbol:   sub.Pair,
+				Channel:  sub.Channel,
+			})
 			break
 		}
 	}
@@ -282,6 +354,25 @@ func (b *Bitfinex) manageUnsubscribe(unsubscribeResponse UnsubscribeResponse) er
 	return b.updateSubscriptions()
 }
 
+// Rejects the (un)subscription described by the error response
+// (ex: {"event":"error","msg":"symbol: invalid","code":10300,"channel":"ticker","symbol":"tXXXUSD"})
+func (b *Bitfinex) manageError(errorResponse ErrorResponse) {
+	log.WithFields(logrus.Fields{"error": errorResponse}).Warn("Error sent by the websocket")
+
+	pair := errorResponse.Pair
+
+	if pair == "" {
+		pair = strings.TrimPrefix(errorResponse.Symbol, "t")
+	}
+
+	subscription.Send(b.AckChannel, subscription.Ack{
+		Exchange: "Bitfinex",
+		Symbol:   pair,
+		Channel:  errorResponse.Channel,
+		Error:    fmt.Sprintf("%s (code %d)", errorResponse.Msg, errorResponse.Code),
+	})
+}
+
 // Returns the channel id of the subscription
 // which manage the channel and the pair which are in arguments
 // Returns -1 if the channel id cannot be found
@@ -378,9 +469,112 @@ func (b *Bitfinex) TranslateCurrency(c currency.CurrencySlice) ([]string, error)
 	return result, nil
 }
 
+// Returns the current state of the proxy
+func (b *Bitfinex) Status() websocket.Status {
+	return b.Proxy.Status()
+}
+
+// Returns the channel which receives the acknowledgements of the (un)subscriptions
+func (b *Bitfinex) Acknowledgements() chan subscription.Ack {
+	return b.AckChannel
+}
+
+// Returns the currency pairs listed by Bitfinex
+func (b *Bitfinex) Pairs() currency.CurrencySlice {
+	if listed, ok := currency.Catalog.Listed("Bitfinex"); ok {
+		return listed
+	}
+
+	return pairs
+}
+
+// Fetches the markets listed by Bitfinex from its configuration endpoint
+func (b *Bitfinex) FetchMarkets() ([]currency.Market, error) {
+	resp, err := b.httpClient.Get(pairsURL)
+
+	if err != nil {
+		return nil, errors.Annotate(err, "tried to get the bitfinex pairs")
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, errors.Errorf("tried to get the bitfinex pairs: unexpected status %s", resp.Status)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+
+	if err != nil {
+		return nil, errors.Annotate(err, "cannot read the pairs given by the bitfinex api")
+	}
+
+	return parsePairs(body)
+}
+
+// Returns the markets of the list given by Bitfinex (ex: [["BTCUSD","ETHBTC"]]).
+// The list only contains the pairs which can be traded, without their sizes.
+// The pairs whose currencies are not supported are skipped.
+func parsePairs(body []byte) ([]currency.Market, error) {
+	lists := [][]string{}
+
+	if err := json.Unmarshal(body, &lists); err != nil {
+		return nil, errors.Annotate(err, "tried to unmarshal the pairs given by the bitfinex api")
+	}
+
+	result := []currency.Market{}
+
+	if len(lists) == 0 {
+		return result, nil
+	}
+
+	for _, symbol := range lists[0] {
+		base, target := splitSymbol(symbol)
+
+		// Paper trading pairs (ex: TESTBTC:TESTUSD)
+		if strings.HasPrefix(base, "TEST") {
+			continue
+		}
+
+		pair, err := currency.NewCurrencyPair(currency.Canonical("Bitfinex", base), currency.Canonical("Bitfinex", target))
+
+		if err != nil {
+			log.WithField("pair", symbol).Debug("Pair not supported")
+			continue
+		}
+
+		result = append(result, currency.Market{Pair: pair, Exchange: "Bitfinex", Status: currency.Online})
+	}
+
+	return result, nil
+}
+
 // Handles SIGINT
 func (b *Bitfinex) Interrupt() {
 	log.Debug("Closing Bitfinex")
 	b.Proxy.Interrupt()
-	b.InterruptChannel <- true
+
+	// Does not block if ListenResponse has already returned
+	select {
+	case b.InterruptChannel <- true:
+	default:
+	}
+}
+
+// Returns the codes of a Bitfinex symbol, without its trading or funding prefix
+// (ex: tBTCUSD, BTCUSD or TESTBTC:TESTUSD).
+// A symbol is either two 3 letters codes or two codes of any length separated by a colon.
+func splitSymbol(symbol string) (string, string) {
+	if len(symbol) > 0 && (symbol[0] == 't' || symbol[0] == 'f') {
+		symbol = symbol[1:]
+	}
+
+	if i := strings.Index(symbol, ":"); i >= 0 {
+		return symbol[:i], symbol[i+1:]
+	}
+
+	if len(symbol) == 6 {
+		return symbol[:3], symbol[3:]
+	}
+
+	return symbol, ""
 }
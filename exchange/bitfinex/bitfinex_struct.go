@@ -1,8 +1,12 @@
 package bitfinex
 
 import (
+	"net/http"
+
 	"github.com/fberrez/romantic-aggregator/aggregator"
+	"github.com/fberrez/romantic-aggregator/subscription"
 	"github.com/fberrez/romantic-aggregator/websocket"
+	"github.com/shopspring/decimal"
 )
 
 type Bitfinex struct {
@@ -10,6 +14,9 @@ type Bitfinex struct {
 	AggregatorChannel chan aggregator.SimpleTicker `json:"aggregator_channel"`
 	Subscriptions     []SubscribeResponse          `json:"subscriptions"`
 	InterruptChannel  chan bool                    `json:"interrupt_channel"`
+	AckChannel        chan subscription.Ack        `json:"ack_channel"`
+
+	httpClient *http.Client
 }
 
 type Message struct {
@@ -24,17 +31,17 @@ type UnsubscribeMessage struct {
 }
 
 type TickerResponse struct {
-	ChannelId       int     `json:"channel_id"`
-	Bid             float64 `json:"bid"`
-	BidSize         float64 `json:"bid_size"`
-	Ask             float64 `json:"ask"`
-	AskSize         float64 `json:"ask_size"`
-	DailyChange     float64 `json:"daily_change"`
-	DailyChangePrec float64 `json:"daily_change_prec"`
-	LastPrice       float64 `json:"last_price"`
-	Volume          float64 `json:"volume"`
-	High            float64 `json:"high"`
-	Low             float64 `json:"low"`
+	ChannelId       int             `json:"channel_id"`
+	Bid             decimal.Decimal `json:"bid"`
+	BidSize         decimal.Decimal `json:"bid_size"`
+	Ask             decimal.Decimal `json:"ask"`
+	AskSize         decimal.Decimal `json:"ask_size"`
+	DailyChange     decimal.Decimal `json:"daily_change"`
+	DailyChangePrec decimal.Decimal `json:"daily_change_prec"`
+	LastPrice       decimal.Decimal `json:"last_price"`
+	Volume          decimal.Decimal `json:"volume"`
+	High            decimal.Decimal `json:"high"`
+	Low             decimal.Decimal `json:"low"`
 }
 
 type SubscribeResponse struct {
@@ -44,6 +51,15 @@ type SubscribeResponse struct {
 	Pair    string `json:"pair"`
 }
 
+type ErrorResponse struct {
+	Event   string `json:"event"`
+	Msg     string `json:"msg"`
+	Code    int    `json:"code"`
+	Channel string `json:"channel"`
+	Symbol  string `json:"symbol"`
+	Pair    string `json:"pair"`
+}
+
 type UnsubscribeResponse struct {
 	Event  string `json:"event"`
 	Status string `json:"status"`
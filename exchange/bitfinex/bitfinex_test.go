@@ -1,9 +1,16 @@
 package bitfinex
 
 import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/fberrez/romantic-aggregator/aggregator"
+	"github.com/fberrez/romantic-aggregator/currency"
+	"github.com/fberrez/romantic-aggregator/subscription"
+	"github.com/fberrez/romantic-aggregator/websocket"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -43,9 +50,126 @@ func TestInitialize(t *testing.T) {
 	}
 }
 
+func TestManageSubscriptions(t *testing.T) {
+	b := &Bitfinex{
+		Proxy:      &websocket.Proxy{},
+		AckChannel: make(chan subscription.Ack, ackBuffer),
+	}
+
+	tables := []struct {
+		response string
+		ack      subscription.Ack
+	}{
+		{`{"event":"subscribed","channel":"ticker","chanId":42,"symbol":"tBTCUSD","pair":"BTCUSD"}`, subscription.Ack{Exchange: "Bitfinex", Action: subscription.Subscribe, Symbol: "BTCUSD", Channel: "ticker"}},
+		{`{"event":"unsubscribed","status":"OK","chanId":42}`, subscription.Ack{Exchange: "Bitfinex", Action: subscription.Unsubscribe, Symbol: "BTCUSD", Channel: "ticker"}},
+		{`{"event":"error","msg":"symbol: invalid","code":10300,"channel":"ticker","symbol":"tXXXUSD"}`, subscription.Ack{Exchange: "Bitfinex", Symbol: "XXXUSD", Channel: "ticker", Error: "symbol: invalid (code 10300)"}},
+	}
+
+	for _, table := range tables {
+		_, err := b.manageSubscriptions([]byte(table.response))
+		assert.Nil(t, err)
+		assert.Equal(t, table.ack, <-b.AckChannel)
+	}
+
+	assert.Equal(t, 0, len(b.Subscriptions))
+}
+
 func generateNewBitfinex() *Bitfinex {
 	b := &Bitfinex{}
 	b.Initialize(make(chan aggregator.SimpleTicker))
 
 	return b
 }
+
+func TestParsePairs(t *testing.T) {
+	markets, err := parsePairs([]byte(`[["BTCUSD","SOLUSD","TESTBTC:TESTUSD","ADA:USD","DOGE","XBTEUR","DOGE:USD","MATIC:UST","BTCUST"]]`))
+	assert.Nil(t, err)
+
+	pairs := []string{}
+
+	for _, market := range markets {
+		assert.Equal(t, currency.Online, market.Status)
+		pairs = append(pairs, market.Pair.String())
+	}
+
+	assert.Equal(t, []string{"BTC-USD", "SOL-USD", "ADA-USD", "BTC-EUR", "DOGE-USD", "MATIC-USDT", "BTC-USDT"}, pairs)
+
+	_, err = parsePairs([]byte(`{"error": "ratelimit"}`))
+	assert.NotNil(t, err)
+}
+
+func TestSplitSymbol(t *testing.T) {
+	tables := []struct {
+		symbol string
+		base   string
+		target string
+	}{
+		{"BTCUSD", "BTC", "USD"},
+		{"tBTCUST", "BTC", "UST"},
+		{"fUSD", "USD", ""},
+		{"tTESTBTC:TESTUSD", "TESTBTC", "TESTUSD"},
+		{"DOGE:USD", "DOGE", "USD"},
+	}
+
+	for _, table := range tables {
+		base, target := splitSymbol(table.symbol)
+		assert.Equal(t, table.base, base, table.symbol)
+		assert.Equal(t, table.target, target, table.symbol)
+	}
+}
+
+func TestMakeTickerResponse(t *testing.T) {
+	b := &Bitfinex{
+		AggregatorChannel: make(chan aggregator.SimpleTicker, 1),
+		Subscriptions:     []SubscribeResponse{{ChanId: 42, Pair: "BTCUSD"}},
+	}
+
+	response, err := b.makeTickerResponse([]byte(`[42,[0.1,12.5,0.30000000000000004,7.25,-0.02,-0.0001,0.2,1234.56789012,0.3,0.1]]`))
+	assert.Nil(t, err)
+	assert.Equal(t, "0.30000000000000004", response.Ask.String())
+
+	// The values are kept as written by Bitfinex
+	ticker := <-b.AggregatorChannel
+	assert.Equal(t, "BTCUSD", ticker.Symbol)
+	assert.Equal(t, "0.2", ticker.Price.String())
+	assert.Equal(t, "0.1", ticker.Bid.String())
+	assert.Equal(t, "1234.56789012", ticker.Volume.String())
+
+	response, err = b.makeTickerResponse([]byte(`[42,"hb"]`))
+	assert.Nil(t, err)
+	assert.Nil(t, response)
+}
+
+// Answers every request with a status code and an empty list
+type statusTransport int
+
+func (s statusTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: int(s),
+		Status:     fmt.Sprintf("%d %s", s, http.StatusText(int(s))),
+		Body:       ioutil.NopCloser(strings.NewReader("[]")),
+		Request:    r,
+	}, nil
+}
+
+func TestFetchMarkets(t *testing.T) {
+	tables := []struct {
+		status int
+		err    bool
+	}{
+		{http.StatusOK, false},
+		{http.StatusTooManyRequests, true},
+		{http.StatusBadGateway, true},
+	}
+
+	for _, table := range tables {
+		b := &Bitfinex{httpClient: &http.Client{Transport: statusTransport(table.status)}}
+		markets, err := b.FetchMarkets()
+
+		assert.Equal(t, table.err, err != nil, "%d", table.status)
+
+		if !table.err {
+			assert.Equal(t, 0, len(markets))
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package exchange
+
+import (
+	"time"
+
+	"github.com/fberrez/romantic-aggregator/currency"
+	"github.com/fberrez/romantic-aggregator/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// Period of the reloads of the product catalogs
+	CatalogRefresh time.Duration = time.Hour
+)
+
+// Loads the product catalog of an exchange in the currency Catalog.
+// The previous listing is kept if the catalog cannot be fetched or is empty.
+// In replay mode, the catalog is not fetched and the default listing is used.
+func loadCatalog(name string, fetcher Fetcher) {
+	if websocket.Replaying() {
+		log.WithField("exchange", name).Info("Replay mode: the product catalog is not fetched")
+		return
+	}
+
+	markets, err := fetcher.FetchMarkets()
+
+	if err != nil {
+		log.WithFields(logrus.Fields{"exchange": name, "error": err}).Warning("Cannot load the product catalog, the previous listing is kept")
+		return
+	}
+
+	if len(markets) == 0 {
+		log.WithField("exchange", name).Warning("Empty product catalog, the previous listing is kept")
+		return
+	}
+
+	listing := currency.Catalog.Set(name, markets)
+	log.WithFields(logrus.Fields{"exchange": name, "pairs": len(listing)}).Info("Product catalog loaded")
+}
+
+// Reloads the product catalog of each running Fetcher periodically,
+// until the FetcherGroup stops. Nothing is reloaded in replay mode.
+func (fg *FetcherGroup) refreshCatalogs() {
+	if websocket.Replaying() {
+		return
+	}
+
+	ticker := time.NewTicker(CatalogRefresh)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			for _, name := range fg.Names() {
+				if fetcher, ok := fg.fetcher(name); ok {
+					loadCatalog(name, fetcher)
+				}
+			}
+		case <-fg.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package exchange
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fberrez/romantic-aggregator/aggregator"
+	"github.com/fberrez/romantic-aggregator/kafka"
+	"github.com/fberrez/romantic-aggregator/transport"
+	"github.com/fberrez/romantic-aggregator/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplayOffline(t *testing.T) {
+	// Every request sent to the network goes through this proxy, which refuses it
+	requests := int32(0)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer proxy.Close()
+
+	transport.Set("GDAX", &transport.Options{Proxy: proxy.URL})
+	defer transport.Set("GDAX", nil)
+
+	dir, err := ioutil.TempDir("", "replay")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "gdax.jsonl.gz")
+	recorder, err := websocket.NewRecorder(path)
+	assert.Nil(t, err)
+	assert.Nil(t, recorder.Record("GDAX", websocket.Inbound, []byte(`{"type": "ticker", "product_id": "BTC-USD", "price": "6000.01", "best_bid": "6000", "best_ask": "6000.02", "volume_24h": "1234.5"}`)))
+	assert.Nil(t, recorder.Close())
+
+	websocket.SetReplay(&websocket.ReplayOptions{Path: path})
+	defer websocket.SetReplay(nil)
+
+	// The aggregates are discarded instead of being sent to Kafka
+	producer := kafka.Discard("romantic-aggregator")
+	go producer.Start()
+	defer producer.Stop()
+
+	messages := make(chan interface{}, 1)
+	a := aggregator.Initialize(messages)
+	go a.Start()
+	defer a.Stop()
+	a.SetInterval(aggregator.Interval{Duration: 50 * time.Millisecond})
+
+	fg := Initialize(a.AggregatorChannel, map[string]bool{"Bitfinex": true})
+	go fg.Start()
+	defer fg.Stop()
+
+	select {
+	case message := <-messages:
+		ticker := message.(*aggregator.Ticker)
+		assert.Equal(t, "BTCUSD", ticker.Symbol)
+		assert.Equal(t, "6000.01", ticker.Price.String())
+		assert.Equal(t, "1234.5", ticker.Volume.String())
+		assert.Nil(t, producer.SendMessage(ticker))
+	case <-time.After(5 * time.Second):
+		t.Fatal("the recorded ticker has not been aggregated")
+	}
+
+	assert.Equal(t, int32(0), atomic.LoadInt32(&requests))
+}
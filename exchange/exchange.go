@@ -1,12 +1,19 @@
 package exchange
 
 import (
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/fberrez/romantic-aggregator/aggregator"
 	"github.com/fberrez/romantic-aggregator/currency"
 	"github.com/fberrez/romantic-aggregator/exchange/bitfinex"
 	"github.com/fberrez/romantic-aggregator/exchange/gdax"
+	"github.com/fberrez/romantic-aggregator/metrics"
+	"github.com/fberrez/romantic-aggregator/subscription"
+	"github.com/fberrez/romantic-aggregator/websocket"
+	"github.com/juju/errors"
 	"github.com/sirupsen/logrus"
 )
 
@@ -27,23 +34,70 @@ type Fetcher interface {
 	// Builds a new message to send to the fetcher websocket
 	NewMessage(bool, []string, []string) error
 
+	// Returns the current state of the fetcher websocket
+	// (ex: number of messages waiting to be sent)
+	Status() websocket.Status
+
+	// Returns the channel which receives the acknowledgements
+	// (or rejections) of the (un)subscriptions sent by NewMessage
+	Acknowledgements() chan subscription.Ack
+
+	// Returns the currency pairs listed by the exchange
+	Pairs() currency.CurrencySlice
+
+	// Fetches the markets listed by the exchange from its product catalog
+	FetchMarkets() ([]currency.Market, error)
+
 	// Interrupt exchanges and Proxy
 	Interrupt()
 }
 
-// FetcherGroup contains an array of Fetcher
+// FetcherGroup contains the initialized Fetchers by name
 // and a WaitGroup (which waits for a collection of goroutines to finish)
 type FetcherGroup struct {
-	fetchers          []Fetcher
+	// Protects the Fetchers, their stop channels, the failures and the drivers
+	// being initialized, which change when an exchange is added or removed
+	mutex sync.RWMutex
+
+	fetchers          map[string]Fetcher
 	waitGroup         sync.WaitGroup
 	exchangeChannel   chan aggregator.SimpleTicker
 	aggregatorChannel chan aggregator.SimpleTicker
+
+	// Follows the (un)subscriptions until the exchanges acknowledge them
+	Tracker *Tracker
+
+	// Contains the subscriptions of every exchange
+	Registry *Registry
+
+	// Drivers which could not be initialized, retried in the background
+	failures map[string]*failure
+
+	// Drivers being initialized
+	starting map[string]bool
+
+	// Called each time a driver is added, see OnAdd
+	onAdd func(name string)
+
+	// Closed when a Fetcher is removed, by name
+	stops map[string]chan struct{}
+
+	// Closed when the Start of a Fetcher returns, by name
+	finished map[string]chan struct{}
+
+	// True once the Fetchers have been started
+	started bool
+
+	// Closed when the FetcherGroup stops
+	done chan struct{}
 }
 
 var (
-	Drivers = map[string]Fetcher{
-		"GDAX":     &gdax.GDAX{},
-		"Bitfinex": &bitfinex.Bitfinex{},
+	// Returns a new Fetcher of each driver, so that a driver
+	// restarted at runtime does not share anything with the previous one
+	Drivers = map[string]func() Fetcher{
+		"GDAX":     func() Fetcher { return &gdax.GDAX{} },
+		"Bitfinex": func() Fetcher { return &bitfinex.Bitfinex{} },
 	}
 	log *logrus.Entry = logrus.WithFields(logrus.Fields{"element": "exchange"})
 )
@@ -51,80 +105,470 @@ var (
 const (
 	Subscribe   bool = true
 	Unsubscribe bool = false
+
+	// Name of the channel which receives the tickers
+	tickerChannel string = "ticker"
+
+	// Duration after which an (un)subscription
+	// which has not been acknowledged is rejected
+	AckTimeout time.Duration = 10 * time.Second
 )
 
 // Initializes a FetcherGroup and
-// each Fetcher which are in the FetcherGroup's fetchers
-func Initialize(aggregatorChan chan aggregator.SimpleTicker) *FetcherGroup {
-	fg := &FetcherGroup{
-		fetchers:          make([]Fetcher, 0),
-		waitGroup:         sync.WaitGroup{},
-		aggregatorChannel: aggregatorChan,
-	}
+// each Fetcher which are in the FetcherGroup's fetchers.
+// The drivers in `disabled` are not initialized.
+func Initialize(aggregatorChan chan aggregator.SimpleTicker, disabled map[string]bool) *FetcherGroup {
+	fg := NewFetcherGroup(aggregatorChan, map[string]Fetcher{})
+
+	// The tickers go through the FetcherGroup,
+	// which counts them before forwarding them to the aggregator
+	for driverName, newFetcher := range Drivers {
+		if disabled[driverName] {
+			log.WithField("exchange", driverName).Info("Disabled by the configuration")
+			continue
+		}
 
-	for driverName, driver := range Drivers {
-		err := driver.Initialize(fg.aggregatorChannel)
+		driver := newFetcher()
+		err := driver.Initialize(fg.exchangeChannel)
 
 		if err != nil {
 			log.WithFields(logrus.Fields{"error": err}).Errorf("Initializing %s", driverName)
+			fg.fail(driverName, err)
 		} else {
-			fg.fetchers = append(fg.fetchers, driver)
+			loadCatalog(driverName, driver)
+			fg.fetchers[driverName] = driver
 		}
 	}
 
 	return fg
 }
 
+// Initializes a FetcherGroup with Fetchers which are already initialized
+func NewFetcherGroup(aggregatorChan chan aggregator.SimpleTicker, fetchers map[string]Fetcher) *FetcherGroup {
+	fg := &FetcherGroup{
+		fetchers:          fetchers,
+		waitGroup:         sync.WaitGroup{},
+		exchangeChannel:   make(chan aggregator.SimpleTicker),
+		aggregatorChannel: aggregatorChan,
+		Tracker:           NewTracker(AckTimeout),
+		Registry:          NewRegistry(),
+		failures:          map[string]*failure{},
+		starting:          map[string]bool{},
+		stops:             map[string]chan struct{}{},
+		finished:          map[string]chan struct{}{},
+		done:              make(chan struct{}),
+	}
+
+	fg.Tracker.OnResolve(fg.Registry.Resolve)
+
+	return fg
+}
+
 // Starts eacher Fetcher which are in the FetcherGroup's fetchers
 func (fg *FetcherGroup) Start() {
-	for index, fetcher := range fg.fetchers {
-		fg.waitGroup.Add(1)
+	go fg.forwardTickers()
+	go fg.retryFailures()
+	go fg.refreshCatalogs()
 
-		go func(fetcher Fetcher) {
-			defer fg.waitGroup.Done()
-			err := fetcher.Start()
+	fg.mutex.Lock()
+	fg.started = true
 
-			if err != nil {
-				log.WithFields(logrus.Fields{"error": err}).Errorf("Trying to start #%v", index)
-			}
-		}(fetcher)
+	for name, fetcher := range fg.fetchers {
+		fg.start(name, fetcher)
 	}
 
+	fg.mutex.Unlock()
+
 	fg.waitGroup.Wait()
 }
 
-// Sends message to each Fetcher's websocket
-func (fg *FetcherGroup) SendMessage(isSubscribe bool, productIds currency.CurrencySlice, channels []string) []error {
-	errors := []error{}
+// Starts a Fetcher and the goroutine which listens to its acknowledgements.
+// The mutex must be locked.
+func (fg *FetcherGroup) start(name string, fetcher Fetcher) {
+	stop, finished := make(chan struct{}), make(chan struct{})
+	fg.stops[name] = stop
+	fg.finished[name] = finished
+
+	go fg.listenAcknowledgements(fetcher, stop)
+
+	fg.waitGroup.Add(1)
+	go func() {
+		defer fg.waitGroup.Done()
+		defer close(finished)
+		err := fetcher.Start()
+
+		if err != nil {
+			log.WithFields(logrus.Fields{"error": err}).Errorf("Trying to start %s", name)
+		}
+	}()
+}
+
+// Initializes a driver which is not running (case insensitive) and starts it
+// if the FetcherGroup has been started.
+// A driver which cannot be initialized is retried in the background.
+func (fg *FetcherGroup) Add(name string) error {
+	return fg.add(name, false)
+}
+
+// Initializes a driver. A retry is skipped if the driver
+// has been removed or added in the meantime.
+func (fg *FetcherGroup) add(name string, retry bool) error {
+	name, newFetcher, ok := findDriver(name)
+
+	if !ok {
+		return errors.NotFoundf("exchange driver %s", name)
+	}
+
+	fg.mutex.Lock()
+	_, exists := fg.fetchers[name]
+	_, failed := fg.failures[name]
+
+	if retry && (exists || !failed) {
+		fg.mutex.Unlock()
+		return nil
+	}
+
+	if exists || fg.starting[name] {
+		fg.mutex.Unlock()
+		return errors.AlreadyExistsf("exchange %s", name)
+	}
+
+	fg.starting[name] = true
+	fg.mutex.Unlock()
+
+	// The connection is opened without holding the mutex
+	driver := newFetcher()
+	err := driver.Initialize(fg.exchangeChannel)
+
+	if err == nil {
+		loadCatalog(name, driver)
+	}
+
+	fg.mutex.Lock()
+	delete(fg.starting, name)
+
+	if err != nil {
+		fg.fail(name, err)
+		fg.mutex.Unlock()
+
+		return errors.Annotatef(err, "tried to initialize %s", name)
+	}
+
+	delete(fg.failures, name)
+	fg.fetchers[name] = driver
+
+	if fg.started {
+		fg.start(name, driver)
+	}
+
+	onAdd := fg.onAdd
+	fg.mutex.Unlock()
+
+	log.WithField("exchange", name).Info("Exchange added")
+
+	if onAdd != nil {
+		onAdd(name)
+	}
+
+	return nil
+}
+
+// Stops a Fetcher (case insensitive), waits for it to return
+// and forgets its subscriptions.
+// A driver which could not be initialized is no longer retried.
+// The other Fetchers are left untouched.
+func (fg *FetcherGroup) Remove(name string) error {
+	name, _, _ = findDriver(name)
+
+	fg.mutex.Lock()
+	fetcher, ok := fg.fetchers[name]
+	_, failed := fg.failures[name]
+	stop := fg.stops[name]
+	finished := fg.finished[name]
+
+	if fg.starting[name] {
+		fg.mutex.Unlock()
+		return errors.NotValidf("stopping %s while it is starting", name)
+	}
+
+	delete(fg.fetchers, name)
+	delete(fg.failures, name)
+	delete(fg.stops, name)
+	delete(fg.finished, name)
+	fg.mutex.Unlock()
+
+	if !ok {
+		if failed {
+			log.WithField("exchange", name).Info("Exchange no longer retried")
+			return nil
+		}
+
+		return errors.NotFoundf("exchange %s", name)
+	}
+
+	// A Fetcher which has not been started does not listen to the interruptions
+	if stop != nil {
+		close(stop)
+		fetcher.Interrupt()
+		<-finished
+	}
+
+	fg.Registry.Forget(name)
+	log.WithField("exchange", name).Info("Exchange removed")
+
+	return nil
+}
+
+// Forwards the acknowledgements sent by a Fetcher to the tracker
+// until the Fetcher is removed or the FetcherGroup stops
+func (fg *FetcherGroup) listenAcknowledgements(fetcher Fetcher, stop chan struct{}) {
+	for {
+		select {
+		case ack := <-fetcher.Acknowledgements():
+			fg.Tracker.Acknowledge(ack)
+		case <-stop:
+			return
+		case <-fg.done:
+			return
+		}
+	}
+}
+
+// Counts the tickers sent by the Fetchers in the registry
+// and forwards them to the aggregator
+func (fg *FetcherGroup) forwardTickers() {
+	for {
+		select {
+		case ticker := <-fg.exchangeChannel:
+			fg.Registry.Received(ticker.Exchange, ticker.Pair(), tickerChannel)
+			metrics.MessagesReceived.WithLabelValues(ticker.Exchange, tickerChannel).Inc()
+
+			select {
+			case fg.aggregatorChannel <- ticker:
+			case <-fg.done:
+				return
+			}
+		case <-fg.done:
+			return
+		}
+	}
+}
+
+// Returns the subscriptions of an exchange,
+// or of every exchange if `exchange` is empty
+func (fg *FetcherGroup) Subscriptions(exchange string) ([]Entry, error) {
+	if exchange == "" {
+		return fg.Registry.Entries(), nil
+	}
+
+	name, err := fg.find(exchange)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return fg.Registry.Entries(name), nil
+}
+
+// Returns the names of the initialized Fetchers, sorted alphabetically
+func (fg *FetcherGroup) Names() []string {
+	fg.mutex.RLock()
+	defer fg.mutex.RUnlock()
+
+	names := []string{}
+
+	for name := range fg.fetchers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Returns the names of the Fetchers which list at least one of the currency pairs
+func (fg *FetcherGroup) Listings(pairs currency.CurrencySlice) []string {
+	names := []string{}
+
+	for _, name := range fg.Names() {
+		if len(fg.Pairs(name).Intersect(pairs)) > 0 {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// Returns the currency pairs listed by a Fetcher
+func (fg *FetcherGroup) Pairs(name string) currency.CurrencySlice {
+	fetcher, ok := fg.fetcher(name)
+
+	if !ok {
+		return currency.CurrencySlice{}
+	}
+
+	return fetcher.Pairs()
+}
+
+// Returns the Fetcher named `name`, false if it is not running
+func (fg *FetcherGroup) fetcher(name string) (Fetcher, bool) {
+	fg.mutex.RLock()
+	defer fg.mutex.RUnlock()
+
+	fetcher, ok := fg.fetchers[name]
+	return fetcher, ok
+}
+
+// Returns the name of the Fetcher matching `name` (case insensitive)
+func (fg *FetcherGroup) find(name string) (string, error) {
+	fg.mutex.RLock()
+	defer fg.mutex.RUnlock()
+
+	for fetcherName := range fg.fetchers {
+		if strings.EqualFold(fetcherName, name) {
+			return fetcherName, nil
+		}
+	}
+
+	return "", errors.NotFoundf("exchange %s", name)
+}
+
+// Returns the names of the Fetchers in `exchanges` (case insensitive).
+// If `exchanges` is empty, returns the Fetchers which list
+// at least one of the currency pairs.
+func (fg *FetcherGroup) Targets(pairs currency.CurrencySlice, exchanges []string) ([]string, error) {
+	targets := []string{}
+
+	for _, exchange := range exchanges {
+		name, err := fg.find(exchange)
+
+		if err != nil {
+			return nil, err
+		}
+
+		targets = append(targets, name)
+	}
+
+	// "all" means all the exchanges which list the currency pairs
+	if len(targets) == 0 {
+		targets = fg.Listings(pairs)
+
+		if len(targets) == 0 {
+			return nil, errors.NotFoundf("exchange listing %s", pairs.ToString())
+		}
+	}
+
+	return targets, nil
+}
+
+// Sends message to the websocket of each Fetcher in `exchanges`.
+// If `exchanges` is empty, the message is sent to every Fetcher
+// which lists at least one of the currency pairs.
+// Returns the request which follows the acknowledgement of each message.
+func (fg *FetcherGroup) SendMessage(isSubscribe bool, productIds currency.CurrencySlice, channels []string, exchanges []string) (*Request, error) {
+	targets, err := fg.Targets(productIds, exchanges)
+
+	if err != nil {
+		return nil, err
+	}
 
 	log.WithFields(logrus.Fields{
 		"subscribe":   isSubscribe,
 		"product_ids": productIds.ToString(),
 		"channels":    channels,
+		"exchanges":   targets,
 	}).Info("Update subscriptions")
 
-	for index, fetcher := range fg.fetchers {
-		formattedCurrencie, err := fetcher.TranslateCurrency(productIds)
+	request := fg.Tracker.NewRequest(subscription.ActionOf(isSubscribe))
+
+	for _, name := range targets {
+		fetcher, ok := fg.fetcher(name)
+
+		// The Fetcher has been removed in the meantime
+		if !ok {
+			fg.Tracker.Reject(request, name, errors.NotFoundf("exchange %s", name))
+			continue
+		}
+
+		listed := fetcher.Pairs().Intersect(productIds)
+
+		// The currency pairs which are not listed are rejected without being sent
+		for _, pair := range productIds {
+			if !listed.Contains(pair) {
+				fg.Tracker.AddRejected(request, name, []string{pair.String()}, channels, errors.NotSupportedf("%s on %s", pair, name))
+			}
+		}
+
+		if len(listed) == 0 {
+			continue
+		}
+
+		formattedCurrencie, err := fetcher.TranslateCurrency(listed)
 
 		if err != nil {
-			log.WithFields(logrus.Fields{"error": err}).Errorf("Trying to send a message to #%d", index)
-			errors = append(errors, err)
+			log.WithFields(logrus.Fields{"error": err}).Errorf("Trying to send a message to %s", name)
+			fg.Tracker.Reject(request, name, err)
+			continue
+		}
+
+		fg.Tracker.Add(request, name, formattedCurrencie, channels)
+		request.Exchanges = append(request.Exchanges, name)
+
+		if isSubscribe {
+			fg.Registry.Add(name, listed.Strings(), formattedCurrencie, channels)
 		}
 
 		err = fetcher.NewMessage(isSubscribe, formattedCurrencie, channels)
 
 		if err != nil {
-			log.WithFields(logrus.Fields{"error": err}).Errorf("Trying to send a message to #%d:", index)
-			errors = append(errors, err)
+			log.WithFields(logrus.Fields{"error": err}).Errorf("Trying to send a message to %s", name)
+			fg.Tracker.Reject(request, name, err)
 		}
 	}
 
-	return errors
+	fg.Tracker.Send(request)
+
+	return request, nil
+}
+
+// Returns the errors of the drivers which could not be initialized
+func (fg *FetcherGroup) Failures() map[string]error {
+	fg.mutex.RLock()
+	defer fg.mutex.RUnlock()
+
+	failures := map[string]error{}
+
+	for name, failure := range fg.failures {
+		failures[name] = failure.err
+	}
+
+	return failures
+}
+
+// Returns the state of each Fetcher's websocket
+func (fg *FetcherGroup) Status() []websocket.Status {
+	status := []websocket.Status{}
+
+	for _, name := range fg.Names() {
+		if fetcher, ok := fg.fetcher(name); ok {
+			status = append(status, fetcher.Status())
+		}
+	}
+
+	return status
 }
 
 func (fg *FetcherGroup) Stop() {
 	log.Info("Closing exchanges")
-	for _, fetcher := range fg.fetchers {
-		fetcher.Interrupt()
+	close(fg.done)
+
+	fg.mutex.RLock()
+	defer fg.mutex.RUnlock()
+
+	for name, fetcher := range fg.fetchers {
+		// A Fetcher which has not been started does not listen to the interruptions
+		if fg.stops[name] != nil {
+			fetcher.Interrupt()
+		}
 	}
 }
@@ -0,0 +1,159 @@
+package exchange
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fberrez/romantic-aggregator/aggregator"
+	"github.com/fberrez/romantic-aggregator/currency"
+	"github.com/fberrez/romantic-aggregator/subscription"
+	"github.com/fberrez/romantic-aggregator/websocket"
+	"github.com/juju/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// Fetcher which records the messages instead of sending them
+type fakeFetcher struct {
+	label    string
+	pairs    currency.CurrencySlice
+	messages [][]string
+}
+
+func (f *fakeFetcher) Initialize(chan aggregator.SimpleTicker) error { return nil }
+func (f *fakeFetcher) Start() error                                  { return nil }
+func (f *fakeFetcher) Interrupt()                                    {}
+func (f *fakeFetcher) Pairs() currency.CurrencySlice                 { return f.pairs }
+func (f *fakeFetcher) Status() websocket.Status                      { return websocket.Status{Label: f.label} }
+func (f *fakeFetcher) Acknowledgements() chan subscription.Ack       { return nil }
+func (f *fakeFetcher) FetchMarkets() ([]currency.Market, error)      { return nil, nil }
+
+func (f *fakeFetcher) TranslateCurrency(pairs currency.CurrencySlice) ([]string, error) {
+	return pairs.ToGDAX()
+}
+
+func (f *fakeFetcher) NewMessage(isSubscribe bool, productIds []string, channels []string) error {
+	f.messages = append(f.messages, productIds)
+	return nil
+}
+
+func newFakeGroup() *FetcherGroup {
+	return &FetcherGroup{
+		fetchers: map[string]Fetcher{
+			"GDAX":     &fakeFetcher{label: "GDAX", pairs: currency.CurrencySlice{currency.BTCUSD, currency.LTCEUR}},
+			"Bitfinex": &fakeFetcher{label: "Bitfinex", pairs: currency.CurrencySlice{currency.BTCUSD}},
+		},
+		Tracker:  NewTracker(time.Hour),
+		Registry: NewRegistry(),
+		done:     make(chan struct{}),
+	}
+}
+
+func TestSendMessage(t *testing.T) {
+	tables := []struct {
+		pairs     currency.CurrencySlice
+		exchanges []string
+		outcomes  map[string]subscription.Status
+		messages  map[string][][]string
+		err       string
+	}{
+		// Every exchange listing the pair is targeted by default
+		{
+			currency.CurrencySlice{currency.BTCUSD}, []string{},
+			map[string]subscription.Status{"Bitfinex": subscription.Pending, "GDAX": subscription.Pending},
+			map[string][][]string{"Bitfinex": {{"BTC-USD"}}, "GDAX": {{"BTC-USD"}}},
+			"",
+		},
+		{
+			currency.CurrencySlice{currency.LTCEUR}, []string{},
+			map[string]subscription.Status{"GDAX": subscription.Pending},
+			map[string][][]string{"GDAX": {{"LTC-EUR"}}},
+			"",
+		},
+		// The exchange names are case insensitive
+		{
+			currency.CurrencySlice{currency.BTCUSD}, []string{"gdax"},
+			map[string]subscription.Status{"GDAX": subscription.Pending},
+			map[string][][]string{"GDAX": {{"BTC-USD"}}},
+			"",
+		},
+		// A pair which is not listed is rejected without being sent
+		{
+			currency.CurrencySlice{currency.LTCEUR}, []string{"Bitfinex"},
+			map[string]subscription.Status{"Bitfinex": subscription.Rejected},
+			map[string][][]string{},
+			"",
+		},
+		{currency.CurrencySlice{currency.BTCUSD}, []string{"Kraken"}, nil, nil, "notFound"},
+		{currency.CurrencySlice{currency.ETHEUR}, []string{}, nil, nil, "notFound"},
+	}
+
+	for _, table := range tables {
+		fg := newFakeGroup()
+		request, err := fg.SendMessage(Subscribe, table.pairs, []string{"ticker"}, table.exchanges)
+
+		switch table.err {
+		case "notFound":
+			assert.True(t, errors.IsNotFound(err))
+			continue
+		case "":
+			assert.Nil(t, err)
+		}
+
+		outcomes := map[string]subscription.Status{}
+		for _, outcome := range fg.Tracker.Outcomes(request) {
+			outcomes[outcome.Exchange] = outcome.Status
+		}
+
+		messages := map[string][][]string{}
+		for name, fetcher := range fg.fetchers {
+			if sent := fetcher.(*fakeFetcher).messages; len(sent) > 0 {
+				messages[name] = sent
+			}
+		}
+
+		assert.Equal(t, table.outcomes, outcomes)
+		assert.Equal(t, table.messages, messages)
+	}
+}
+
+// Fetcher whose initialization fails
+type brokenFetcher struct {
+	fakeFetcher
+}
+
+func (f *brokenFetcher) Initialize(chan aggregator.SimpleTicker) error {
+	return errors.New("connection refused")
+}
+
+func TestAddRemove(t *testing.T) {
+	drivers := Drivers
+	defer func() { Drivers = drivers }()
+
+	Drivers = map[string]func() Fetcher{
+		"GDAX":     func() Fetcher { return &fakeFetcher{label: "GDAX", pairs: currency.CurrencySlice{currency.BTCUSD}} },
+		"Bitfinex": func() Fetcher { return &brokenFetcher{fakeFetcher{label: "Bitfinex"}} },
+	}
+
+	fg := NewFetcherGroup(nil, map[string]Fetcher{})
+
+	assert.Nil(t, fg.Add("GDAX"))
+	assert.True(t, errors.IsAlreadyExists(fg.Add("GDAX")))
+	assert.True(t, errors.IsNotFound(fg.Add("Kraken")))
+	assert.NotNil(t, fg.Add("Bitfinex"))
+	assert.Equal(t, []string{"GDAX"}, fg.Names())
+	assert.Contains(t, fg.Failures(), "Bitfinex")
+
+	_, err := fg.SendMessage(Subscribe, currency.CurrencySlice{currency.BTCUSD}, []string{"ticker"}, []string{"GDAX"})
+	assert.Nil(t, err)
+	assert.Len(t, fg.Registry.Entries("GDAX"), 1)
+
+	// The subscriptions of a removed exchange are forgotten
+	assert.Nil(t, fg.Remove("GDAX"))
+	assert.Empty(t, fg.Names())
+	assert.Empty(t, fg.Registry.Entries("GDAX"))
+	assert.True(t, errors.IsNotFound(fg.Remove("GDAX")))
+
+	// Removing a driver which failed forgets its failure
+	assert.Nil(t, fg.Remove("Bitfinex"))
+	assert.Empty(t, fg.Failures())
+}
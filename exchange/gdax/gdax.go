@@ -4,16 +4,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"net/http"
 	"net/url"
 	"reflect"
-	"strconv"
-	"time"
+	"strings"
 
 	"github.com/fberrez/romantic-aggregator/aggregator"
 	"github.com/fberrez/romantic-aggregator/currency"
+	"github.com/fberrez/romantic-aggregator/metrics"
+	"github.com/fberrez/romantic-aggregator/subscription"
+	"github.com/fberrez/romantic-aggregator/transport"
 	"github.com/fberrez/romantic-aggregator/websocket"
 	"github.com/juju/errors"
+	"github.com/shopspring/decimal"
 	"github.com/sirupsen/logrus"
 )
 
@@ -26,11 +28,26 @@ const (
 
 	Subscribe   string = "subscribe"
 	Unsubscribe string = "unsubscribe"
+
+	Subscriptions string = "subscriptions"
+	Error         string = "error"
+
+	// Number of acknowledgements waiting to be processed
+	ackBuffer = 64
 )
 
 var (
+	// Currency pairs listed by GDAX until its product catalog is loaded
+	pairs currency.CurrencySlice = currency.CurrencySlice{currency.BCHBTC, currency.BCHUSD, currency.BTCEUR, currency.BTCGBP, currency.BTCUSD, currency.ETHBTC, currency.ETHEUR, currency.ETHUSD, currency.LTCBTC, currency.LTCEUR}
+
+	// Coinbase allows 8 messages per second with bursts of 20 messages
+	limits websocket.Limits = websocket.Limits{Rate: 8, Burst: 20, QueueSize: 64}
+
 	uri url.URL       = url.URL{Scheme: "wss", Host: "ws-feed.pro.coinbase.com", Path: "/"}
 	log *logrus.Entry = logrus.WithFields(logrus.Fields{"element": "exchange", "label": "GDAX"})
+
+	// Product catalog of GDAX
+	productsURL string = "https://api.pro.coinbase.com/products"
 )
 
 // Initializes the GDAX struct
@@ -38,9 +55,20 @@ func (g *GDAX) Initialize(aggregatorChan chan aggregator.SimpleTicker) error {
 	log.Infof("Initializing")
 	g.AggregatorChannel = aggregatorChan
 	g.Proxy = &websocket.Proxy{
-		Label: "GDAX",
+		Label:  "GDAX",
+		Limits: limits,
 	}
-	g.InterruptChannel = make(chan bool)
+	g.InterruptChannel = make(chan bool, 1)
+	g.AckChannel = make(chan subscription.Ack, ackBuffer)
+	g.subscribed = map[subscriptionKey]bool{}
+
+	client, err := transport.NewHTTPClient("GDAX")
+
+	if err != nil {
+		return errors.Annotate(err, "tried to build the gdax http client")
+	}
+
+	g.httpClient = client
 
 	return g.Proxy.Initialize(uri)
 }
@@ -73,6 +101,7 @@ func (g *GDAX) ListenResponse() {
 			_, err := g.makeResponse(response)
 
 			if err != nil {
+				metrics.ParseErrors.WithLabelValues("GDAX").Inc()
 				log.WithFields(logrus.Fields{
 					"action": "listening to the responses sent by websocket",
 				}).Errorf("%v", err)
@@ -118,9 +147,7 @@ func (g *GDAX) sendMessage(message Message) error {
 		return errors.Annotatef(err, "tried to send message %v", message)
 	}
 
-	g.Proxy.MessageChannel <- marshalledMessage
-
-	return nil
+	return g.Proxy.Send(marshalledMessage)
 }
 
 // Parses the response received to a JSON struct
@@ -133,10 +160,15 @@ func (g *GDAX) makeResponse(b []byte) (interface{}, error) {
 	}
 
 	// If it is a subscriptions feedback
-	if response.Type == "subscriptions" {
+	if response.Type == Subscriptions {
 		return g.manageSubscriptions(b)
 	}
 
+	// If a message has been rejected
+	if response.Type == Error {
+		return g.manageError(b)
+	}
+
 	if response.Type == "ticker" {
 		tickerResponse := &TickerResponse{}
 		err = json.Unmarshal(b, &tickerResponse)
@@ -153,27 +185,48 @@ func (g *GDAX) makeResponse(b []byte) (interface{}, error) {
 
 // Parses and send a new ticker response to aggregator
 func (g *GDAX) parseAndSendTickerResponseToAggregator(t *TickerResponse) (*aggregator.SimpleTicker, error) {
-	price, err := strconv.ParseFloat(t.Price, 64)
-	bid, err := strconv.ParseFloat(t.BestBid, 64)
-	ask, err := strconv.ParseFloat(t.BestAsk, 64)
+	// The decimals are kept as written by GDAX
+	values := []decimal.Decimal{}
 
-	if err != nil {
-		return nil, errors.Annotatef(err, "tried make an new ticker response %v", t)
+	for _, value := range []string{t.Price, t.BestBid, t.BestAsk} {
+		d, err := decimal.NewFromString(value)
+
+		if err != nil {
+			return nil, errors.Annotatef(err, "tried make an new ticker response %v", t)
+		}
+
+		values = append(values, d)
 	}
 
-	volume, err := getVolume(t.ProductId)
+	price, bid, ask := values[0], values[1], values[2]
+	volume, err := g.volumeOf(t)
 
-	if err != nil || volume == 0.0 {
+	if err != nil || volume.IsZero() {
 		return nil, err
 	}
 
+	codes := strings.SplitN(t.ProductId, "-", 2)
+
+	if len(codes) != 2 {
+		return nil, errors.NotValidf("product id %q", t.ProductId)
+	}
+
+	pair, err := currency.FindNativePair("GDAX", codes[0], codes[1])
+
+	if err != nil {
+		return nil, errors.Annotatef(err, "tried to find the currency pair of %s", t.ProductId)
+	}
+
 	aggregatorTicker := &aggregator.SimpleTicker{
-		Exchange: "GDAX",
-		Symbol:   fmt.Sprintf("%s%s", t.ProductId[:3], t.ProductId[4:]),
-		Price:    price,
-		Bid:      bid,
-		Ask:      ask,
-		Volume:   volume,
+		Exchange:     "GDAX",
+		Symbol:       pair.Symbol(),
+		Base:         pair.Base,
+		Quote:        pair.Quote,
+		NativeSymbol: t.ProductId,
+		Price:        currency.NewDecimal(price),
+		Bid:          currency.NewDecimal(bid),
+		Ask:          currency.NewDecimal(ask),
+		Volume:       currency.NewDecimal(volume),
 	}
 
 	g.AggregatorChannel <- *aggregatorTicker
@@ -181,36 +234,53 @@ func (g *GDAX) parseAndSendTickerResponseToAggregator(t *TickerResponse) (*aggre
 	return aggregatorTicker, nil
 }
 
-// Returns the volume for a specific currency pair
-func getVolume(symbol string) (float64, error) {
-	client := &http.Client{
-		Timeout: time.Second * 10,
+// Returns the 24h volume sent with the ticker.
+// It is only fetched from the api if the ticker does not have any,
+// and never in replay mode.
+func (g *GDAX) volumeOf(t *TickerResponse) (decimal.Decimal, error) {
+	if t.Volume24h != "" {
+		volume, err := decimal.NewFromString(t.Volume24h)
+
+		if err != nil {
+			return decimal.Zero, errors.Annotatef(err, "tried to parse the volume of %s", t.ProductId)
+		}
+
+		return volume, nil
 	}
 
-	resp, err := client.Get(fmt.Sprintf("https://api.pro.coinbase.com/products/%s/ticker", symbol))
+	if websocket.Replaying() {
+		return decimal.Zero, errors.NotFoundf("volume of %s in the recorded ticker", t.ProductId)
+	}
+
+	return g.getVolume(t.ProductId)
+}
+
+// Returns the volume for a specific currency pair
+func (g *GDAX) getVolume(symbol string) (decimal.Decimal, error) {
+	resp, err := g.httpClient.Get(fmt.Sprintf("https://api.pro.coinbase.com/products/%s/ticker", symbol))
 
 	if err != nil {
-		return 0.0, errors.Annotatef(err, "tried to get volume of %s", symbol)
+		return decimal.Zero, errors.Annotatef(err, "tried to get volume of %s", symbol)
 	}
 
 	defer resp.Body.Close()
 	body, err := ioutil.ReadAll(resp.Body)
 
 	if err != nil {
-		return 0.0, errors.Annotatef(err, "cannot read the response given by the gdax api about %s", symbol)
+		return decimal.Zero, errors.Annotatef(err, "cannot read the response given by the gdax api about %s", symbol)
 	}
 
 	tickerApiResponse := &TickerApiResponse{}
 	err = json.Unmarshal(body, tickerApiResponse)
 
 	if err != nil {
-		return 0.0, errors.Annotatef(err, "tried to unmarshal the response given by the gdax api about %s", symbol)
+		return decimal.Zero, errors.Annotatef(err, "tried to unmarshal the response given by the gdax api about %s", symbol)
 	}
 
-	volume, err := strconv.ParseFloat(tickerApiResponse.Volume, 64)
+	volume, err := decimal.NewFromString(tickerApiResponse.Volume)
 
 	if err != nil {
-		return 0.0, errors.Annotatef(err, "tried to parse the volume")
+		return decimal.Zero, errors.Annotatef(err, "tried to parse the volume")
 	}
 
 	return volume, nil
@@ -231,13 +301,18 @@ func (g *GDAX) manageSubscriptions(b []byte) (interface{}, error) {
 		Type: Subscribe,
 	}
 
+	subscribed := map[subscriptionKey]bool{}
+
 	for _, channel := range subscriptionResponse.Channels {
 		subscriptionMessage.Channels = append(subscriptionMessage.Channels, channel.Name)
 		for _, productId := range channel.ProductIds {
 			subscriptionMessage.ProductIds = append(subscriptionMessage.ProductIds, productId)
+			subscribed[subscriptionKey{channel.Name, productId}] = true
 		}
 	}
 
+	g.acknowledge(subscribed)
+
 	subscriptionMessageByte, err := json.Marshal(subscriptionMessage)
 
 	if err != nil {
@@ -254,6 +329,58 @@ func (g *GDAX) manageSubscriptions(b []byte) (interface{}, error) {
 	return subscriptionMessage, nil
 }
 
+// Sends an acknowledgement for each current subscription,
+// and for each subscription which has been removed since the last feedback
+func (g *GDAX) acknowledge(subscribed map[subscriptionKey]bool) {
+	for key := range subscribed {
+		subscription.Send(g.AckChannel, subscription.Ack{
+			Exchange: "GDAX",
+			Action:   subscription.Subscribe,
+			Symbol:   key.productId,
+			Channel:  key.channel,
+		})
+	}
+
+	for key := range g.subscribed {
+		if !subscribed[key] {
+			subscription.Send(g.AckChannel, subscription.Ack{
+				Exchange: "GDAX",
+				Action:   subscription.Unsubscribe,
+				Symbol:   key.productId,
+				Channel:  key.channel,
+			})
+		}
+	}
+
+	g.subscribed = subscribed
+}
+
+// Processes an error sent by the websocket
+// (ex: {"type":"error","message":"Failed to subscribe","reason":"ETH-XXX is not a valid product"}).
+// GDAX does not tell which message has been rejected:
+// the oldest pending (un)subscription is rejected.
+func (g *GDAX) manageError(b []byte) (interface{}, error) {
+	errorResponse := &ErrorResponse{}
+
+	if err := json.Unmarshal(b, errorResponse); err != nil {
+		return nil, errors.Annotatef(err, "tried to parse an error response %v", string(b))
+	}
+
+	log.WithFields(logrus.Fields{"error": *errorResponse}).Warn("Error sent by the websocket")
+
+	reason := errorResponse.Message
+	if errorResponse.Reason != "" {
+		reason = fmt.Sprintf("%s: %s", errorResponse.Message, errorResponse.Reason)
+	}
+
+	subscription.Send(g.AckChannel, subscription.Ack{
+		Exchange: "GDAX",
+		Error:    reason,
+	})
+
+	return errorResponse, nil
+}
+
 // Returns false if at least one of these condition is verified:
 // 	- The GDAX structure has not been initialized
 // 	- The Kafka channel has not been initialized
@@ -293,9 +420,108 @@ func (g *GDAX) TranslateCurrency(c currency.CurrencySlice) ([]string, error) {
 	return result, nil
 }
 
+// Returns the current state of the proxy
+func (g *GDAX) Status() websocket.Status {
+	return g.Proxy.Status()
+}
+
+// Returns the channel which receives the acknowledgements of the (un)subscriptions
+func (g *GDAX) Acknowledgements() chan subscription.Ack {
+	return g.AckChannel
+}
+
+// Returns the currency pairs listed by GDAX
+func (g *GDAX) Pairs() currency.CurrencySlice {
+	if listed, ok := currency.Catalog.Listed("GDAX"); ok {
+		return listed
+	}
+
+	return pairs
+}
+
+// Fetches the markets listed by GDAX from its product catalog
+func (g *GDAX) FetchMarkets() ([]currency.Market, error) {
+	resp, err := g.httpClient.Get(productsURL)
+
+	if err != nil {
+		return nil, errors.Annotate(err, "tried to get the gdax products")
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, errors.Errorf("tried to get the gdax products: unexpected status %s", resp.Status)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+
+	if err != nil {
+		return nil, errors.Annotate(err, "cannot read the products given by the gdax api")
+	}
+
+	return parseProducts(body)
+}
+
+// Returns the markets of the products, with their increments and status.
+// The products whose currencies are not supported are skipped.
+func parseProducts(body []byte) ([]currency.Market, error) {
+	products := []Product{}
+
+	if err := json.Unmarshal(body, &products); err != nil {
+		return nil, errors.Annotate(err, "tried to unmarshal the products given by the gdax api")
+	}
+
+	result := []currency.Market{}
+
+	for _, product := range products {
+		pair, err := currency.NewCurrencyPair(currency.Canonical("GDAX", product.BaseCurrency), currency.Canonical("GDAX", product.QuoteCurrency))
+
+		if err != nil {
+			log.WithField("product", product.Id).Debug("Product not supported")
+			continue
+		}
+
+		market := currency.Market{
+			Pair:        pair,
+			Exchange:    "GDAX",
+			TickSize:    parseSize(product.QuoteIncrement),
+			LotSize:     parseSize(product.BaseIncrement),
+			MinNotional: parseSize(product.MinMarketFunds),
+			Status:      currency.Online,
+		}
+
+		switch {
+		case product.Status != "online":
+			market.Status = currency.Offline
+		case product.TradingDisabled:
+			market.Status = currency.Halted
+		}
+
+		result = append(result, market)
+	}
+
+	return result, nil
+}
+
+// Parses a size given by the gdax api (ex: "0.01"), 0 if it is empty or not valid
+func parseSize(size string) currency.Decimal {
+	value, err := decimal.NewFromString(size)
+
+	if err != nil {
+		return currency.Decimal{}
+	}
+
+	return currency.NewDecimal(value)
+}
+
 // Handles SIGINT
 func (g *GDAX) Interrupt() {
 	log.Debug("Closing GDAX")
 	g.Proxy.Interrupt()
-	g.InterruptChannel <- true
+
+	// Does not block if ListenResponse has already returned
+	select {
+	case g.InterruptChannel <- true:
+	default:
+	}
 }
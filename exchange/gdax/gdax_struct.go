@@ -1,7 +1,10 @@
 package gdax
 
 import (
+	"net/http"
+
 	"github.com/fberrez/romantic-aggregator/aggregator"
+	"github.com/fberrez/romantic-aggregator/subscription"
 	"github.com/fberrez/romantic-aggregator/websocket"
 )
 
@@ -10,6 +13,19 @@ type GDAX struct {
 	AggregatorChannel chan aggregator.SimpleTicker `json:"aggregator_channel"`
 	Subscriptions     *Message                     `json:"subscriptions"`
 	InterruptChannel  chan bool                    `json:"interrupt_channel"`
+	AckChannel        chan subscription.Ack        `json:"ack_channel"`
+
+	httpClient *http.Client
+
+	// Current subscriptions by channel and product id,
+	// used to detect which ones have been removed
+	subscribed map[subscriptionKey]bool
+}
+
+// Identifies the subscription of a product id to a channel
+type subscriptionKey struct {
+	channel   string
+	productId string
 }
 
 type Message struct {
@@ -32,6 +48,24 @@ type Response struct {
 	Type string `json:"type"`
 }
 
+type ErrorResponse struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+	Reason  string `json:"reason"`
+}
+
+// Product listed by GET /products
+type Product struct {
+	Id              string `json:"id"`
+	BaseCurrency    string `json:"base_currency"`
+	QuoteCurrency   string `json:"quote_currency"`
+	Status          string `json:"status"`
+	TradingDisabled bool   `json:"trading_disabled"`
+	QuoteIncrement  string `json:"quote_increment"`
+	BaseIncrement   string `json:"base_increment"`
+	MinMarketFunds  string `json:"min_market_funds"`
+}
+
 type TickerResponse struct {
 	Type      string `json:"type"`
 	Sequence  int    `json:"sequence"`
@@ -1 +1,81 @@
 package gdax
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/fberrez/romantic-aggregator/currency"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseProducts(t *testing.T) {
+	body := `[
+		{"id": "BTC-USD", "base_currency": "BTC", "quote_currency": "USD", "status": "online", "quote_increment": "0.01", "base_increment": "0.00000001", "min_market_funds": "1"},
+		{"id": "SOL-EUR", "base_currency": "SOL", "quote_currency": "EUR", "status": "online"},
+		{"id": "SHIB-USD", "base_currency": "SHIB", "quote_currency": "USD", "status": "online"},
+		{"id": "MATIC-USDT", "base_currency": "MATIC", "quote_currency": "USDT", "status": "online"},
+		{"id": "BTC-USD-OLD", "base_currency": "BTC", "quote_currency": "", "status": "online"},
+		{"id": "XRP-USD", "base_currency": "XRP", "quote_currency": "USD", "status": "delisted"},
+		{"id": "ETH-DAI", "base_currency": "ETH", "quote_currency": "DAI", "status": "online", "trading_disabled": true}
+	]`
+
+	markets, err := parseProducts([]byte(body))
+	assert.Nil(t, err)
+
+	statuses := map[string]currency.MarketStatus{}
+
+	for _, market := range markets {
+		statuses[market.Pair.String()] = market.Status
+	}
+
+	assert.Equal(t, map[string]currency.MarketStatus{
+		"BTC-USD":    currency.Online,
+		"SOL-EUR":    currency.Online,
+		"SHIB-USD":   currency.Online,
+		"MATIC-USDT": currency.Online,
+		"XRP-USD":    currency.Offline,
+		"ETH-DAI":    currency.Halted,
+	}, statuses)
+	assert.Equal(t, currency.Market{Pair: currency.BTCUSD, Exchange: "GDAX", TickSize: currency.NewDecimal(decimal.RequireFromString("0.01")), LotSize: currency.NewDecimal(decimal.RequireFromString("0.00000001")), MinNotional: currency.NewDecimal(decimal.RequireFromString("1")), Status: currency.Online}, markets[0])
+
+	_, err = parseProducts([]byte(`{"message": "Not Found"}`))
+	assert.NotNil(t, err)
+}
+
+// Answers every request with a status code and an empty list
+type statusTransport int
+
+func (s statusTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: int(s),
+		Status:     fmt.Sprintf("%d %s", s, http.StatusText(int(s))),
+		Body:       ioutil.NopCloser(strings.NewReader("[]")),
+		Request:    r,
+	}, nil
+}
+
+func TestFetchMarkets(t *testing.T) {
+	tables := []struct {
+		status int
+		err    bool
+	}{
+		{http.StatusOK, false},
+		{http.StatusTooManyRequests, true},
+		{http.StatusBadGateway, true},
+	}
+
+	for _, table := range tables {
+		g := &GDAX{httpClient: &http.Client{Transport: statusTransport(table.status)}}
+		markets, err := g.FetchMarkets()
+
+		assert.Equal(t, table.err, err != nil, "%d", table.status)
+
+		if !table.err {
+			assert.Equal(t, 0, len(markets))
+		}
+	}
+}
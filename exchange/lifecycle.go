@@ -0,0 +1,189 @@
+package exchange
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// State of an exchange driver
+type DriverState string
+
+const (
+	// The driver is initialized and receives the subscriptions
+	Running DriverState = "running"
+
+	// The driver is being initialized
+	Starting DriverState = "starting"
+
+	// The initialization failed, it is retried in the background
+	Failed DriverState = "failed"
+
+	// The driver is disabled by the configuration or has been stopped
+	Stopped DriverState = "stopped"
+)
+
+const (
+	// Delay before retrying to initialize a driver which failed,
+	// doubled after each attempt
+	RetryDelay time.Duration = 5 * time.Second
+
+	// Maximum delay between two attempts
+	MaxRetryDelay time.Duration = 5 * time.Minute
+
+	// Period of the checks of the drivers to retry
+	retryPeriod time.Duration = time.Second
+)
+
+// State of an exchange driver and its last failure
+type DriverStatus struct {
+	Name  string      `json:"name"`
+	State DriverState `json:"state"`
+
+	// Error returned by the last initialization
+	Error string `json:"error,omitempty"`
+
+	// Number of initializations which failed in a row
+	Attempts int `json:"attempts,omitempty"`
+
+	// Date and time of the next attempt
+	NextRetry *time.Time `json:"next_retry,omitempty"`
+}
+
+// Initialization of a driver which failed
+type failure struct {
+	err      error
+	attempts int
+	next     time.Time
+}
+
+// Returns the name of the driver matching `name` (case insensitive)
+// and the function which builds its Fetchers, `name` itself if there is none
+func findDriver(name string) (string, func() Fetcher, bool) {
+	for driverName, newFetcher := range Drivers {
+		if strings.EqualFold(driverName, name) {
+			return driverName, newFetcher, true
+		}
+	}
+
+	return name, nil, false
+}
+
+// Records a failed initialization and schedules the next attempt.
+// The mutex must be locked.
+func (fg *FetcherGroup) fail(name string, err error) {
+	previous, ok := fg.failures[name]
+	attempts := 1
+
+	if ok {
+		attempts = previous.attempts + 1
+	}
+
+	delay := RetryDelay
+
+	for i := 1; i < attempts && delay < MaxRetryDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > MaxRetryDelay {
+		delay = MaxRetryDelay
+	}
+
+	fg.failures[name] = &failure{err: err, attempts: attempts, next: time.Now().Add(delay)}
+
+	log.WithFields(logrus.Fields{"exchange": name, "attempts": attempts, "error": err}).Warningf("Initialization failed, retrying in %s", delay)
+}
+
+// Registers a function called each time a driver is added at runtime,
+// either by Add or by a successful retry (ex: to subscribe it again)
+func (fg *FetcherGroup) OnAdd(onAdd func(name string)) {
+	fg.mutex.Lock()
+	defer fg.mutex.Unlock()
+
+	fg.onAdd = onAdd
+}
+
+// Stops a driver (case insensitive), if it is running,
+// then initializes a new Fetcher once the previous one has returned
+func (fg *FetcherGroup) Restart(name string) error {
+	if err := fg.Remove(name); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	return fg.Add(name)
+}
+
+// Returns the state of every driver, sorted by name
+func (fg *FetcherGroup) States() []DriverStatus {
+	fg.mutex.RLock()
+	defer fg.mutex.RUnlock()
+
+	states := []DriverStatus{}
+
+	for name := range Drivers {
+		status := DriverStatus{Name: name, State: Stopped}
+
+		if failure, ok := fg.failures[name]; ok {
+			next := failure.next
+			status.State = Failed
+			status.Error = failure.err.Error()
+			status.Attempts = failure.attempts
+			status.NextRetry = &next
+		}
+
+		switch {
+		case fg.starting[name]:
+			status.State = Starting
+		case fg.fetchers[name] != nil:
+			status.State = Running
+		}
+
+		states = append(states, status)
+	}
+
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Name < states[j].Name
+	})
+
+	return states
+}
+
+// Initializes the drivers which failed once their delay has elapsed,
+// until the FetcherGroup stops
+func (fg *FetcherGroup) retryFailures() {
+	ticker := time.NewTicker(retryPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			// A new failure is logged and scheduled by add
+			for _, name := range fg.due(now) {
+				go fg.add(name, true)
+			}
+		case <-fg.done:
+			return
+		}
+	}
+}
+
+// Returns the drivers which failed and must be retried at `now`
+func (fg *FetcherGroup) due(now time.Time) []string {
+	fg.mutex.RLock()
+	defer fg.mutex.RUnlock()
+
+	names := []string{}
+
+	for name, failure := range fg.failures {
+		if !fg.starting[name] && !now.Before(failure.next) {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	return names
+}
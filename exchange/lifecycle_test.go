@@ -0,0 +1,125 @@
+package exchange
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fberrez/romantic-aggregator/currency"
+	"github.com/juju/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStates(t *testing.T) {
+	drivers := Drivers
+	defer func() { Drivers = drivers }()
+
+	Drivers = map[string]func() Fetcher{
+		"GDAX":     func() Fetcher { return &fakeFetcher{label: "GDAX", pairs: currency.CurrencySlice{currency.BTCUSD}} },
+		"Bitfinex": func() Fetcher { return &brokenFetcher{fakeFetcher{label: "Bitfinex"}} },
+	}
+
+	fg := NewFetcherGroup(nil, map[string]Fetcher{})
+	added := []string{}
+	fg.OnAdd(func(name string) { added = append(added, name) })
+
+	assert.Nil(t, fg.Add("gdax"))
+	assert.NotNil(t, fg.Add("bitfinex"))
+	assert.Equal(t, []string{"GDAX"}, added)
+
+	states := fg.States()
+	assert.Equal(t, "Bitfinex", states[0].Name)
+	assert.Equal(t, Failed, states[0].State)
+	assert.Equal(t, "connection refused", states[0].Error)
+	assert.Equal(t, 1, states[0].Attempts)
+	assert.Equal(t, DriverStatus{Name: "GDAX", State: Running}, states[1])
+
+	assert.Nil(t, fg.Restart("GDAX"))
+	assert.Equal(t, []string{"GDAX", "GDAX"}, added)
+
+	assert.Nil(t, fg.Remove("GDAX"))
+	assert.Nil(t, fg.Remove("Bitfinex"))
+	assert.Equal(t, []DriverStatus{{Name: "Bitfinex", State: Stopped}, {Name: "GDAX", State: Stopped}}, fg.States())
+	assert.True(t, errors.IsNotFound(fg.Restart("Kraken")))
+}
+
+func TestRetry(t *testing.T) {
+	fg := NewFetcherGroup(nil, map[string]Fetcher{})
+	now := time.Now()
+	err := errors.New("connection refused")
+
+	// The delay is doubled after each attempt, up to MaxRetryDelay
+	delays := []time.Duration{RetryDelay, 2 * RetryDelay, 4 * RetryDelay}
+
+	for _, delay := range delays {
+		fg.fail("GDAX", err)
+		next := fg.failures["GDAX"].next
+
+		assert.True(t, next.Sub(now) >= delay && next.Sub(now) < delay+time.Second, delay)
+		assert.Empty(t, fg.due(now))
+		assert.Equal(t, []string{"GDAX"}, fg.due(next))
+	}
+
+	for i := 0; i < 10; i++ {
+		fg.fail("GDAX", err)
+	}
+
+	assert.Equal(t, 13, fg.failures["GDAX"].attempts)
+	assert.True(t, fg.failures["GDAX"].next.Sub(now) <= MaxRetryDelay+time.Second)
+
+	// A driver being initialized is not retried twice
+	fg.starting["GDAX"] = true
+	assert.Empty(t, fg.due(now.Add(time.Hour)))
+}
+
+// Fetcher whose Start runs until it is interrupted
+type runningFetcher struct {
+	fakeFetcher
+	interrupted chan bool
+	stopped     bool
+}
+
+func (f *runningFetcher) Start() error {
+	<-f.interrupted
+	time.Sleep(10 * time.Millisecond)
+	f.stopped = true
+
+	return nil
+}
+
+func (f *runningFetcher) Interrupt() {
+	select {
+	case f.interrupted <- true:
+	default:
+	}
+}
+
+func TestRestartWaits(t *testing.T) {
+	drivers := Drivers
+	defer func() { Drivers = drivers }()
+
+	fetchers := []*runningFetcher{}
+	Drivers = map[string]func() Fetcher{
+		"GDAX": func() Fetcher {
+			fetcher := &runningFetcher{fakeFetcher: fakeFetcher{label: "GDAX"}, interrupted: make(chan bool, 1)}
+			fetchers = append(fetchers, fetcher)
+			return fetcher
+		},
+	}
+
+	fg := NewFetcherGroup(nil, map[string]Fetcher{})
+	fg.started = true
+
+	assert.Nil(t, fg.Add("GDAX"))
+	assert.Nil(t, fg.Restart("GDAX"))
+
+	// The previous Fetcher has returned before a new one is initialized
+	assert.Len(t, fetchers, 2)
+	assert.True(t, fetchers[0].stopped)
+
+	// Interrupting a Fetcher which has stopped does not block
+	fetchers[0].Interrupt()
+	fetchers[0].Interrupt()
+
+	assert.Nil(t, fg.Remove("GDAX"))
+	assert.True(t, fetchers[1].stopped)
+}
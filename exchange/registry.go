@@ -0,0 +1,277 @@
+package exchange
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fberrez/romantic-aggregator/currency"
+	"github.com/fberrez/romantic-aggregator/subscription"
+)
+
+// Registry contains the subscriptions of every exchange,
+// from the moment they are sent until they are unsubscribed
+type Registry struct {
+	mutex sync.Mutex
+
+	entries map[entryKey]*Entry
+
+	// Same entries, by currency pair (ex: BTC-USD) instead of symbol,
+	// so that the frames received are counted without scanning the entries
+	byPair map[pairKey]*Entry
+
+	// Called each time a pending (un)subscription is resolved, see OnResolve
+	onResolve func(subscription.Action, Entry)
+}
+
+// Subscription of one currency pair to one channel on one exchange
+type Entry struct {
+	// Name of the exchange (ex: GDAX)
+	Exchange string `json:"exchange"`
+
+	// Currency pair (ex: BTC-USD)
+	Pair string `json:"pair"`
+
+	// Symbol in the exchange format (ex: GDAX = "BTC-USD", Bitfinex = "BTCUSD")
+	Symbol string `json:"symbol"`
+
+	// Name of the channel (ex: ticker)
+	Channel string `json:"channel"`
+
+	// Pending until the exchange acknowledges the subscription
+	Status subscription.Status `json:"status"`
+
+	// Reason of the rejection
+	Reason string `json:"reason,omitempty"`
+
+	// Date and time of the acknowledgement
+	Since *time.Time `json:"since,omitempty"`
+
+	// Date and time of the last message received on the channel
+	LastMessage *time.Time `json:"last_message,omitempty"`
+
+	// Number of messages received on the channel
+	Messages int64 `json:"messages"`
+}
+
+type entryKey struct {
+	exchange string
+	symbol   string
+	channel  string
+}
+
+type pairKey struct {
+	exchange string
+	pair     string
+	channel  string
+}
+
+// Initializes a new registry
+func NewRegistry() *Registry {
+	return &Registry{
+		entries: map[entryKey]*Entry{},
+		byPair:  map[pairKey]*Entry{},
+	}
+}
+
+// Registers the subscriptions sent to an exchange.
+// `pairs` and `symbols` are the same currency pairs, respectively
+// in the "BASE-TARGET" format and in the exchange format.
+func (r *Registry) Add(exchange string, pairs []string, symbols []string, channels []string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i, symbol := range symbols {
+		for _, channel := range channels {
+			key := entryKey{exchange, symbol, channel}
+
+			// An active subscription stays active until it is unsubscribed
+			if entry, ok := r.entries[key]; ok && entry.Status == subscription.Confirmed {
+				continue
+			}
+
+			entry := &Entry{
+				Exchange: exchange,
+				Pair:     pairs[i],
+				Symbol:   symbol,
+				Channel:  channel,
+				Status:   subscription.Pending,
+			}
+
+			r.entries[key] = entry
+			r.byPair[entry.pairKey()] = entry
+		}
+	}
+}
+
+// Sets the function called each time a pending subscription is confirmed or rejected,
+// and each time an unsubscription is confirmed.
+// It is called with the tracker mutex held, but not the registry one.
+func (r *Registry) OnResolve(fn func(action subscription.Action, entry Entry)) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.onResolve = fn
+}
+
+// Updates the registry once the tracker resolves an (un)subscription
+func (r *Registry) Resolve(action subscription.Action, exchange string, outcome ItemOutcome) {
+	r.mutex.Lock()
+
+	key := entryKey{exchange, outcome.Symbol, outcome.Channel}
+	entry, ok := r.entries[key]
+
+	if !ok {
+		r.mutex.Unlock()
+		return
+	}
+
+	resolved := true
+
+	switch {
+	case action == subscription.Unsubscribe && outcome.Status == subscription.Confirmed:
+		r.delete(key, entry)
+	case action == subscription.Subscribe && entry.Status == subscription.Pending:
+		entry.Status = outcome.Status
+		entry.Reason = outcome.Reason
+
+		if outcome.Status == subscription.Confirmed {
+			now := time.Now()
+			entry.Since = &now
+		}
+	default:
+		resolved = false
+	}
+
+	copied, onResolve := *entry, r.onResolve
+	r.mutex.Unlock()
+
+	if resolved && onResolve != nil {
+		onResolve(action, copied)
+	}
+}
+
+// Forgets the subscriptions of an exchange which has been removed
+func (r *Registry) Forget(exchange string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for key, entry := range r.entries {
+		if key.exchange == exchange {
+			r.delete(key, entry)
+		}
+	}
+}
+
+// Removes an entry from both indexes.
+// The mutex must be held.
+func (r *Registry) delete(key entryKey, entry *Entry) {
+	delete(r.entries, key)
+
+	if r.byPair[entry.pairKey()] == entry {
+		delete(r.byPair, entry.pairKey())
+	}
+}
+
+// Counts a message received by an exchange for a currency pair,
+// with its canonical codes
+func (r *Registry) Received(exchange string, pair currency.Pair, channel string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if entry, ok := r.byPair[pairKey{exchange, pair.String(), channel}]; ok {
+		now := time.Now()
+		entry.LastMessage = &now
+		entry.Messages++
+	}
+}
+
+// Returns true if the currency pair (ex: BTC-USD) is subscribed
+// or being subscribed to the channel on the exchange
+func (r *Registry) Has(exchange string, pair string, channel string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	entry, ok := r.byPair[pairKey{exchange, pair, channel}]
+
+	return ok && entry.Status != subscription.Rejected
+}
+
+// Returns true if the currency pair (ex: BTC-USD) is being subscribed
+// on at least one exchange, i.e. not confirmed yet
+func (r *Registry) Pending(pair string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, entry := range r.entries {
+		if entry.Pair == pair && entry.Status == subscription.Pending {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns the number of subscriptions confirmed by the exchange
+func (r *Registry) Confirmed(exchange string) int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	count := 0
+
+	for _, entry := range r.entries {
+		if entry.Exchange == exchange && entry.Status == subscription.Confirmed {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Returns a copy of the subscriptions of the exchanges,
+// or of every exchange if `exchanges` is empty,
+// sorted by exchange, currency pair and channel
+func (r *Registry) Entries(exchanges ...string) []Entry {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	entries := []Entry{}
+
+	for _, entry := range r.entries {
+		if len(exchanges) > 0 && !contains(exchanges, entry.Exchange) {
+			continue
+		}
+
+		entries = append(entries, *entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Exchange != entries[j].Exchange {
+			return entries[i].Exchange < entries[j].Exchange
+		}
+
+		if entries[i].Pair != entries[j].Pair {
+			return entries[i].Pair < entries[j].Pair
+		}
+
+		return entries[i].Channel < entries[j].Channel
+	})
+
+	return entries
+}
+
+// Returns the key of the entry in the index by currency pair
+func (e *Entry) pairKey() pairKey {
+	return pairKey{e.Exchange, e.Pair, e.Channel}
+}
+
+// Returns true if `values` contains `value`
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,103 @@
+package exchange
+
+import (
+	"testing"
+
+	"github.com/fberrez/romantic-aggregator/currency"
+	"github.com/fberrez/romantic-aggregator/subscription"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+	registry.Add("GDAX", []string{"BTC-USD", "ETH-EUR"}, []string{"BTC-USD", "ETH-EUR"}, []string{"ticker"})
+	registry.Add("Bitfinex", []string{"BTC-USD"}, []string{"BTCUSD"}, []string{"ticker"})
+
+	entries := registry.Entries()
+	assert.Equal(t, 3, len(entries))
+	assert.Equal(t, "Bitfinex", entries[0].Exchange)
+	assert.Equal(t, subscription.Pending, entries[0].Status)
+	assert.Nil(t, entries[0].Since)
+
+	registry.Resolve(subscription.Subscribe, "GDAX", ItemOutcome{Symbol: "BTC-USD", Channel: "ticker", Status: subscription.Confirmed})
+	registry.Resolve(subscription.Subscribe, "GDAX", ItemOutcome{Symbol: "ETH-EUR", Channel: "ticker", Status: subscription.Rejected, Reason: "unknown product"})
+
+	// Messages are counted on the matching subscription only
+	registry.Received("GDAX", currency.Pair{Base: "BTC", Quote: "USD"}, "ticker")
+	registry.Received("GDAX", currency.Pair{Base: "BTC", Quote: "USD"}, "ticker")
+	registry.Received("GDAX", currency.Pair{Base: "LTC", Quote: "EUR"}, "ticker")
+
+	// The currency pairs are compared by their codes, not by their concatenation
+	other := NewRegistry()
+	other.Add("Bitfinex", []string{"DOGE-UR"}, []string{"DOGEUR"}, []string{"ticker"})
+	other.Received("Bitfinex", currency.Pair{Base: "DOG", Quote: "EUR"}, "ticker")
+	assert.Equal(t, int64(0), other.Entries("Bitfinex")[0].Messages)
+
+	entries = registry.Entries("GDAX")
+	assert.Equal(t, 2, len(entries))
+	assert.Equal(t, subscription.Confirmed, entries[0].Status)
+	assert.NotNil(t, entries[0].Since)
+	assert.NotNil(t, entries[0].LastMessage)
+	assert.Equal(t, int64(2), entries[0].Messages)
+	assert.Equal(t, subscription.Rejected, entries[1].Status)
+	assert.Equal(t, "unknown product", entries[1].Reason)
+
+	// A new subscription does not reset an active one
+	registry.Add("GDAX", []string{"BTC-USD"}, []string{"BTC-USD"}, []string{"ticker"})
+	assert.Equal(t, subscription.Confirmed, registry.Entries("GDAX")[0].Status)
+
+	// A rejected unsubscription keeps the subscription
+	registry.Resolve(subscription.Unsubscribe, "GDAX", ItemOutcome{Symbol: "BTC-USD", Channel: "ticker", Status: subscription.Rejected})
+	assert.Equal(t, 2, len(registry.Entries("GDAX")))
+
+	registry.Resolve(subscription.Unsubscribe, "GDAX", ItemOutcome{Symbol: "BTC-USD", Channel: "ticker", Status: subscription.Confirmed})
+	assert.Equal(t, 1, len(registry.Entries("GDAX")))
+	assert.Equal(t, 2, len(registry.Entries()))
+}
+
+func TestRegistryHas(t *testing.T) {
+	registry := NewRegistry()
+	registry.Add("GDAX", []string{"BTC-USD", "ETH-EUR"}, []string{"BTC-USD", "ETH-EUR"}, []string{"ticker"})
+	registry.Resolve(subscription.Subscribe, "GDAX", ItemOutcome{Symbol: "ETH-EUR", Channel: "ticker", Status: subscription.Rejected})
+
+	assert.True(t, registry.Has("GDAX", "BTC-USD", "ticker"))
+	assert.False(t, registry.Has("GDAX", "ETH-EUR", "ticker"))
+	assert.False(t, registry.Has("Bitfinex", "BTC-USD", "ticker"))
+	assert.False(t, registry.Has("GDAX", "BTC-USD", "trades"))
+
+	// Both indexes forget the entries
+	registry.Add("Bitfinex", []string{"BTC-USD"}, []string{"BTCUSD"}, []string{"ticker"})
+	registry.Resolve(subscription.Unsubscribe, "GDAX", ItemOutcome{Symbol: "BTC-USD", Channel: "ticker", Status: subscription.Confirmed})
+	registry.Forget("Bitfinex")
+	assert.False(t, registry.Has("GDAX", "BTC-USD", "ticker"))
+	assert.False(t, registry.Has("Bitfinex", "BTC-USD", "ticker"))
+
+	registry.Received("Bitfinex", currency.Pair{Base: "BTC", Quote: "USD"}, "ticker")
+	assert.Equal(t, 1, len(registry.Entries()))
+}
+
+func TestRegistryOnResolve(t *testing.T) {
+	registry := NewRegistry()
+	resolved := []string{}
+	registry.OnResolve(func(action subscription.Action, entry Entry) {
+		resolved = append(resolved, string(action)+" "+entry.Pair+" "+string(entry.Status))
+	})
+
+	registry.Add("GDAX", []string{"BTC-USD", "ETH-EUR"}, []string{"BTC-USD", "ETH-EUR"}, []string{"ticker"})
+	assert.True(t, registry.Pending("BTC-USD"))
+
+	registry.Resolve(subscription.Subscribe, "GDAX", ItemOutcome{Symbol: "BTC-USD", Channel: "ticker", Status: subscription.Confirmed})
+	registry.Resolve(subscription.Subscribe, "GDAX", ItemOutcome{Symbol: "ETH-EUR", Channel: "ticker", Status: subscription.Rejected})
+	assert.False(t, registry.Pending("BTC-USD"))
+
+	// Only the changes are notified
+	registry.Resolve(subscription.Subscribe, "GDAX", ItemOutcome{Symbol: "BTC-USD", Channel: "ticker", Status: subscription.Confirmed})
+	registry.Resolve(subscription.Unsubscribe, "GDAX", ItemOutcome{Symbol: "BTC-USD", Channel: "ticker", Status: subscription.Rejected})
+	registry.Resolve(subscription.Unsubscribe, "GDAX", ItemOutcome{Symbol: "BTC-USD", Channel: "ticker", Status: subscription.Confirmed})
+
+	assert.Equal(t, []string{
+		"subscribe BTC-USD confirmed",
+		"subscribe ETH-EUR rejected",
+		"unsubscribe BTC-USD confirmed",
+	}, resolved)
+}
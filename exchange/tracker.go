@@ -0,0 +1,378 @@
+package exchange
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fberrez/romantic-aggregator/subscription"
+	"github.com/juju/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Tracker follows each (un)subscription request
+// until the exchanges acknowledge or reject it
+type Tracker struct {
+	mutex sync.Mutex
+
+	// Requests which can still be consulted, by id
+	requests map[string]*Request
+
+	// Ids of the requests, from the oldest to the newest
+	order []string
+
+	// Last id given to a request
+	lastId int
+
+	// Duration after which a pending (un)subscription is rejected
+	timeout time.Duration
+
+	// Called each time a pending (un)subscription is resolved
+	onResolve func(subscription.Action, string, ItemOutcome)
+}
+
+// Request contains the (un)subscriptions sent to the exchanges
+// by a single call to FetcherGroup.SendMessage
+type Request struct {
+	Id      string              `json:"id"`
+	Action  subscription.Action `json:"action"`
+	Created time.Time           `json:"created"`
+
+	// Exchanges to which the message is sent,
+	// i.e. the targets which list at least one of the currency pairs
+	Exchanges []string `json:"exchanges"`
+
+	items []*item
+
+	// True once every item has been added
+	sent bool
+
+	// Closed when every item is resolved
+	done chan struct{}
+}
+
+// Outcome of a request on one exchange
+type Outcome struct {
+	Exchange string              `json:"exchange"`
+	Status   subscription.Status `json:"status"`
+	Reason   string              `json:"reason,omitempty"`
+	Items    []ItemOutcome       `json:"items"`
+}
+
+// Outcome of a request for one symbol and one channel
+type ItemOutcome struct {
+	Symbol  string              `json:"symbol"`
+	Channel string              `json:"channel"`
+	Status  subscription.Status `json:"status"`
+	Reason  string              `json:"reason,omitempty"`
+}
+
+// (Un)subscription of one symbol to one channel on one exchange
+type item struct {
+	exchange string
+	symbol   string
+	channel  string
+	status   subscription.Status
+	reason   string
+}
+
+const (
+	// Number of requests kept once resolved
+	maxTrackedRequests = 100
+
+	// Reason given when an exchange does not answer in time
+	timeoutReason = "no acknowledgement received from the exchange"
+)
+
+// Initializes a new tracker
+func NewTracker(timeout time.Duration) *Tracker {
+	return &Tracker{
+		requests: map[string]*Request{},
+		order:    []string{},
+		timeout:  timeout,
+	}
+}
+
+// Sets the function called each time a pending (un)subscription
+// is confirmed or rejected. It is called with the tracker mutex held.
+func (t *Tracker) OnResolve(fn func(action subscription.Action, exchange string, outcome ItemOutcome)) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.onResolve = fn
+}
+
+// Creates a new request.
+// Items must be added with Add before calling Send.
+func (t *Tracker) NewRequest(action subscription.Action) *Request {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.lastId++
+
+	request := &Request{
+		Id:        strconv.Itoa(t.lastId),
+		Action:    action,
+		Created:   time.Now(),
+		Exchanges: []string{},
+		items:     []*item{},
+		done:      make(chan struct{}),
+	}
+
+	t.requests[request.Id] = request
+	t.order = append(t.order, request.Id)
+
+	// Forgets the oldest requests
+	for len(t.order) > maxTrackedRequests {
+		delete(t.requests, t.order[0])
+		t.order = t.order[1:]
+	}
+
+	return request
+}
+
+// Adds the (un)subscriptions sent to an exchange to the request.
+// They must be added before sending the message, so no ack is missed.
+func (t *Tracker) Add(r *Request, exchange string, symbols []string, channels []string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for _, symbol := range symbols {
+		for _, channel := range channels {
+			r.items = append(r.items, &item{
+				exchange: exchange,
+				symbol:   symbol,
+				channel:  channel,
+				status:   subscription.Pending,
+			})
+		}
+	}
+}
+
+// Adds (un)subscriptions which are rejected without being sent
+// (ex: the currency pair is not listed by the exchange)
+func (t *Tracker) AddRejected(r *Request, exchange string, symbols []string, channels []string, err error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for _, symbol := range symbols {
+		for _, channel := range channels {
+			r.items = append(r.items, &item{
+				exchange: exchange,
+				symbol:   symbol,
+				channel:  channel,
+				status:   subscription.Rejected,
+				reason:   err.Error(),
+			})
+		}
+	}
+}
+
+// Rejects every pending item of an exchange,
+// when the message cannot be sent to it
+func (t *Tracker) Reject(r *Request, exchange string, err error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	rejected := false
+
+	for _, item := range r.items {
+		if item.exchange == exchange && item.status == subscription.Pending {
+			item.status = subscription.Rejected
+			item.reason = err.Error()
+			rejected = true
+			t.resolved(r, item)
+		}
+	}
+
+	// The exchange may not have any item yet
+	// (ex: the currencies could not be translated)
+	if !rejected {
+		r.items = append(r.items, &item{
+			exchange: exchange,
+			status:   subscription.Rejected,
+			reason:   err.Error(),
+		})
+	}
+}
+
+// Starts the timeout of the request.
+// Every item still pending when it expires is rejected.
+func (t *Tracker) Send(r *Request) {
+	t.mutex.Lock()
+	r.sent = true
+	r.checkDone()
+	t.mutex.Unlock()
+
+	time.AfterFunc(t.timeout, func() {
+		t.mutex.Lock()
+		defer t.mutex.Unlock()
+
+		for _, item := range r.items {
+			if item.status == subscription.Pending {
+				item.status = subscription.Rejected
+				item.reason = timeoutReason
+				t.resolved(r, item)
+			}
+		}
+
+		r.checkDone()
+	})
+}
+
+// Resolves the pending items matching the ack.
+// If the ack has a symbol, only the oldest matching item is resolved.
+// Otherwise, every matching item of the oldest matching request is resolved.
+func (t *Tracker) Acknowledge(ack subscription.Ack) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for _, id := range t.order {
+		request := t.requests[id]
+
+		if ack.Action != "" && ack.Action != request.Action {
+			continue
+		}
+
+		resolved := false
+
+		for _, item := range request.items {
+			if !item.matches(ack) {
+				continue
+			}
+
+			item.status = subscription.Confirmed
+			item.reason = ""
+
+			if ack.Error != "" {
+				item.status = subscription.Rejected
+				item.reason = ack.Error
+			}
+
+			resolved = true
+			t.resolved(request, item)
+
+			if ack.Symbol != "" {
+				break
+			}
+		}
+
+		if resolved {
+			log.WithFields(logrus.Fields{"request": request.Id, "ack": ack}).Debug("Acknowledgement received")
+			request.checkDone()
+			return
+		}
+	}
+}
+
+// Returns the request identified by `id`
+func (t *Tracker) Find(id string) (*Request, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	request, ok := t.requests[id]
+
+	if !ok {
+		return nil, errors.NotFoundf("request %s", id)
+	}
+
+	return request, nil
+}
+
+// Returns the outcome of the request on each exchange
+func (t *Tracker) Outcomes(r *Request) []Outcome {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	outcomes := []Outcome{}
+	indexes := map[string]int{}
+
+	for _, item := range r.items {
+		index, ok := indexes[item.exchange]
+
+		if !ok {
+			index = len(outcomes)
+			indexes[item.exchange] = index
+			outcomes = append(outcomes, Outcome{
+				Exchange: item.exchange,
+				Status:   subscription.Confirmed,
+				Items:    []ItemOutcome{},
+			})
+		}
+
+		outcome := &outcomes[index]
+		outcome.Items = append(outcome.Items, ItemOutcome{
+			Symbol:  item.symbol,
+			Channel: item.channel,
+			Status:  item.status,
+			Reason:  item.reason,
+		})
+
+		// A rejection prevails over a pending item,
+		// which prevails over a confirmation
+		switch {
+		case item.status == subscription.Rejected && outcome.Status != subscription.Rejected:
+			outcome.Status = subscription.Rejected
+			outcome.Reason = item.reason
+		case item.status == subscription.Pending && outcome.Status == subscription.Confirmed:
+			outcome.Status = subscription.Pending
+		}
+	}
+
+	return outcomes
+}
+
+// Notifies the resolution of an item.
+// The tracker mutex must be held.
+func (t *Tracker) resolved(r *Request, i *item) {
+	if t.onResolve == nil {
+		return
+	}
+
+	t.onResolve(r.Action, i.exchange, ItemOutcome{
+		Symbol:  i.symbol,
+		Channel: i.channel,
+		Status:  i.status,
+		Reason:  i.reason,
+	})
+}
+
+// Waits until every item of the request is resolved,
+// or until the timeout expires
+func (r *Request) Wait(timeout time.Duration) {
+	select {
+	case <-r.done:
+	case <-time.After(timeout):
+	}
+}
+
+// Closes the done channel if the request has been sent
+// and no item is pending anymore.
+// The tracker mutex must be held.
+func (r *Request) checkDone() {
+	if !r.sent {
+		return
+	}
+
+	select {
+	case <-r.done:
+		return
+	default:
+	}
+
+	for _, item := range r.items {
+		if item.status == subscription.Pending {
+			return
+		}
+	}
+
+	close(r.done)
+}
+
+// Returns true if the item is pending and matches the ack
+func (i *item) matches(ack subscription.Ack) bool {
+	return i.status == subscription.Pending &&
+		i.exchange == ack.Exchange &&
+		(ack.Symbol == "" || ack.Symbol == i.symbol) &&
+		(ack.Channel == "" || ack.Channel == i.channel)
+}
@@ -0,0 +1,67 @@
+package exchange
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fberrez/romantic-aggregator/subscription"
+	"github.com/juju/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracker(t *testing.T) {
+	tracker := NewTracker(time.Hour)
+
+	request := tracker.NewRequest(subscription.Subscribe)
+	tracker.Add(request, "GDAX", []string{"BTC-USD", "ETH-USD"}, []string{"ticker"})
+	tracker.Add(request, "Bitfinex", []string{"BTCUSD"}, []string{"ticker"})
+	tracker.Reject(request, "Kraken", errors.New("not initialized"))
+	tracker.Send(request)
+
+	// Acks which do not match any pending item are ignored
+	tracker.Acknowledge(subscription.Ack{Exchange: "GDAX", Action: subscription.Unsubscribe, Symbol: "BTC-USD", Channel: "ticker"})
+	tracker.Acknowledge(subscription.Ack{Exchange: "GDAX", Action: subscription.Subscribe, Symbol: "LTC-USD", Channel: "ticker"})
+	tracker.Acknowledge(subscription.Ack{Exchange: "GDAX", Action: subscription.Subscribe, Symbol: "BTC-USD", Channel: "ticker"})
+
+	outcomes := tracker.Outcomes(request)
+	assert.Equal(t, 3, len(outcomes))
+	assert.Equal(t, subscription.Pending, outcomes[0].Status)
+	assert.Equal(t, subscription.Confirmed, outcomes[0].Items[0].Status)
+	assert.Equal(t, subscription.Pending, outcomes[0].Items[1].Status)
+	assert.Equal(t, subscription.Pending, outcomes[1].Status)
+	assert.Equal(t, subscription.Rejected, outcomes[2].Status)
+	assert.Equal(t, "not initialized", outcomes[2].Reason)
+
+	// An ack without symbol rejects every pending item of the exchange
+	tracker.Acknowledge(subscription.Ack{Exchange: "GDAX", Error: "Failed to subscribe"})
+	tracker.Acknowledge(subscription.Ack{Exchange: "Bitfinex", Action: subscription.Subscribe, Symbol: "BTCUSD", Channel: "ticker"})
+
+	request.Wait(time.Second)
+
+	outcomes = tracker.Outcomes(request)
+	assert.Equal(t, subscription.Rejected, outcomes[0].Status)
+	assert.Equal(t, "Failed to subscribe", outcomes[0].Reason)
+	assert.Equal(t, subscription.Confirmed, outcomes[0].Items[0].Status)
+	assert.Equal(t, subscription.Confirmed, outcomes[1].Status)
+
+	found, err := tracker.Find(request.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, request, found)
+
+	_, err = tracker.Find("unknown")
+	assert.True(t, errors.IsNotFound(err))
+}
+
+func TestTrackerTimeout(t *testing.T) {
+	tracker := NewTracker(10 * time.Millisecond)
+
+	request := tracker.NewRequest(subscription.Unsubscribe)
+	tracker.Add(request, "GDAX", []string{"BTC-USD"}, []string{"ticker"})
+	tracker.Send(request)
+
+	request.Wait(time.Second)
+
+	outcomes := tracker.Outcomes(request)
+	assert.Equal(t, subscription.Rejected, outcomes[0].Status)
+	assert.Equal(t, timeoutReason, outcomes[0].Reason)
+}
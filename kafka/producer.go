@@ -2,9 +2,11 @@ package kafka
 
 import (
 	"encoding/json"
-	"os"
+	"sync"
+	"time"
 
 	"github.com/Shopify/sarama"
+	"github.com/fberrez/romantic-aggregator/metrics"
 	"github.com/sirupsen/logrus"
 )
 
@@ -16,24 +18,74 @@ type AggregatorProducer struct {
 	Channel          chan interface{}
 	InterruptChannel chan bool
 	Topic            string
+
+	// Client shared with the producer, which knows the brokers
+	client sarama.Client
+
+	// True if the messages are dropped instead of being sent to Kafka
+	discard bool
+
+	// Protects the state below, which is read by the health checks
+	mutex       sync.Mutex
+	running     bool
+	produced    int64
+	failed      int64
+	lastSuccess time.Time
+	lastError   time.Time
+	err         error
+}
+
+// Current state of the producer
+type Status struct {
+	// Name of the Kafka topic
+	Topic string `json:"topic"`
+
+	// True while the producer loop is running
+	Running bool `json:"running"`
+
+	// Number of brokers the client is connected to
+	Brokers int `json:"brokers"`
+
+	// True if the messages are dropped instead of being sent to Kafka
+	Discard bool `json:"discard,omitempty"`
+
+	// Number of messages acknowledged by Kafka
+	Produced int64 `json:"produced"`
+
+	// Number of messages which could not be produced
+	Failed int64 `json:"failed"`
+
+	// Date and time of the last message acknowledged by Kafka
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+
+	// Date and time of the last failure
+	LastError *time.Time `json:"last_error,omitempty"`
+
+	// Last error returned by Kafka
+	Error string `json:"error,omitempty"`
 }
 
 var (
 	log *logrus.Entry = logrus.WithFields(logrus.Fields{"element": "kafka"})
 )
 
-// Initializes the Aggregator Producer
-func Initialize(addr string) (*AggregatorProducer, error) {
-	producer, err := sarama.NewAsyncProducer([]string{addr}, nil)
+// Initializes the Aggregator Producer which sends the messages to `topic`
+func Initialize(addr string, topic string) (*AggregatorProducer, error) {
+	config := sarama.NewConfig()
+	// The successes are used to know if Kafka is reachable
+	config.Producer.Return.Successes = true
+
+	client, err := sarama.NewClient([]string{addr}, config)
 
 	if err != nil {
 		return nil, err
 	}
 
-	topic := os.Getenv("KAFKA_TOPIC")
+	producer, err := sarama.NewAsyncProducerFromClient(client)
 
-	if topic == "" {
-		topic = "romantic-aggregator"
+	if err != nil {
+		client.Close()
+		return nil, err
 	}
 
 	aggrProd := &AggregatorProducer{
@@ -41,6 +93,7 @@ func Initialize(addr string) (*AggregatorProducer, error) {
 		Channel:          make(chan interface{}),
 		InterruptChannel: make(chan bool),
 		Topic:            topic,
+		client:           client,
 	}
 
 	log.WithField("topic", topic).Info("Initializing Kafka producer...")
@@ -48,11 +101,29 @@ func Initialize(addr string) (*AggregatorProducer, error) {
 	return aggrProd, nil
 }
 
-// Starts the loop which will handle the messages stream and the sigterm
+// Initializes a producer which drops the messages without connecting to Kafka
+// (ex: to replay a recording offline)
+func Discard(topic string) *AggregatorProducer {
+	log.WithField("topic", topic).Info("Initializing Kafka producer, the messages are discarded")
+
+	return &AggregatorProducer{
+		Channel:          make(chan interface{}),
+		InterruptChannel: make(chan bool),
+		Topic:            topic,
+		discard:          true,
+	}
+}
+
+// Starts the loop which will handle the messages stream and the sigterm.
+// The acknowledgements are read by another go routine, so that the producer
+// does not block when its input and its successes are both full.
 func (p *AggregatorProducer) Start() {
-	defer p.Stop()
+	p.setRunning(true)
+	defer p.setRunning(false)
 
-	var enqueued, errors int
+	if !p.discard {
+		go p.readAcknowledgements()
+	}
 
 ProducerLoop:
 	for {
@@ -61,10 +132,6 @@ ProducerLoop:
 		// between exchangers and kafka producer
 		case message := <-p.Channel:
 			p.SendMessage(message)
-			// Handles errors
-		case err := <-p.Producer.Errors():
-			log.WithFields(logrus.Fields{"error": err}).Errorf("Failed to produce message")
-			errors++
 			// Handles sigterm
 		case signal := <-p.InterruptChannel:
 			if signal {
@@ -73,8 +140,39 @@ ProducerLoop:
 		}
 	}
 
-	log.WithFields(logrus.Fields{"enqueued": enqueued, "errors": errors}).Infof("Closing Kafka producer")
+	status := p.Status()
+	log.WithFields(logrus.Fields{"enqueued": status.Produced, "errors": status.Failed}).Infof("Closing Kafka producer")
+}
+
+// Reads the successes and the errors of the producer until it is closed
+func (p *AggregatorProducer) readAcknowledgements() {
+	successes, errors := p.Producer.Successes(), p.Producer.Errors()
+
+	for successes != nil || errors != nil {
+		select {
+		case message, ok := <-successes:
+			if !ok {
+				successes = nil
+				continue
+			}
+
+			p.succeeded()
+			metrics.ProduceSuccesses.Inc()
+
+			if enqueuedAt, ok := message.Metadata.(time.Time); ok {
+				metrics.ProduceLatency.Observe(time.Since(enqueuedAt).Seconds())
+			}
+		case err, ok := <-errors:
+			if !ok {
+				errors = nil
+				continue
+			}
 
+			log.WithFields(logrus.Fields{"error": err}).Errorf("Failed to produce message")
+			p.failedWith(err)
+			metrics.ProduceFailures.Inc()
+		}
+	}
 }
 
 // Sends messages to the kafka stream
@@ -85,11 +183,18 @@ func (p *AggregatorProducer) SendMessage(message interface{}) error {
 		return err
 	}
 
+	if p.discard {
+		log.WithField("message", string(marshalledMessage)).Debug("Message discarded")
+		return nil
+	}
+
 	// Builds the message struct
 	// which contains the topic name and the message
 	producerMess := &sarama.ProducerMessage{
 		Topic: p.Topic,
 		Value: sarama.StringEncoder(string(marshalledMessage)),
+		// Used to measure the latency of Kafka
+		Metadata: time.Now(),
 	}
 
 	p.Producer.Input() <- producerMess
@@ -97,8 +202,79 @@ func (p *AggregatorProducer) SendMessage(message interface{}) error {
 	return nil
 }
 
-// Handles the sigterm
+// Returns the current state of the producer
+func (p *AggregatorProducer) Status() Status {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	status := Status{
+		Topic:    p.Topic,
+		Running:  p.running,
+		Discard:  p.discard,
+		Produced: p.produced,
+		Failed:   p.failed,
+	}
+
+	if p.client != nil && !p.client.Closed() {
+		for _, broker := range p.client.Brokers() {
+			if connected, _ := broker.Connected(); connected {
+				status.Brokers++
+			}
+		}
+	}
+
+	if !p.lastSuccess.IsZero() {
+		lastSuccess := p.lastSuccess
+		status.LastSuccess = &lastSuccess
+	}
+
+	if !p.lastError.IsZero() {
+		lastError := p.lastError
+		status.LastError = &lastError
+		status.Error = p.err.Error()
+	}
+
+	return status
+}
+
+func (p *AggregatorProducer) setRunning(running bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.running = running
+}
+
+// Records a message acknowledged by Kafka
+func (p *AggregatorProducer) succeeded() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.produced++
+	p.lastSuccess = time.Now()
+}
+
+// Records a message which could not be produced
+func (p *AggregatorProducer) failedWith(err error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.failed++
+	p.lastError = time.Now()
+	p.err = err
+}
+
+// Stops the loop started by Start, then closes the producer and its client
 func (p *AggregatorProducer) Stop() error {
 	p.InterruptChannel <- true
-	return p.Producer.Close()
+
+	if p.discard {
+		return nil
+	}
+
+	if err := p.Producer.Close(); err != nil {
+		return err
+	}
+
+	// The producer does not close the client it has been built from
+	return p.client.Close()
 }
@@ -0,0 +1,167 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Prefix of every metric
+const namespace = "romantic"
+
+// The labels only take values known in advance
+// (exchange and channel names, HTTP routes, gRPC methods)
+// to keep the number of series bounded.
+var (
+	// Messages received from the exchanges, by exchange and channel
+	MessagesReceived = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "exchange",
+		Name:      "messages_received_total",
+		Help:      "Messages received from the exchanges, by exchange and channel.",
+	}, []string{"exchange", "channel"})
+
+	// Frames received from the exchange websockets
+	FramesReceived = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "websocket",
+		Name:      "frames_received_total",
+		Help:      "Frames received from the exchange websockets.",
+	}, []string{"exchange"})
+
+	// Frames which could not be parsed or processed
+	ParseErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "exchange",
+		Name:      "parse_errors_total",
+		Help:      "Frames which could not be parsed or processed, by exchange.",
+	}, []string{"exchange"})
+
+	// Connections reopened after the websocket has been closed by the host
+	Reconnects = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "websocket",
+		Name:      "reconnects_total",
+		Help:      "Connections reopened after the websocket has been closed by the host.",
+	}, []string{"exchange"})
+
+	// Messages waiting to be sent to the exchange websockets
+	OutboundQueueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "websocket",
+		Name:      "outbound_queue_depth",
+		Help:      "Messages waiting to be sent to the exchange websockets.",
+	}, []string{"exchange"})
+
+	// Messages dropped because the outbound queue was full
+	OutboundDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "websocket",
+		Name:      "outbound_dropped_total",
+		Help:      "Messages dropped because the outbound queue of the exchange websocket was full.",
+	}, []string{"exchange"})
+
+	// Currency pairs waiting for the end of the interval
+	AggregatorQueueDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "aggregator",
+		Name:      "queue_depth",
+		Help:      "Currency pairs aggregated during the current interval, waiting to be flushed.",
+	})
+
+	// Duration of the flush of the aggregates at the end of an interval
+	FlushDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "aggregator",
+		Name:      "flush_duration_seconds",
+		Help:      "Duration of the flush of the aggregates at the end of an interval.",
+		Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 10),
+	})
+
+	// Aggregates sent to Kafka at the end of each interval, by interval
+	TickersEmitted = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "aggregator",
+		Name:      "tickers_emitted",
+		Help:      "Aggregated tickers emitted at the end of each interval, by interval.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 10),
+	}, []string{"interval"})
+
+	// Messages acknowledged by Kafka
+	ProduceSuccesses = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "kafka",
+		Name:      "produce_successes_total",
+		Help:      "Messages acknowledged by Kafka.",
+	})
+
+	// Messages which could not be produced
+	ProduceFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "kafka",
+		Name:      "produce_failures_total",
+		Help:      "Messages which could not be produced.",
+	})
+
+	// Duration between the enqueuing of a message and its acknowledgement
+	ProduceLatency = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "kafka",
+		Name:      "produce_latency_seconds",
+		Help:      "Duration between the enqueuing of a message and its acknowledgement by Kafka.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 14),
+	})
+
+	// HTTP requests handled by the API, by method, route and status code
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "api",
+		Name:      "http_requests_total",
+		Help:      "HTTP requests handled by the API, by method, route and status code.",
+	}, []string{"method", "route", "code"})
+
+	// Duration of the HTTP requests, by method and route
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "api",
+		Name:      "http_request_duration_seconds",
+		Help:      "Duration of the HTTP requests, by method and route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	// gRPC calls handled by the API, by method and status code
+	GRPCRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "api",
+		Name:      "grpc_requests_total",
+		Help:      "gRPC calls handled by the API, by method and status code.",
+	}, []string{"method", "code"})
+
+	// Duration of the gRPC calls, by method
+	GRPCDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "api",
+		Name:      "grpc_request_duration_seconds",
+		Help:      "Duration of the gRPC calls, by method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		MessagesReceived,
+		FramesReceived,
+		ParseErrors,
+		Reconnects,
+		OutboundQueueDepth,
+		OutboundDropped,
+		AggregatorQueueDepth,
+		FlushDuration,
+		TickersEmitted,
+		ProduceSuccesses,
+		ProduceFailures,
+		ProduceLatency,
+		HTTPRequests,
+		HTTPDuration,
+		GRPCRequests,
+		GRPCDuration,
+	)
+}
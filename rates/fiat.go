@@ -0,0 +1,132 @@
+package rates
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fberrez/romantic-aggregator/aggregator"
+	"github.com/fberrez/romantic-aggregator/currency"
+	"github.com/juju/errors"
+	"github.com/shopspring/decimal"
+)
+
+// Conversion table of the fiat currencies
+type Table struct {
+	// Currency the rates are written in (ex: USD)
+	Base string `json:"base"`
+
+	// Units of each currency for one unit of Base (ex: {"EUR": "0.92"})
+	Rates map[string]decimal.Decimal `json:"rates"`
+
+	// Date and time of the rates, the date of the file if it is not set
+	Time time.Time `json:"time"`
+}
+
+// Source of the fiat conversion table (ex: a file, a web service)
+type Provider interface {
+	Fetch() (*Table, error)
+}
+
+// Provider which reads the table from a JSON file,
+// ex: {"base": "USD", "rates": {"EUR": "0.92", "GBP": "0.79"}}
+type FileProvider struct {
+	Path string
+}
+
+// Reads the table from the file
+func (p *FileProvider) Fetch() (*Table, error) {
+	content, err := ioutil.ReadFile(p.Path)
+
+	if err != nil {
+		return nil, errors.Annotatef(err, "tried to read %s", p.Path)
+	}
+
+	table := &Table{}
+
+	if err := json.Unmarshal(content, table); err != nil {
+		return nil, errors.NewNotValid(err, "fiat rates "+p.Path)
+	}
+
+	if table.Time.IsZero() {
+		if info, err := os.Stat(p.Path); err == nil {
+			table.Time = info.ModTime()
+		}
+	}
+
+	return table, table.normalize()
+}
+
+// Writes the codes in uppercase and checks the rates
+func (t *Table) normalize() error {
+	t.Base = strings.ToUpper(t.Base)
+
+	if t.Base == "" {
+		return errors.NotValidf("fiat rates without base currency")
+	}
+
+	rates := map[string]decimal.Decimal{}
+
+	for code, rate := range t.Rates {
+		if rate.Sign() <= 0 {
+			return errors.NotValidf("fiat rate of %s: %s", code, rate)
+		}
+
+		rates[strings.ToUpper(code)] = rate
+	}
+
+	t.Rates = rates
+
+	return nil
+}
+
+// Returns true if the table converts `code`
+func (t *Table) Has(code string) bool {
+	_, ok := t.Rates[code]
+	return ok || code == t.Base
+}
+
+// Returns the currencies of the table, Base first then in alphabetical order
+func (t *Table) Currencies() []string {
+	codes := []string{}
+
+	for code := range t.Rates {
+		if code != t.Base {
+			codes = append(codes, code)
+		}
+	}
+
+	sort.Strings(codes)
+
+	return append([]string{t.Base}, codes...)
+}
+
+// Returns the price of `base` in `quote`, computed through Base.
+// The source is written as the currency pair of the table: Base-quote,
+// inverted to convert a currency to Base.
+func (t *Table) source(base string, quote string) (Source, bool) {
+	if !t.Has(base) || !t.Has(quote) {
+		return Source{}, false
+	}
+
+	source := Source{Exchange: Fiat, Time: t.Time}
+
+	switch {
+	case base == t.Base:
+		source.Pair = currency.Pair{Base: t.Base, Quote: quote}
+		source.Price = currency.NewDecimal(t.Rates[quote])
+	case quote == t.Base:
+		source.Pair = currency.Pair{Base: t.Base, Quote: base}
+		source.Price = currency.NewDecimal(t.Rates[base])
+		source.Inverted = true
+	default:
+		// Units of quote for one unit of base, through Base
+		source.Pair = currency.Pair{Base: base, Quote: quote}
+		source.Price = currency.NewDecimal(t.Rates[quote].DivRound(t.Rates[base], aggregator.Precision))
+	}
+
+	return source, true
+}
@@ -0,0 +1,372 @@
+package rates
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fberrez/romantic-aggregator/aggregator"
+	"github.com/fberrez/romantic-aggregator/currency"
+	"github.com/juju/errors"
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// Price of a currency pair, either traded or synthesized from other currency pairs
+type Rate struct {
+	Base  string           `json:"base"`
+	Quote string           `json:"quote"`
+	Price currency.Decimal `json:"price"`
+
+	// True if the rate is computed from other currency pairs or from the fiat table
+	Synthetic bool `json:"synthetic"`
+
+	// Prices the rate is computed from, in order (ex: BTC-USD then EUR-USD for BTC-EUR)
+	Sources []Source `json:"sources"`
+
+	// Date and time of the oldest source
+	Time time.Time `json:"time"`
+}
+
+// Price used to compute a rate
+type Source struct {
+	// Currency pair as traded (ex: EUR-USD), or as written in the fiat table
+	Pair currency.Pair `json:"pair"`
+
+	// Exchange which sent the price, Fiat for the fiat table
+	Exchange string `json:"exchange"`
+
+	// Price of the currency pair, before it is inverted
+	Price currency.Decimal `json:"price"`
+
+	// True if the rate uses 1/price (ex: USD-EUR computed from EUR-USD)
+	Inverted bool `json:"inverted,omitempty"`
+
+	// Date and time of the price
+	Time time.Time `json:"time"`
+}
+
+// Value of a portfolio in one reporting currency
+type Valuation struct {
+	// Reporting currency (ex: EUR)
+	Currency string           `json:"currency"`
+	Total    currency.Decimal `json:"total"`
+
+	// Value of each asset, sorted by asset
+	Assets []Asset `json:"assets"`
+
+	// Assets which cannot be converted to the reporting currency
+	Missing []string `json:"missing"`
+}
+
+// Value of one asset of a portfolio
+type Asset struct {
+	Asset  string           `json:"asset"`
+	Amount currency.Decimal `json:"amount"`
+	Value  currency.Decimal `json:"value"`
+	Rate   *Rate            `json:"rate"`
+}
+
+const (
+	// Name of the source of the rates read from the fiat table
+	Fiat string = "fiat"
+)
+
+var (
+	// Currencies used to synthesize a cross rate, in order of preference
+	DefaultBridges []string = []string{"USD", "USDT", "EUR", "BTC"}
+
+	log = logrus.WithFields(logrus.Fields{"element": "rates"})
+)
+
+// Service computes the rates from the last tickers received by the aggregator.
+// The rates which are not traded are synthesized through the bridge currencies,
+// then converted with the fiat table.
+type Service struct {
+	mutex sync.RWMutex
+
+	cache   *aggregator.Cache
+	bridges []string
+
+	provider Provider
+	table    *Table
+
+	done chan struct{}
+}
+
+// Initializes a service which reads the last tickers of `cache`.
+// The fiat table is only used if `provider` is not nil.
+func New(cache *aggregator.Cache, bridges []string, provider Provider) *Service {
+	normalized := []string{}
+
+	for _, bridge := range bridges {
+		normalized = append(normalized, strings.ToUpper(bridge))
+	}
+
+	return &Service{
+		cache:    cache,
+		bridges:  normalized,
+		provider: provider,
+		done:     make(chan struct{}),
+	}
+}
+
+// Fetches the fiat table again.
+// The previous table is kept if it cannot be fetched.
+func (s *Service) Refresh() error {
+	if s.provider == nil {
+		return nil
+	}
+
+	table, err := s.provider.Fetch()
+
+	if err != nil {
+		return errors.Annotate(err, "tried to fetch the fiat rates")
+	}
+
+	s.mutex.Lock()
+	s.table = table
+	s.mutex.Unlock()
+
+	log.WithFields(logrus.Fields{"base": table.Base, "rates": len(table.Rates)}).Info("Fiat rates loaded")
+
+	return nil
+}
+
+// Fetches the fiat table every `period`, until the service stops
+func (s *Service) Start(period time.Duration) {
+	if err := s.Refresh(); err != nil {
+		log.WithField("error", err).Warning("Cannot load the fiat rates")
+	}
+
+	if s.provider == nil || period <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Refresh(); err != nil {
+				log.WithField("error", err).Warning("Cannot reload the fiat rates, the previous ones are kept")
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// Stops the refresh of the fiat table
+func (s *Service) Stop() {
+	close(s.done)
+}
+
+// Returns the last fiat table, nil if it has not been loaded
+func (s *Service) Table() *Table {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.table
+}
+
+// Returns the rate of `base` in `quote` (ex: BTC, EUR), in this order of preference:
+// the currency pair traded, its inverse, a cross rate through a bridge currency,
+// then a rate converted with the fiat table.
+// Returns a NotFound error if none can be computed.
+func (s *Service) Rate(base string, quote string) (*Rate, error) {
+	base, quote = strings.ToUpper(base), strings.ToUpper(quote)
+
+	if base == "" || quote == "" || base == quote {
+		return nil, errors.NotValidf("currency pair %s-%s", base, quote)
+	}
+
+	prices := s.prices()
+
+	if rate, ok := cross(prices, base, quote, s.bridges); ok {
+		return rate, nil
+	}
+
+	if rate, ok := s.fiatRate(prices, base, quote); ok {
+		return rate, nil
+	}
+
+	return nil, errors.NotFoundf("rate of %s-%s", base, quote)
+}
+
+// Returns the value of each asset of `holdings` (ex: {"BTC": 0.5}) in `reporting` (ex: EUR).
+// The assets without any rate are reported as missing.
+func (s *Service) Value(holdings map[string]decimal.Decimal, reporting string) (*Valuation, error) {
+	reporting = strings.ToUpper(reporting)
+
+	if reporting == "" {
+		return nil, errors.NotValidf("empty reporting currency")
+	}
+
+	valuation := &Valuation{Currency: reporting, Assets: []Asset{}, Missing: []string{}}
+
+	for asset, amount := range holdings {
+		asset = strings.ToUpper(asset)
+
+		if asset == reporting {
+			valuation.Assets = append(valuation.Assets, Asset{Asset: asset, Amount: currency.NewDecimal(amount), Value: currency.NewDecimal(amount)})
+			valuation.Total = currency.NewDecimal(valuation.Total.Add(amount))
+			continue
+		}
+
+		rate, err := s.Rate(asset, reporting)
+
+		if err != nil {
+			valuation.Missing = append(valuation.Missing, asset)
+			continue
+		}
+
+		value := amount.Mul(rate.Price.Decimal)
+		valuation.Assets = append(valuation.Assets, Asset{Asset: asset, Amount: currency.NewDecimal(amount), Value: currency.NewDecimal(value), Rate: rate})
+		valuation.Total = currency.NewDecimal(valuation.Total.Add(value))
+	}
+
+	sort.Slice(valuation.Assets, func(i, j int) bool {
+		return valuation.Assets[i].Asset < valuation.Assets[j].Asset
+	})
+	sort.Strings(valuation.Missing)
+
+	return valuation, nil
+}
+
+// Returns the last price of each currency pair, the most recent one among the exchanges
+func (s *Service) prices() map[currency.Pair]Source {
+	prices := map[currency.Pair]Source{}
+
+	for _, ticker := range s.cache.Tickers("", currency.Pair{}) {
+		if ticker.Base == "" || ticker.Quote == "" || ticker.Price.Sign() <= 0 {
+			continue
+		}
+
+		pair := currency.Pair{Base: ticker.Base, Quote: ticker.Quote}
+
+		if last, ok := prices[pair]; ok && last.Time.After(ticker.Received) {
+			continue
+		}
+
+		prices[pair] = Source{Pair: pair, Exchange: ticker.Exchange, Price: ticker.Price, Time: ticker.Received}
+	}
+
+	return prices
+}
+
+// Converts `base` to `quote` with the fiat table and the traded prices:
+// through the table only if it has both currencies, otherwise through a fiat
+// currency of the table which is traded against the other one
+// (ex: BTC-JPY as BTC-USD then USD-JPY, JPY-BTC as JPY-USD then USD-BTC)
+func (s *Service) fiatRate(prices map[currency.Pair]Source, base string, quote string) (*Rate, bool) {
+	table := s.Table()
+
+	if table == nil || (!table.Has(base) && !table.Has(quote)) {
+		return nil, false
+	}
+
+	if table.Has(base) && table.Has(quote) {
+		source, _ := table.source(base, quote)
+		return newRate(base, quote, true, source), true
+	}
+
+	for _, fiat := range table.Currencies() {
+		if fiat == base || fiat == quote {
+			continue
+		}
+
+		// The traded currency is converted to the fiat currency, in the same direction
+		if table.Has(quote) {
+			traded, ok := cross(prices, base, fiat, s.bridges)
+
+			if !ok {
+				continue
+			}
+
+			source, _ := table.source(fiat, quote)
+			return newRate(base, quote, true, append(traded.Sources, source)...), true
+		}
+
+		traded, ok := cross(prices, fiat, quote, s.bridges)
+
+		if !ok {
+			continue
+		}
+
+		source, _ := table.source(base, fiat)
+		return newRate(base, quote, true, append([]Source{source}, traded.Sources...)...), true
+	}
+
+	return nil, false
+}
+
+// Returns the rate of a traded currency pair or of its inverse,
+// otherwise the first cross rate through `bridges`
+func cross(prices map[currency.Pair]Source, base string, quote string, bridges []string) (*Rate, bool) {
+	if source, ok := leg(prices, base, quote); ok {
+		return newRate(base, quote, source.Inverted, source), true
+	}
+
+	for _, bridge := range bridges {
+		if bridge == base || bridge == quote {
+			continue
+		}
+
+		first, ok := leg(prices, base, bridge)
+
+		if !ok {
+			continue
+		}
+
+		second, ok := leg(prices, bridge, quote)
+
+		if !ok {
+			continue
+		}
+
+		return newRate(base, quote, true, first, second), true
+	}
+
+	return nil, false
+}
+
+// Returns the price of `base` in `quote` from the currency pair traded,
+// or from its inverse
+func leg(prices map[currency.Pair]Source, base string, quote string) (Source, bool) {
+	if source, ok := prices[currency.Pair{Base: base, Quote: quote}]; ok {
+		return source, true
+	}
+
+	if source, ok := prices[currency.Pair{Base: quote, Quote: base}]; ok {
+		source.Inverted = true
+		return source, true
+	}
+
+	return Source{}, false
+}
+
+// Multiplies the prices of the sources and divides by the inverted ones
+func newRate(base string, quote string, synthetic bool, sources ...Source) *Rate {
+	rate := &Rate{Base: base, Quote: quote, Synthetic: synthetic, Sources: sources}
+	numerator, denominator := decimal.NewFromInt(1), decimal.NewFromInt(1)
+
+	for i, source := range sources {
+		if source.Inverted {
+			denominator = denominator.Mul(source.Price.Decimal)
+		} else {
+			numerator = numerator.Mul(source.Price.Decimal)
+		}
+
+		if i == 0 || source.Time.Before(rate.Time) {
+			rate.Time = source.Time
+		}
+	}
+
+	// Divided once, so that the rate is not rounded several times
+	rate.Price = currency.NewDecimal(numerator.DivRound(denominator, aggregator.Precision))
+
+	return rate
+}
@@ -0,0 +1,200 @@
+package rates
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fberrez/romantic-aggregator/aggregator"
+	"github.com/fberrez/romantic-aggregator/currency"
+	"github.com/juju/errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns a cache which contains the last price of each currency pair,
+// as "exchange:BASE-QUOTE"
+func newCache(now time.Time, prices map[string]string) *aggregator.Cache {
+	cache := aggregator.NewCache()
+
+	for key, price := range prices {
+		exchange, pair := key[:len(key)-len("BBB-QQQ")-1], key[len(key)-len("BBB-QQQ"):]
+		cache.AddTicker(aggregator.SimpleTicker{
+			Exchange: exchange,
+			Symbol:   pair[:3] + pair[4:],
+			Base:     pair[:3],
+			Quote:    pair[4:],
+			Price:    currency.NewDecimal(decimal.RequireFromString(price)),
+		}, now)
+	}
+
+	return cache
+}
+
+// Writes a fiat table in a temporary file
+func newFiatFile(t *testing.T, content string) string {
+	dir, err := ioutil.TempDir("", "rates")
+	assert.Nil(t, err)
+
+	path := filepath.Join(dir, "fiat.json")
+	assert.Nil(t, ioutil.WriteFile(path, []byte(content), 0644))
+
+	return path
+}
+
+func TestRate(t *testing.T) {
+	now := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	cache := newCache(now, map[string]string{
+		"GDAX:BTC-USD":     "6000",
+		"GDAX:EUR-USD":     "1.2",
+		"Bitfinex:ETH-BTC": "0.08",
+		"Bitfinex:LTC-BTC": "0.015",
+	})
+
+	path := newFiatFile(t, `{"base": "USD", "rates": {"eur": "0.8", "JPY": "110", "GBP": "0.75"}, "time": "2018-06-01T00:00:00Z"}`)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	s := New(cache, []string{"usd", "BTC"}, &FileProvider{Path: path})
+	assert.Nil(t, s.Refresh())
+
+	testCases := []struct {
+		base, quote string
+		price       string
+		synthetic   bool
+		sources     []Source
+		err         func(error) bool
+	}{
+		// Traded currency pair
+		{"BTC", "USD", "6000", false, []Source{
+			{Pair: currency.Pair{Base: "BTC", Quote: "USD"}, Exchange: "GDAX", Price: currency.NewDecimal(decimal.RequireFromString("6000")), Time: now},
+		}, nil},
+		// Inverse of a traded currency pair
+		{"usd", "eur", "0.8333333333333333", true, []Source{
+			{Pair: currency.Pair{Base: "EUR", Quote: "USD"}, Exchange: "GDAX", Price: currency.NewDecimal(decimal.RequireFromString("1.2")), Inverted: true, Time: now},
+		}, nil},
+		// Cross rate through USD
+		{"BTC", "EUR", "5000", true, []Source{
+			{Pair: currency.Pair{Base: "BTC", Quote: "USD"}, Exchange: "GDAX", Price: currency.NewDecimal(decimal.RequireFromString("6000")), Time: now},
+			{Pair: currency.Pair{Base: "EUR", Quote: "USD"}, Exchange: "GDAX", Price: currency.NewDecimal(decimal.RequireFromString("1.2")), Inverted: true, Time: now},
+		}, nil},
+		// Cross rate through BTC, USD is not traded against ETH
+		{"ETH", "LTC", "5.3333333333333333", true, []Source{
+			{Pair: currency.Pair{Base: "ETH", Quote: "BTC"}, Exchange: "Bitfinex", Price: currency.NewDecimal(decimal.RequireFromString("0.08")), Time: now},
+			{Pair: currency.Pair{Base: "LTC", Quote: "BTC"}, Exchange: "Bitfinex", Price: currency.NewDecimal(decimal.RequireFromString("0.015")), Inverted: true, Time: now},
+		}, nil},
+		// Converted with the fiat table
+		{"BTC", "JPY", "660000", true, []Source{
+			{Pair: currency.Pair{Base: "BTC", Quote: "USD"}, Exchange: "GDAX", Price: currency.NewDecimal(decimal.RequireFromString("6000")), Time: now},
+			{Pair: currency.Pair{Base: "USD", Quote: "JPY"}, Exchange: Fiat, Price: currency.NewDecimal(decimal.RequireFromString("110")), Time: time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC)},
+		}, nil},
+		// Converted with the fiat table, then with the traded price
+		{"JPY", "BTC", "0.0000015151515152", true, []Source{
+			{Pair: currency.Pair{Base: "USD", Quote: "JPY"}, Exchange: Fiat, Price: currency.NewDecimal(decimal.RequireFromString("110")), Inverted: true, Time: time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC)},
+			{Pair: currency.Pair{Base: "BTC", Quote: "USD"}, Exchange: "GDAX", Price: currency.NewDecimal(decimal.RequireFromString("6000")), Inverted: true, Time: now},
+		}, nil},
+		// Between two currencies of the fiat table
+		{"GBP", "JPY", "146.6666666666666667", true, []Source{
+			{Pair: currency.Pair{Base: "GBP", Quote: "JPY"}, Exchange: Fiat, Price: currency.NewDecimal(decimal.RequireFromString("146.6666666666666667")), Time: time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC)},
+		}, nil},
+		{"XRP", "USD", "", false, nil, errors.IsNotFound},
+		{"BTC", "btc", "", false, nil, errors.IsNotValid},
+	}
+
+	for _, testCase := range testCases {
+		rate, err := s.Rate(testCase.base, testCase.quote)
+
+		if testCase.err != nil {
+			assert.True(t, testCase.err(err), "%s-%s: %v", testCase.base, testCase.quote, err)
+			continue
+		}
+
+		if !assert.Nil(t, err, "%s-%s", testCase.base, testCase.quote) {
+			continue
+		}
+
+		assert.Equal(t, testCase.price, rate.Price.String(), "%s-%s", testCase.base, testCase.quote)
+		assert.Equal(t, testCase.synthetic, rate.Synthetic, "%s-%s", testCase.base, testCase.quote)
+
+		for i := range testCase.sources {
+			assert.True(t, testCase.sources[i].Price.Equal(rate.Sources[i].Price.Decimal), "%s-%s", testCase.base, testCase.quote)
+			rate.Sources[i].Price = testCase.sources[i].Price
+		}
+
+		assert.Equal(t, testCase.sources, rate.Sources, "%s-%s", testCase.base, testCase.quote)
+	}
+}
+
+func TestRateMostRecent(t *testing.T) {
+	now := time.Now()
+	cache := aggregator.NewCache()
+	cache.AddTicker(aggregator.SimpleTicker{Exchange: "GDAX", Symbol: "BTCUSD", Base: "BTC", Quote: "USD", Price: currency.NewDecimal(decimal.NewFromInt(6000))}, now.Add(-time.Minute))
+	cache.AddTicker(aggregator.SimpleTicker{Exchange: "Bitfinex", Symbol: "BTCUSD", Base: "BTC", Quote: "USD", Price: currency.NewDecimal(decimal.NewFromInt(6100))}, now)
+
+	rate, err := New(cache, DefaultBridges, nil).Rate("BTC", "USD")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "6100", rate.Price.String())
+	assert.Equal(t, "Bitfinex", rate.Sources[0].Exchange)
+}
+
+func TestValue(t *testing.T) {
+	cache := newCache(time.Now(), map[string]string{
+		"GDAX:BTC-USD": "6000",
+		"GDAX:EUR-USD": "1.25",
+	})
+
+	s := New(cache, DefaultBridges, nil)
+
+	valuation, err := s.Value(map[string]decimal.Decimal{
+		"btc": decimal.RequireFromString("0.5"),
+		"USD": decimal.RequireFromString("500"),
+		"EUR": decimal.RequireFromString("100"),
+		"XRP": decimal.RequireFromString("10"),
+	}, "eur")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "EUR", valuation.Currency)
+	assert.Equal(t, "2900", valuation.Total.String())
+	assert.Equal(t, []string{"XRP"}, valuation.Missing)
+	assert.Len(t, valuation.Assets, 3)
+	assert.Equal(t, "BTC", valuation.Assets[0].Asset)
+	assert.Equal(t, "2400", valuation.Assets[0].Value.String())
+	assert.Equal(t, "EUR", valuation.Assets[1].Asset)
+	assert.Nil(t, valuation.Assets[1].Rate)
+
+	_, err = s.Value(nil, "")
+	assert.True(t, errors.IsNotValid(err))
+}
+
+func TestFileProvider(t *testing.T) {
+	testCases := []struct {
+		content string
+		err     func(error) bool
+	}{
+		{`{"base": "usd", "rates": {"eur": "0.8"}}`, nil},
+		{`{"rates": {"EUR": "0.8"}}`, errors.IsNotValid},
+		{`{"base": "USD", "rates": {"EUR": "-1"}}`, errors.IsNotValid},
+		{`{"base": "USD", "rates": {"EUR": "abc"}}`, errors.IsNotValid},
+	}
+
+	for _, testCase := range testCases {
+		path := newFiatFile(t, testCase.content)
+		table, err := (&FileProvider{Path: path}).Fetch()
+		os.RemoveAll(filepath.Dir(path))
+
+		if testCase.err != nil {
+			assert.True(t, testCase.err(err), "%s: %v", testCase.content, err)
+			continue
+		}
+
+		assert.Nil(t, err)
+		assert.Equal(t, "USD", table.Base)
+		assert.Equal(t, []string{"USD", "EUR"}, table.Currencies())
+		assert.False(t, table.Time.IsZero())
+	}
+
+	_, err := (&FileProvider{Path: "unknown.json"}).Fetch()
+	assert.NotNil(t, err)
+}
@@ -1,13 +1,17 @@
 package main
 
 import (
+	"flag"
 	"net/http"
 	"os"
 	"os/signal"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/fberrez/romantic-aggregator/api"
+	"github.com/fberrez/romantic-aggregator/config"
+	"github.com/fberrez/romantic-aggregator/currency"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
@@ -16,46 +20,55 @@ var (
 	log *logrus.Entry = logrus.WithFields(logrus.Fields{"element": "romantic-aggregator"})
 )
 
-func init() {
-	env := os.Getenv("ENVIRONMENT")
-
-	if env == "" || env == "DEV" {
-		// Log as the default ASCII formatter.
-		logrus.SetFormatter(&logrus.TextFormatter{})
-
-		// Output to stdout instead of the default stderr
-		logrus.SetOutput(os.Stdout)
-
-		// Log all messages.
-		logrus.SetLevel(logrus.DebugLevel)
-	} else if env == "PROD" {
-		// Log as JSON instead of the default ASCII formatter.
-		logrus.SetFormatter(&logrus.JSONFormatter{})
-
-		// Output to stdout instead of the default stderr
-		logrus.SetOutput(os.Stdout)
-
-		// Only log the info severity or above.
-		logrus.SetLevel(logrus.InfoLevel)
+// Configures the logging and the mode of gin
+func configureLogging(cfg *config.Config) {
+	cfg.Logging.Apply()
 
+	if cfg.Environment == config.Production {
 		// Sets mode of the API on release mode.
 		gin.SetMode(gin.ReleaseMode)
 	}
 }
 
+// Reloads the configuration each time a SIGHUP is received
+func reloadOnHangup(a *api.Api) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+
+	for range hangup {
+		if _, err := a.Reload(); err != nil {
+			log.WithField("error", err).Error("Cannot reload the configuration")
+		}
+	}
+}
+
 func main() {
 	start := time.Now()
 
 	interrupt := make(chan os.Signal, 1)
 	signal.Notify(interrupt, os.Interrupt)
 
-	port := os.Getenv("API_PORT")
+	cfg, err := config.Load(os.Args[1:])
+
+	if err == flag.ErrHelp {
+		os.Exit(0)
+	}
+
+	if err != nil {
+		if invalid, ok := err.(*config.ValidationError); ok {
+			for _, problem := range invalid.Problems {
+				log.WithField("path", invalid.Path).Error(problem)
+			}
+		}
 
-	if port == "" {
-		port = "4242"
+		log.WithField("error", err).Fatal("Cannot load the configuration")
 	}
 
-	a := api.Initiliaze()
+	configureLogging(cfg)
+	currency.SetDecimalStrings(cfg.Sinks.Decimals == "string")
+	port := cfg.API.Port
+
+	a := api.Initiliaze(cfg)
 
 	srv := &http.Server{
 		Addr:    ":" + port,
@@ -64,7 +77,9 @@ func main() {
 
 	waitGroupApi := sync.WaitGroup{}
 
-	a.Start(waitGroupApi)
+	a.Start(&waitGroupApi)
+
+	go reloadOnHangup(a)
 
 	go func() {
 		for {
@@ -89,7 +104,7 @@ func main() {
 	elapsed := time.Since(start)
 	log.Infof("Server started in %s", elapsed)
 
-	err := srv.ListenAndServe()
+	err = srv.ListenAndServe()
 
 	if err != nil && err.Error() != "http: Server closed" {
 		log.WithField("error", err).Errorf("An error occured while launching the server")
@@ -0,0 +1,1300 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: aggregator.proto
+
+package rpc
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type SubscribeRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Currency pairs (ex: BTC-USD)
+	Pairs []string `protobuf:"bytes,1,rep,name=pairs,proto3" json:"pairs,omitempty"`
+	// Channels, ticker by default
+	Channels []string `protobuf:"bytes,2,rep,name=channels,proto3" json:"channels,omitempty"`
+	// Exchanges, every exchange listing the currency pairs by default
+	Exchanges []string `protobuf:"bytes,3,rep,name=exchanges,proto3" json:"exchanges,omitempty"`
+	// Aggregation interval (ex: 5m), unchanged if empty
+	Interval string `protobuf:"bytes,4,opt,name=interval,proto3" json:"interval,omitempty"`
+	// Waits for the acknowledgement of the exchanges before answering
+	Wait bool `protobuf:"varint,5,opt,name=wait,proto3" json:"wait,omitempty"`
+	// Aggregation intervals of these currency pairs (ex: 10s), unchanged if empty
+	Intervals     []string `protobuf:"bytes,6,rep,name=intervals,proto3" json:"intervals,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubscribeRequest) Reset() {
+	*x = SubscribeRequest{}
+	mi := &file_aggregator_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubscribeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscribeRequest) ProtoMessage() {}
+
+func (x *SubscribeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_aggregator_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscribeRequest.ProtoReflect.Descriptor instead.
+func (*SubscribeRequest) Descriptor() ([]byte, []int) {
+	return file_aggregator_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *SubscribeRequest) GetPairs() []string {
+	if x != nil {
+		return x.Pairs
+	}
+	return nil
+}
+
+func (x *SubscribeRequest) GetChannels() []string {
+	if x != nil {
+		return x.Channels
+	}
+	return nil
+}
+
+func (x *SubscribeRequest) GetExchanges() []string {
+	if x != nil {
+		return x.Exchanges
+	}
+	return nil
+}
+
+func (x *SubscribeRequest) GetInterval() string {
+	if x != nil {
+		return x.Interval
+	}
+	return ""
+}
+
+func (x *SubscribeRequest) GetWait() bool {
+	if x != nil {
+		return x.Wait
+	}
+	return false
+}
+
+func (x *SubscribeRequest) GetIntervals() []string {
+	if x != nil {
+		return x.Intervals
+	}
+	return nil
+}
+
+type UnsubscribeRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Currency pairs (ex: BTC-USD)
+	Pairs []string `protobuf:"bytes,1,rep,name=pairs,proto3" json:"pairs,omitempty"`
+	// Channels, ticker by default
+	Channels []string `protobuf:"bytes,2,rep,name=channels,proto3" json:"channels,omitempty"`
+	// Exchanges, every exchange listing the currency pairs by default
+	Exchanges []string `protobuf:"bytes,3,rep,name=exchanges,proto3" json:"exchanges,omitempty"`
+	// Waits for the acknowledgement of the exchanges before answering
+	Wait          bool `protobuf:"varint,4,opt,name=wait,proto3" json:"wait,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnsubscribeRequest) Reset() {
+	*x = UnsubscribeRequest{}
+	mi := &file_aggregator_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnsubscribeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnsubscribeRequest) ProtoMessage() {}
+
+func (x *UnsubscribeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_aggregator_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnsubscribeRequest.ProtoReflect.Descriptor instead.
+func (*UnsubscribeRequest) Descriptor() ([]byte, []int) {
+	return file_aggregator_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *UnsubscribeRequest) GetPairs() []string {
+	if x != nil {
+		return x.Pairs
+	}
+	return nil
+}
+
+func (x *UnsubscribeRequest) GetChannels() []string {
+	if x != nil {
+		return x.Channels
+	}
+	return nil
+}
+
+func (x *UnsubscribeRequest) GetExchanges() []string {
+	if x != nil {
+		return x.Exchanges
+	}
+	return nil
+}
+
+func (x *UnsubscribeRequest) GetWait() bool {
+	if x != nil {
+		return x.Wait
+	}
+	return false
+}
+
+type SubscriptionResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Id of the request, which can be consulted on /v1/requests/{id}
+	Request string `protobuf:"bytes,1,opt,name=request,proto3" json:"request,omitempty"`
+	// pending, confirmed or rejected
+	Status string `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	// Outcome on each exchange
+	Outcomes      []*Outcome `protobuf:"bytes,3,rep,name=outcomes,proto3" json:"outcomes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubscriptionResponse) Reset() {
+	*x = SubscriptionResponse{}
+	mi := &file_aggregator_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubscriptionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscriptionResponse) ProtoMessage() {}
+
+func (x *SubscriptionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_aggregator_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscriptionResponse.ProtoReflect.Descriptor instead.
+func (*SubscriptionResponse) Descriptor() ([]byte, []int) {
+	return file_aggregator_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *SubscriptionResponse) GetRequest() string {
+	if x != nil {
+		return x.Request
+	}
+	return ""
+}
+
+func (x *SubscriptionResponse) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *SubscriptionResponse) GetOutcomes() []*Outcome {
+	if x != nil {
+		return x.Outcomes
+	}
+	return nil
+}
+
+type Outcome struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Exchange      string                 `protobuf:"bytes,1,opt,name=exchange,proto3" json:"exchange,omitempty"`
+	Status        string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	Reason        string                 `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+	Items         []*ItemOutcome         `protobuf:"bytes,4,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Outcome) Reset() {
+	*x = Outcome{}
+	mi := &file_aggregator_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Outcome) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Outcome) ProtoMessage() {}
+
+func (x *Outcome) ProtoReflect() protoreflect.Message {
+	mi := &file_aggregator_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Outcome.ProtoReflect.Descriptor instead.
+func (*Outcome) Descriptor() ([]byte, []int) {
+	return file_aggregator_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Outcome) GetExchange() string {
+	if x != nil {
+		return x.Exchange
+	}
+	return ""
+}
+
+func (x *Outcome) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Outcome) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *Outcome) GetItems() []*ItemOutcome {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+type ItemOutcome struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Symbol        string                 `protobuf:"bytes,1,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	Channel       string                 `protobuf:"bytes,2,opt,name=channel,proto3" json:"channel,omitempty"`
+	Status        string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	Reason        string                 `protobuf:"bytes,4,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ItemOutcome) Reset() {
+	*x = ItemOutcome{}
+	mi := &file_aggregator_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ItemOutcome) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ItemOutcome) ProtoMessage() {}
+
+func (x *ItemOutcome) ProtoReflect() protoreflect.Message {
+	mi := &file_aggregator_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ItemOutcome.ProtoReflect.Descriptor instead.
+func (*ItemOutcome) Descriptor() ([]byte, []int) {
+	return file_aggregator_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ItemOutcome) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *ItemOutcome) GetChannel() string {
+	if x != nil {
+		return x.Channel
+	}
+	return ""
+}
+
+func (x *ItemOutcome) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *ItemOutcome) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type ListSubscriptionsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Name of the exchange, every exchange if empty
+	Exchange      string `protobuf:"bytes,1,opt,name=exchange,proto3" json:"exchange,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListSubscriptionsRequest) Reset() {
+	*x = ListSubscriptionsRequest{}
+	mi := &file_aggregator_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListSubscriptionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSubscriptionsRequest) ProtoMessage() {}
+
+func (x *ListSubscriptionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_aggregator_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSubscriptionsRequest.ProtoReflect.Descriptor instead.
+func (*ListSubscriptionsRequest) Descriptor() ([]byte, []int) {
+	return file_aggregator_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ListSubscriptionsRequest) GetExchange() string {
+	if x != nil {
+		return x.Exchange
+	}
+	return ""
+}
+
+type ListSubscriptionsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Subscriptions []*Subscription        `protobuf:"bytes,1,rep,name=subscriptions,proto3" json:"subscriptions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListSubscriptionsResponse) Reset() {
+	*x = ListSubscriptionsResponse{}
+	mi := &file_aggregator_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListSubscriptionsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSubscriptionsResponse) ProtoMessage() {}
+
+func (x *ListSubscriptionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_aggregator_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSubscriptionsResponse.ProtoReflect.Descriptor instead.
+func (*ListSubscriptionsResponse) Descriptor() ([]byte, []int) {
+	return file_aggregator_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ListSubscriptionsResponse) GetSubscriptions() []*Subscription {
+	if x != nil {
+		return x.Subscriptions
+	}
+	return nil
+}
+
+type Subscription struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Exchange string                 `protobuf:"bytes,1,opt,name=exchange,proto3" json:"exchange,omitempty"`
+	// Currency pair (ex: BTC-USD)
+	Pair string `protobuf:"bytes,2,opt,name=pair,proto3" json:"pair,omitempty"`
+	// Symbol in the exchange format
+	Symbol        string                 `protobuf:"bytes,3,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	Channel       string                 `protobuf:"bytes,4,opt,name=channel,proto3" json:"channel,omitempty"`
+	Status        string                 `protobuf:"bytes,5,opt,name=status,proto3" json:"status,omitempty"`
+	Reason        string                 `protobuf:"bytes,6,opt,name=reason,proto3" json:"reason,omitempty"`
+	Since         *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=since,proto3" json:"since,omitempty"`
+	LastMessage   *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=last_message,json=lastMessage,proto3" json:"last_message,omitempty"`
+	Messages      int64                  `protobuf:"varint,9,opt,name=messages,proto3" json:"messages,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Subscription) Reset() {
+	*x = Subscription{}
+	mi := &file_aggregator_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Subscription) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Subscription) ProtoMessage() {}
+
+func (x *Subscription) ProtoReflect() protoreflect.Message {
+	mi := &file_aggregator_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Subscription.ProtoReflect.Descriptor instead.
+func (*Subscription) Descriptor() ([]byte, []int) {
+	return file_aggregator_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Subscription) GetExchange() string {
+	if x != nil {
+		return x.Exchange
+	}
+	return ""
+}
+
+func (x *Subscription) GetPair() string {
+	if x != nil {
+		return x.Pair
+	}
+	return ""
+}
+
+func (x *Subscription) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *Subscription) GetChannel() string {
+	if x != nil {
+		return x.Channel
+	}
+	return ""
+}
+
+func (x *Subscription) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Subscription) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *Subscription) GetSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Since
+	}
+	return nil
+}
+
+func (x *Subscription) GetLastMessage() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastMessage
+	}
+	return nil
+}
+
+func (x *Subscription) GetMessages() int64 {
+	if x != nil {
+		return x.Messages
+	}
+	return 0
+}
+
+type SetIntervalsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Aggregation interval (ex: 5m)
+	Interval      string `protobuf:"bytes,1,opt,name=interval,proto3" json:"interval,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetIntervalsRequest) Reset() {
+	*x = SetIntervalsRequest{}
+	mi := &file_aggregator_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetIntervalsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetIntervalsRequest) ProtoMessage() {}
+
+func (x *SetIntervalsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_aggregator_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetIntervalsRequest.ProtoReflect.Descriptor instead.
+func (*SetIntervalsRequest) Descriptor() ([]byte, []int) {
+	return file_aggregator_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *SetIntervalsRequest) GetInterval() string {
+	if x != nil {
+		return x.Interval
+	}
+	return ""
+}
+
+type SetIntervalsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Interval      string                 `protobuf:"bytes,1,opt,name=interval,proto3" json:"interval,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetIntervalsResponse) Reset() {
+	*x = SetIntervalsResponse{}
+	mi := &file_aggregator_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetIntervalsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetIntervalsResponse) ProtoMessage() {}
+
+func (x *SetIntervalsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_aggregator_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetIntervalsResponse.ProtoReflect.Descriptor instead.
+func (*SetIntervalsResponse) Descriptor() ([]byte, []int) {
+	return file_aggregator_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *SetIntervalsResponse) GetInterval() string {
+	if x != nil {
+		return x.Interval
+	}
+	return ""
+}
+
+type SetPairIntervalsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Currency pair (ex: BTC-USD)
+	Pair string `protobuf:"bytes,1,opt,name=pair,proto3" json:"pair,omitempty"`
+	// Aggregation intervals (ex: 10s), the default interval if empty
+	Intervals     []string `protobuf:"bytes,2,rep,name=intervals,proto3" json:"intervals,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetPairIntervalsRequest) Reset() {
+	*x = SetPairIntervalsRequest{}
+	mi := &file_aggregator_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetPairIntervalsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetPairIntervalsRequest) ProtoMessage() {}
+
+func (x *SetPairIntervalsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_aggregator_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetPairIntervalsRequest.ProtoReflect.Descriptor instead.
+func (*SetPairIntervalsRequest) Descriptor() ([]byte, []int) {
+	return file_aggregator_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *SetPairIntervalsRequest) GetPair() string {
+	if x != nil {
+		return x.Pair
+	}
+	return ""
+}
+
+func (x *SetPairIntervalsRequest) GetIntervals() []string {
+	if x != nil {
+		return x.Intervals
+	}
+	return nil
+}
+
+type SetPairIntervalsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Pair          string                 `protobuf:"bytes,1,opt,name=pair,proto3" json:"pair,omitempty"`
+	Intervals     []string               `protobuf:"bytes,2,rep,name=intervals,proto3" json:"intervals,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetPairIntervalsResponse) Reset() {
+	*x = SetPairIntervalsResponse{}
+	mi := &file_aggregator_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetPairIntervalsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetPairIntervalsResponse) ProtoMessage() {}
+
+func (x *SetPairIntervalsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_aggregator_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetPairIntervalsResponse.ProtoReflect.Descriptor instead.
+func (*SetPairIntervalsResponse) Descriptor() ([]byte, []int) {
+	return file_aggregator_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *SetPairIntervalsResponse) GetPair() string {
+	if x != nil {
+		return x.Pair
+	}
+	return ""
+}
+
+func (x *SetPairIntervalsResponse) GetIntervals() []string {
+	if x != nil {
+		return x.Intervals
+	}
+	return nil
+}
+
+type StreamTickersRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Exchanges of the tickers (ex: GDAX)
+	Exchanges []string `protobuf:"bytes,1,rep,name=exchanges,proto3" json:"exchanges,omitempty"`
+	// Currency pairs (ex: BTC-USD)
+	Symbols []string `protobuf:"bytes,2,rep,name=symbols,proto3" json:"symbols,omitempty"`
+	// Intervals of the aggregates (ex: 5m)
+	Intervals []string `protobuf:"bytes,3,rep,name=intervals,proto3" json:"intervals,omitempty"`
+	// Types of records (ticker, aggregate)
+	Types         []string `protobuf:"bytes,4,rep,name=types,proto3" json:"types,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamTickersRequest) Reset() {
+	*x = StreamTickersRequest{}
+	mi := &file_aggregator_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamTickersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamTickersRequest) ProtoMessage() {}
+
+func (x *StreamTickersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_aggregator_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamTickersRequest.ProtoReflect.Descriptor instead.
+func (*StreamTickersRequest) Descriptor() ([]byte, []int) {
+	return file_aggregator_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *StreamTickersRequest) GetExchanges() []string {
+	if x != nil {
+		return x.Exchanges
+	}
+	return nil
+}
+
+func (x *StreamTickersRequest) GetSymbols() []string {
+	if x != nil {
+		return x.Symbols
+	}
+	return nil
+}
+
+func (x *StreamTickersRequest) GetIntervals() []string {
+	if x != nil {
+		return x.Intervals
+	}
+	return nil
+}
+
+func (x *StreamTickersRequest) GetTypes() []string {
+	if x != nil {
+		return x.Types
+	}
+	return nil
+}
+
+type Record struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// ticker, aggregate, or dropped when records were dropped
+	// because the client was too slow
+	Type string `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	// Name of the exchange, empty for an aggregate
+	Exchange string `protobuf:"bytes,2,opt,name=exchange,proto3" json:"exchange,omitempty"`
+	// Symbol of the currency pair (ex: BTCUSD)
+	Symbol string                 `protobuf:"bytes,3,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	Time   *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=time,proto3" json:"time,omitempty"`
+	Price  float64                `protobuf:"fixed64,6,opt,name=price,proto3" json:"price,omitempty"`
+	Bid    float64                `protobuf:"fixed64,7,opt,name=bid,proto3" json:"bid,omitempty"`
+	Ask    float64                `protobuf:"fixed64,8,opt,name=ask,proto3" json:"ask,omitempty"`
+	Volume float64                `protobuf:"fixed64,9,opt,name=volume,proto3" json:"volume,omitempty"`
+	// High and low prices of an aggregate
+	High float64 `protobuf:"fixed64,10,opt,name=high,proto3" json:"high,omitempty"`
+	Low  float64 `protobuf:"fixed64,11,opt,name=low,proto3" json:"low,omitempty"`
+	// Number of records dropped, for a dropped record
+	Dropped uint64 `protobuf:"varint,12,opt,name=dropped,proto3" json:"dropped,omitempty"`
+	// Interval of the aggregation (ex: 5m, 1M), empty for a ticker
+	Interval string `protobuf:"bytes,13,opt,name=interval,proto3" json:"interval,omitempty"`
+	// Canonical codes of the currency pair (ex: BTC, USDT)
+	Base  string `protobuf:"bytes,14,opt,name=base,proto3" json:"base,omitempty"`
+	Quote string `protobuf:"bytes,15,opt,name=quote,proto3" json:"quote,omitempty"`
+	// Symbol used by the exchange (ex: BTCUST on Bitfinex), empty for an aggregate
+	NativeSymbol string `protobuf:"bytes,16,opt,name=native_symbol,json=nativeSymbol,proto3" json:"native_symbol,omitempty"`
+	// Symbols used by each exchange which sent a ticker, for an aggregate
+	NativeSymbols map[string]string `protobuf:"bytes,17,rep,name=native_symbols,json=nativeSymbols,proto3" json:"native_symbols,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// Exact values of price, bid, ask, volume, high and low, which the doubles round
+	Decimals      *Decimals `protobuf:"bytes,18,opt,name=decimals,proto3" json:"decimals,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Record) Reset() {
+	*x = Record{}
+	mi := &file_aggregator_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Record) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Record) ProtoMessage() {}
+
+func (x *Record) ProtoReflect() protoreflect.Message {
+	mi := &file_aggregator_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Record.ProtoReflect.Descriptor instead.
+func (*Record) Descriptor() ([]byte, []int) {
+	return file_aggregator_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *Record) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Record) GetExchange() string {
+	if x != nil {
+		return x.Exchange
+	}
+	return ""
+}
+
+func (x *Record) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *Record) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *Record) GetPrice() float64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *Record) GetBid() float64 {
+	if x != nil {
+		return x.Bid
+	}
+	return 0
+}
+
+func (x *Record) GetAsk() float64 {
+	if x != nil {
+		return x.Ask
+	}
+	return 0
+}
+
+func (x *Record) GetVolume() float64 {
+	if x != nil {
+		return x.Volume
+	}
+	return 0
+}
+
+func (x *Record) GetHigh() float64 {
+	if x != nil {
+		return x.High
+	}
+	return 0
+}
+
+func (x *Record) GetLow() float64 {
+	if x != nil {
+		return x.Low
+	}
+	return 0
+}
+
+func (x *Record) GetDropped() uint64 {
+	if x != nil {
+		return x.Dropped
+	}
+	return 0
+}
+
+func (x *Record) GetInterval() string {
+	if x != nil {
+		return x.Interval
+	}
+	return ""
+}
+
+func (x *Record) GetBase() string {
+	if x != nil {
+		return x.Base
+	}
+	return ""
+}
+
+func (x *Record) GetQuote() string {
+	if x != nil {
+		return x.Quote
+	}
+	return ""
+}
+
+func (x *Record) GetNativeSymbol() string {
+	if x != nil {
+		return x.NativeSymbol
+	}
+	return ""
+}
+
+func (x *Record) GetNativeSymbols() map[string]string {
+	if x != nil {
+		return x.NativeSymbols
+	}
+	return nil
+}
+
+func (x *Record) GetDecimals() *Decimals {
+	if x != nil {
+		return x.Decimals
+	}
+	return nil
+}
+
+// Prices and volume written as decimal strings (ex: "0.1")
+type Decimals struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Price         string                 `protobuf:"bytes,1,opt,name=price,proto3" json:"price,omitempty"`
+	Bid           string                 `protobuf:"bytes,2,opt,name=bid,proto3" json:"bid,omitempty"`
+	Ask           string                 `protobuf:"bytes,3,opt,name=ask,proto3" json:"ask,omitempty"`
+	Volume        string                 `protobuf:"bytes,4,opt,name=volume,proto3" json:"volume,omitempty"`
+	High          string                 `protobuf:"bytes,5,opt,name=high,proto3" json:"high,omitempty"`
+	Low           string                 `protobuf:"bytes,6,opt,name=low,proto3" json:"low,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Decimals) Reset() {
+	*x = Decimals{}
+	mi := &file_aggregator_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Decimals) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Decimals) ProtoMessage() {}
+
+func (x *Decimals) ProtoReflect() protoreflect.Message {
+	mi := &file_aggregator_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Decimals.ProtoReflect.Descriptor instead.
+func (*Decimals) Descriptor() ([]byte, []int) {
+	return file_aggregator_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *Decimals) GetPrice() string {
+	if x != nil {
+		return x.Price
+	}
+	return ""
+}
+
+func (x *Decimals) GetBid() string {
+	if x != nil {
+		return x.Bid
+	}
+	return ""
+}
+
+func (x *Decimals) GetAsk() string {
+	if x != nil {
+		return x.Ask
+	}
+	return ""
+}
+
+func (x *Decimals) GetVolume() string {
+	if x != nil {
+		return x.Volume
+	}
+	return ""
+}
+
+func (x *Decimals) GetHigh() string {
+	if x != nil {
+		return x.High
+	}
+	return ""
+}
+
+func (x *Decimals) GetLow() string {
+	if x != nil {
+		return x.Low
+	}
+	return ""
+}
+
+var File_aggregator_proto protoreflect.FileDescriptor
+
+const file_aggregator_proto_rawDesc = "" +
+	"\n" +
+	"\x10aggregator.proto\x12\x16romantic.aggregator.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xb0\x01\n" +
+	"\x10SubscribeRequest\x12\x14\n" +
+	"\x05pairs\x18\x01 \x03(\tR\x05pairs\x12\x1a\n" +
+	"\bchannels\x18\x02 \x03(\tR\bchannels\x12\x1c\n" +
+	"\texchanges\x18\x03 \x03(\tR\texchanges\x12\x1a\n" +
+	"\binterval\x18\x04 \x01(\tR\binterval\x12\x12\n" +
+	"\x04wait\x18\x05 \x01(\bR\x04wait\x12\x1c\n" +
+	"\tintervals\x18\x06 \x03(\tR\tintervals\"x\n" +
+	"\x12UnsubscribeRequest\x12\x14\n" +
+	"\x05pairs\x18\x01 \x03(\tR\x05pairs\x12\x1a\n" +
+	"\bchannels\x18\x02 \x03(\tR\bchannels\x12\x1c\n" +
+	"\texchanges\x18\x03 \x03(\tR\texchanges\x12\x12\n" +
+	"\x04wait\x18\x04 \x01(\bR\x04wait\"\x85\x01\n" +
+	"\x14SubscriptionResponse\x12\x18\n" +
+	"\arequest\x18\x01 \x01(\tR\arequest\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12;\n" +
+	"\boutcomes\x18\x03 \x03(\v2\x1f.romantic.aggregator.v1.OutcomeR\boutcomes\"\x90\x01\n" +
+	"\aOutcome\x12\x1a\n" +
+	"\bexchange\x18\x01 \x01(\tR\bexchange\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\x129\n" +
+	"\x05items\x18\x04 \x03(\v2#.romantic.aggregator.v1.ItemOutcomeR\x05items\"o\n" +
+	"\vItemOutcome\x12\x16\n" +
+	"\x06symbol\x18\x01 \x01(\tR\x06symbol\x12\x18\n" +
+	"\achannel\x18\x02 \x01(\tR\achannel\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\x12\x16\n" +
+	"\x06reason\x18\x04 \x01(\tR\x06reason\"6\n" +
+	"\x18ListSubscriptionsRequest\x12\x1a\n" +
+	"\bexchange\x18\x01 \x01(\tR\bexchange\"g\n" +
+	"\x19ListSubscriptionsResponse\x12J\n" +
+	"\rsubscriptions\x18\x01 \x03(\v2$.romantic.aggregator.v1.SubscriptionR\rsubscriptions\"\xad\x02\n" +
+	"\fSubscription\x12\x1a\n" +
+	"\bexchange\x18\x01 \x01(\tR\bexchange\x12\x12\n" +
+	"\x04pair\x18\x02 \x01(\tR\x04pair\x12\x16\n" +
+	"\x06symbol\x18\x03 \x01(\tR\x06symbol\x12\x18\n" +
+	"\achannel\x18\x04 \x01(\tR\achannel\x12\x16\n" +
+	"\x06status\x18\x05 \x01(\tR\x06status\x12\x16\n" +
+	"\x06reason\x18\x06 \x01(\tR\x06reason\x120\n" +
+	"\x05since\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\x05since\x12=\n" +
+	"\flast_message\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\vlastMessage\x12\x1a\n" +
+	"\bmessages\x18\t \x01(\x03R\bmessages\"1\n" +
+	"\x13SetIntervalsRequest\x12\x1a\n" +
+	"\binterval\x18\x01 \x01(\tR\binterval\"2\n" +
+	"\x14SetIntervalsResponse\x12\x1a\n" +
+	"\binterval\x18\x01 \x01(\tR\binterval\"K\n" +
+	"\x17SetPairIntervalsRequest\x12\x12\n" +
+	"\x04pair\x18\x01 \x01(\tR\x04pair\x12\x1c\n" +
+	"\tintervals\x18\x02 \x03(\tR\tintervals\"L\n" +
+	"\x18SetPairIntervalsResponse\x12\x12\n" +
+	"\x04pair\x18\x01 \x01(\tR\x04pair\x12\x1c\n" +
+	"\tintervals\x18\x02 \x03(\tR\tintervals\"\x82\x01\n" +
+	"\x14StreamTickersRequest\x12\x1c\n" +
+	"\texchanges\x18\x01 \x03(\tR\texchanges\x12\x18\n" +
+	"\asymbols\x18\x02 \x03(\tR\asymbols\x12\x1c\n" +
+	"\tintervals\x18\x03 \x03(\tR\tintervals\x12\x14\n" +
+	"\x05types\x18\x04 \x03(\tR\x05types\"\xdd\x04\n" +
+	"\x06Record\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x1a\n" +
+	"\bexchange\x18\x02 \x01(\tR\bexchange\x12\x16\n" +
+	"\x06symbol\x18\x03 \x01(\tR\x06symbol\x12.\n" +
+	"\x04time\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x12\x14\n" +
+	"\x05price\x18\x06 \x01(\x01R\x05price\x12\x10\n" +
+	"\x03bid\x18\a \x01(\x01R\x03bid\x12\x10\n" +
+	"\x03ask\x18\b \x01(\x01R\x03ask\x12\x16\n" +
+	"\x06volume\x18\t \x01(\x01R\x06volume\x12\x12\n" +
+	"\x04high\x18\n" +
+	" \x01(\x01R\x04high\x12\x10\n" +
+	"\x03low\x18\v \x01(\x01R\x03low\x12\x18\n" +
+	"\adropped\x18\f \x01(\x04R\adropped\x12\x1a\n" +
+	"\binterval\x18\r \x01(\tR\binterval\x12\x12\n" +
+	"\x04base\x18\x0e \x01(\tR\x04base\x12\x14\n" +
+	"\x05quote\x18\x0f \x01(\tR\x05quote\x12#\n" +
+	"\rnative_symbol\x18\x10 \x01(\tR\fnativeSymbol\x12X\n" +
+	"\x0enative_symbols\x18\x11 \x03(\v21.romantic.aggregator.v1.Record.NativeSymbolsEntryR\rnativeSymbols\x12<\n" +
+	"\bdecimals\x18\x12 \x01(\v2 .romantic.aggregator.v1.DecimalsR\bdecimals\x1a@\n" +
+	"\x12NativeSymbolsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01J\x04\b\x04\x10\x05\"\x82\x01\n" +
+	"\bDecimals\x12\x14\n" +
+	"\x05price\x18\x01 \x01(\tR\x05price\x12\x10\n" +
+	"\x03bid\x18\x02 \x01(\tR\x03bid\x12\x10\n" +
+	"\x03ask\x18\x03 \x01(\tR\x03ask\x12\x16\n" +
+	"\x06volume\x18\x04 \x01(\tR\x06volume\x12\x12\n" +
+	"\x04high\x18\x05 \x01(\tR\x04high\x12\x10\n" +
+	"\x03low\x18\x06 \x01(\tR\x03low2\x97\x05\n" +
+	"\n" +
+	"Aggregator\x12c\n" +
+	"\tSubscribe\x12(.romantic.aggregator.v1.SubscribeRequest\x1a,.romantic.aggregator.v1.SubscriptionResponse\x12g\n" +
+	"\vUnsubscribe\x12*.romantic.aggregator.v1.UnsubscribeRequest\x1a,.romantic.aggregator.v1.SubscriptionResponse\x12x\n" +
+	"\x11ListSubscriptions\x120.romantic.aggregator.v1.ListSubscriptionsRequest\x1a1.romantic.aggregator.v1.ListSubscriptionsResponse\x12i\n" +
+	"\fSetIntervals\x12+.romantic.aggregator.v1.SetIntervalsRequest\x1a,.romantic.aggregator.v1.SetIntervalsResponse\x12u\n" +
+	"\x10SetPairIntervals\x12/.romantic.aggregator.v1.SetPairIntervalsRequest\x1a0.romantic.aggregator.v1.SetPairIntervalsResponse\x12_\n" +
+	"\rStreamTickers\x12,.romantic.aggregator.v1.StreamTickersRequest\x1a\x1e.romantic.aggregator.v1.Record0\x01B,Z*github.com/fberrez/romantic-aggregator/rpcb\x06proto3"
+
+var (
+	file_aggregator_proto_rawDescOnce sync.Once
+	file_aggregator_proto_rawDescData []byte
+)
+
+func file_aggregator_proto_rawDescGZIP() []byte {
+	file_aggregator_proto_rawDescOnce.Do(func() {
+		file_aggregator_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_aggregator_proto_rawDesc), len(file_aggregator_proto_rawDesc)))
+	})
+	return file_aggregator_proto_rawDescData
+}
+
+var file_aggregator_proto_msgTypes = make([]protoimpl.MessageInfo, 16)
+var file_aggregator_proto_goTypes = []any{
+	(*SubscribeRequest)(nil),          // 0: romantic.aggregator.v1.SubscribeRequest
+	(*UnsubscribeRequest)(nil),        // 1: romantic.aggregator.v1.UnsubscribeRequest
+	(*SubscriptionResponse)(nil),      // 2: romantic.aggregator.v1.SubscriptionResponse
+	(*Outcome)(nil),                   // 3: romantic.aggregator.v1.Outcome
+	(*ItemOutcome)(nil),               // 4: romantic.aggregator.v1.ItemOutcome
+	(*ListSubscriptionsRequest)(nil),  // 5: romantic.aggregator.v1.ListSubscriptionsRequest
+	(*ListSubscriptionsResponse)(nil), // 6: romantic.aggregator.v1.ListSubscriptionsResponse
+	(*Subscription)(nil),              // 7: romantic.aggregator.v1.Subscription
+	(*SetIntervalsRequest)(nil),       // 8: romantic.aggregator.v1.SetIntervalsRequest
+	(*SetIntervalsResponse)(nil),      // 9: romantic.aggregator.v1.SetIntervalsResponse
+	(*SetPairIntervalsRequest)(nil),   // 10: romantic.aggregator.v1.SetPairIntervalsRequest
+	(*SetPairIntervalsResponse)(nil),  // 11: romantic.aggregator.v1.SetPairIntervalsResponse
+	(*StreamTickersRequest)(nil),      // 12: romantic.aggregator.v1.StreamTickersRequest
+	(*Record)(nil),                    // 13: romantic.aggregator.v1.Record
+	(*Decimals)(nil),                  // 14: romantic.aggregator.v1.Decimals
+	nil,                               // 15: romantic.aggregator.v1.Record.NativeSymbolsEntry
+	(*timestamppb.Timestamp)(nil),     // 16: google.protobuf.Timestamp
+}
+var file_aggregator_proto_depIdxs = []int32{
+	3,  // 0: romantic.aggregator.v1.SubscriptionResponse.outcomes:type_name -> romantic.aggregator.v1.Outcome
+	4,  // 1: romantic.aggregator.v1.Outcome.items:type_name -> romantic.aggregator.v1.ItemOutcome
+	7,  // 2: romantic.aggregator.v1.ListSubscriptionsResponse.subscriptions:type_name -> romantic.aggregator.v1.Subscription
+	16, // 3: romantic.aggregator.v1.Subscription.since:type_name -> google.protobuf.Timestamp
+	16, // 4: romantic.aggregator.v1.Subscription.last_message:type_name -> google.protobuf.Timestamp
+	16, // 5: romantic.aggregator.v1.Record.time:type_name -> google.protobuf.Timestamp
+	15, // 6: romantic.aggregator.v1.Record.native_symbols:type_name -> romantic.aggregator.v1.Record.NativeSymbolsEntry
+	14, // 7: romantic.aggregator.v1.Record.decimals:type_name -> romantic.aggregator.v1.Decimals
+	0,  // 8: romantic.aggregator.v1.Aggregator.Subscribe:input_type -> romantic.aggregator.v1.SubscribeRequest
+	1,  // 9: romantic.aggregator.v1.Aggregator.Unsubscribe:input_type -> romantic.aggregator.v1.UnsubscribeRequest
+	5,  // 10: romantic.aggregator.v1.Aggregator.ListSubscriptions:input_type -> romantic.aggregator.v1.ListSubscriptionsRequest
+	8,  // 11: romantic.aggregator.v1.Aggregator.SetIntervals:input_type -> romantic.aggregator.v1.SetIntervalsRequest
+	10, // 12: romantic.aggregator.v1.Aggregator.SetPairIntervals:input_type -> romantic.aggregator.v1.SetPairIntervalsRequest
+	12, // 13: romantic.aggregator.v1.Aggregator.StreamTickers:input_type -> romantic.aggregator.v1.StreamTickersRequest
+	2,  // 14: romantic.aggregator.v1.Aggregator.Subscribe:output_type -> romantic.aggregator.v1.SubscriptionResponse
+	2,  // 15: romantic.aggregator.v1.Aggregator.Unsubscribe:output_type -> romantic.aggregator.v1.SubscriptionResponse
+	6,  // 16: romantic.aggregator.v1.Aggregator.ListSubscriptions:output_type -> romantic.aggregator.v1.ListSubscriptionsResponse
+	9,  // 17: romantic.aggregator.v1.Aggregator.SetIntervals:output_type -> romantic.aggregator.v1.SetIntervalsResponse
+	11, // 18: romantic.aggregator.v1.Aggregator.SetPairIntervals:output_type -> romantic.aggregator.v1.SetPairIntervalsResponse
+	13, // 19: romantic.aggregator.v1.Aggregator.StreamTickers:output_type -> romantic.aggregator.v1.Record
+	14, // [14:20] is the sub-list for method output_type
+	8,  // [8:14] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
+}
+
+func init() { file_aggregator_proto_init() }
+func file_aggregator_proto_init() {
+	if File_aggregator_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_aggregator_proto_rawDesc), len(file_aggregator_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   16,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_aggregator_proto_goTypes,
+		DependencyIndexes: file_aggregator_proto_depIdxs,
+		MessageInfos:      file_aggregator_proto_msgTypes,
+	}.Build()
+	File_aggregator_proto = out.File
+	file_aggregator_proto_goTypes = nil
+	file_aggregator_proto_depIdxs = nil
+}
@@ -0,0 +1,304 @@
+package state
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/juju/errors"
+)
+
+// State contains the subscriptions and the aggregation interval
+// which must be restored when the aggregator restarts
+type State struct {
+	Subscriptions []Subscription `json:"subscriptions"`
+
+	// Interval between each message sent to Kafka (ex: 5m)
+	Interval string `json:"interval,omitempty"`
+}
+
+// Subscription of a currency pair on some exchanges
+type Subscription struct {
+	Base   string `json:"base"`
+	Target string `json:"target"`
+
+	// Names of the exchanges (ex: GDAX)
+	Exchanges []string `json:"exchanges"`
+
+	// Aggregation intervals of the currency pair (ex: 10s),
+	// the interval of the state if empty
+	Intervals []string `json:"intervals,omitempty"`
+}
+
+// Store keeps the desired state in a JSON file,
+// which is rewritten each time the state changes
+type Store struct {
+	mutex sync.Mutex
+	path  string
+	state State
+}
+
+// Opens the store saved in the file at `path`.
+// The store is empty if the file does not exist yet.
+func Open(path string) (*Store, error) {
+	store := &Store{
+		path:  path,
+		state: State{Subscriptions: []Subscription{}},
+	}
+
+	data, err := ioutil.ReadFile(path)
+
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+
+	if err != nil {
+		return nil, errors.Annotatef(err, "tried to read the state file %s", path)
+	}
+
+	if err := json.Unmarshal(data, &store.state); err != nil {
+		return nil, errors.NotValidf("state file %s: %v", path, err)
+	}
+
+	return store, nil
+}
+
+// Returns true if the state file exists
+func (s *Store) Exists() bool {
+	_, err := os.Stat(s.path)
+	return err == nil
+}
+
+// Returns a copy of the state
+func (s *Store) State() State {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	state := State{Subscriptions: []Subscription{}, Interval: s.state.Interval}
+
+	for _, sub := range s.state.Subscriptions {
+		copied := Subscription{
+			Base:      sub.Base,
+			Target:    sub.Target,
+			Exchanges: append([]string{}, sub.Exchanges...),
+		}
+
+		if len(sub.Intervals) > 0 {
+			copied.Intervals = append([]string{}, sub.Intervals...)
+		}
+
+		state.Subscriptions = append(state.Subscriptions, copied)
+	}
+
+	return state
+}
+
+// Adds the exchanges to the subscription of the currency pair and saves the state.
+// Nothing is saved without any exchange, since an empty list means every exchange.
+func (s *Store) Subscribe(base string, target string, exchanges []string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(exchanges) == 0 {
+		return nil
+	}
+
+	sub := s.find(base, target)
+
+	if sub == nil {
+		s.state.Subscriptions = append(s.state.Subscriptions, Subscription{Base: base, Target: target})
+		sub = &s.state.Subscriptions[len(s.state.Subscriptions)-1]
+	}
+
+	changed := false
+
+	for _, exchange := range exchanges {
+		if !contains(sub.Exchanges, exchange) {
+			sub.Exchanges = append(sub.Exchanges, exchange)
+			changed = true
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+
+	sort.Strings(sub.Exchanges)
+
+	return s.save()
+}
+
+// Removes the exchanges from the subscription of the currency pair and saves the state
+func (s *Store) Unsubscribe(base string, target string, exchanges []string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	sub := s.find(base, target)
+
+	if sub == nil {
+		return nil
+	}
+
+	remaining := []string{}
+
+	for _, exchange := range sub.Exchanges {
+		if !contains(exchanges, exchange) {
+			remaining = append(remaining, exchange)
+		}
+	}
+
+	sub.Exchanges = remaining
+
+	// Forgets the currency pair once no exchange is subscribed to it
+	if len(remaining) == 0 {
+		subscriptions := []Subscription{}
+
+		for _, other := range s.state.Subscriptions {
+			if other.Base != base || other.Target != target {
+				subscriptions = append(subscriptions, other)
+			}
+		}
+
+		s.state.Subscriptions = subscriptions
+	}
+
+	return s.save()
+}
+
+// Returns true if the currency pair is subscribed on at least one exchange
+func (s *Store) Has(base string, target string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.find(base, target) != nil
+}
+
+// Sets the aggregation intervals of a subscribed currency pair and saves the state.
+// The currency pair uses the interval of the state if `intervals` is empty.
+func (s *Store) SetIntervals(base string, target string, intervals []string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	sub := s.find(base, target)
+
+	if sub == nil {
+		return errors.NotFoundf("subscription to %s-%s", base, target)
+	}
+
+	sub.Intervals = nil
+
+	if len(intervals) > 0 {
+		sub.Intervals = append([]string{}, intervals...)
+	}
+
+	return s.save()
+}
+
+// Sets the aggregation interval and saves the state
+func (s *Store) SetInterval(interval string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.state.Interval = interval
+
+	return s.save()
+}
+
+// Returns the subscription of the currency pair, or nil.
+// The mutex must be held.
+func (s *Store) find(base string, target string) *Subscription {
+	for i := range s.state.Subscriptions {
+		if s.state.Subscriptions[i].Base == base && s.state.Subscriptions[i].Target == target {
+			return &s.state.Subscriptions[i]
+		}
+	}
+
+	return nil
+}
+
+// Writes the state in a temporary file, then renames it
+// so that the state file is never partially written.
+// The mutex must be held.
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(s.state, "", "  ")
+
+	if err != nil {
+		return errors.Annotate(err, "tried to encode the state")
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+
+	if err != nil {
+		return errors.Annotatef(err, "tried to save the state in %s", s.path)
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return errors.Annotatef(err, "tried to save the state in %s", s.path)
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return errors.Annotatef(err, "tried to save the state in %s", s.path)
+	}
+
+	return errors.Annotatef(os.Rename(tmp.Name(), s.path), "tried to save the state in %s", s.path)
+}
+
+// Parses the initial subscriptions declared in the configuration,
+// a comma-separated list of currency pairs (ex: "BTC-USD,ETH-EUR@GDAX").
+// A pair without "@exchange" is subscribed on every exchange listing it,
+// which is represented by an empty list of exchanges.
+func ParseSubscriptions(value string) ([]Subscription, error) {
+	subscriptions := []Subscription{}
+
+	for _, raw := range strings.Split(value, ",") {
+		raw = strings.TrimSpace(raw)
+
+		if raw == "" {
+			continue
+		}
+
+		pair, exchange := raw, ""
+
+		if i := strings.Index(raw, "@"); i >= 0 {
+			pair, exchange = raw[:i], raw[i+1:]
+		}
+
+		currencies := strings.Split(pair, "-")
+
+		if len(currencies) != 2 || currencies[0] == "" || currencies[1] == "" {
+			return nil, errors.NotValidf("subscription %q, expected BASE-TARGET[@exchange]", raw)
+		}
+
+		sub := Subscription{
+			Base:      strings.ToUpper(currencies[0]),
+			Target:    strings.ToUpper(currencies[1]),
+			Exchanges: []string{},
+		}
+
+		if exchange != "" {
+			sub.Exchanges = append(sub.Exchanges, exchange)
+		}
+
+		subscriptions = append(subscriptions, sub)
+	}
+
+	return subscriptions, nil
+}
+
+// Returns true if `values` contains `value`
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,81 @@
+package state
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/juju/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "state.json")
+
+	store, err := Open(path)
+	assert.Nil(t, err)
+	assert.False(t, store.Exists())
+	assert.Equal(t, State{Subscriptions: []Subscription{}}, store.State())
+
+	assert.Nil(t, store.Subscribe("BTC", "USD", []string{"GDAX"}))
+	assert.Nil(t, store.Subscribe("BTC", "USD", []string{"GDAX", "Bitfinex"}))
+	assert.Nil(t, store.Subscribe("ETH", "EUR", []string{"GDAX"}))
+	assert.Nil(t, store.Unsubscribe("ETH", "EUR", []string{"GDAX"}))
+
+	// An empty list of exchanges would mean every exchange once restored
+	assert.Nil(t, store.Subscribe("LTC", "EUR", []string{}))
+	assert.False(t, store.Has("LTC", "EUR"))
+	assert.Nil(t, store.Unsubscribe("LTC", "EUR", []string{"GDAX"}))
+	assert.Nil(t, store.SetInterval("5m"))
+	assert.Nil(t, store.SetIntervals("BTC", "USD", []string{"10s", "1m"}))
+	assert.True(t, errors.IsNotFound(store.SetIntervals("ETH", "EUR", []string{"10s"})))
+	assert.True(t, store.Exists())
+	assert.True(t, store.Has("BTC", "USD"))
+	assert.False(t, store.Has("ETH", "EUR"))
+
+	// The state is restored from the file
+	restored, err := Open(path)
+	assert.Nil(t, err)
+	assert.Equal(t, State{
+		Subscriptions: []Subscription{{Base: "BTC", Target: "USD", Exchanges: []string{"Bitfinex", "GDAX"}, Intervals: []string{"10s", "1m"}}},
+		Interval:      "5m",
+	}, restored.State())
+
+	assert.Nil(t, ioutil.WriteFile(path, []byte("{"), 0644))
+	_, err = Open(path)
+	assert.True(t, errors.IsNotValid(err))
+}
+
+func TestParseSubscriptions(t *testing.T) {
+	tables := []struct {
+		value  string
+		result []Subscription
+		err    string
+	}{
+		{"", []Subscription{}, ""},
+		{"btc-usd, ETH-EUR@GDAX", []Subscription{
+			{Base: "BTC", Target: "USD", Exchanges: []string{}},
+			{Base: "ETH", Target: "EUR", Exchanges: []string{"GDAX"}},
+		}, ""},
+		{"BTCUSD", nil, "notValid"},
+		{"BTC-@GDAX", nil, "notValid"},
+	}
+
+	for _, table := range tables {
+		result, err := ParseSubscriptions(table.value)
+
+		assert.Equal(t, table.result, result)
+
+		switch table.err {
+		case "notValid":
+			assert.True(t, errors.IsNotValid(err))
+		case "":
+			assert.Nil(t, err)
+		}
+	}
+}
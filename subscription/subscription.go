@@ -0,0 +1,56 @@
+package subscription
+
+// Action requested to an exchange
+type Action string
+
+// Status of a (un)subscription
+type Status string
+
+const (
+	Subscribe   Action = "subscribe"
+	Unsubscribe Action = "unsubscribe"
+
+	// Waiting for the exchange acknowledgement
+	Pending Status = "pending"
+	// Acknowledged by the exchange
+	Confirmed Status = "confirmed"
+	// Rejected by the exchange, or not acknowledged in time
+	Rejected Status = "rejected"
+)
+
+// Ack is sent by an exchange when it confirms or rejects a (un)subscription.
+// Empty fields match any pending (un)subscription, which is useful
+// when the exchange does not tell which one it rejects.
+type Ack struct {
+	// Name of the exchange (ex: GDAX)
+	Exchange string `json:"exchange"`
+
+	// Action acknowledged by the exchange
+	Action Action `json:"action"`
+
+	// Symbol in the exchange format (ex: GDAX = "BTC-USD", Bitfinex = "BTCUSD")
+	Symbol string `json:"symbol"`
+
+	// Name of the channel (ex: ticker)
+	Channel string `json:"channel"`
+
+	// Reason of the rejection, empty when it is confirmed
+	Error string `json:"error,omitempty"`
+}
+
+// Returns the action matching the boolean used by the exchanges
+func ActionOf(isSubscribe bool) Action {
+	if isSubscribe {
+		return Subscribe
+	}
+
+	return Unsubscribe
+}
+
+// Sends an ack without blocking the exchange if nobody is listening
+func Send(acks chan Ack, ack Ack) {
+	select {
+	case acks <- ack:
+	default:
+	}
+}
@@ -0,0 +1,98 @@
+package transport
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGet(t *testing.T) {
+	os.Setenv("TESTEXCHANGE_PROXY", "socks5://127.0.0.1:1080")
+	os.Setenv("TESTEXCHANGE_TIMEOUT", "30s")
+	os.Setenv("BADTIMEOUT_TIMEOUT", "thirty")
+	defer os.Unsetenv("TESTEXCHANGE_PROXY")
+	defer os.Unsetenv("TESTEXCHANGE_TIMEOUT")
+	defer os.Unsetenv("BADTIMEOUT_TIMEOUT")
+
+	o, err := Get("TestExchange")
+	assert.Nil(t, err)
+	assert.Equal(t, &Options{Proxy: "socks5://127.0.0.1:1080", Timeout: 30 * time.Second}, o)
+
+	_, err = Get("BadTimeout")
+	assert.True(t, errors.IsNotValid(err))
+
+	Set("TestExchange", &Options{Proxy: "http://127.0.0.1:3128"})
+	defer Set("TestExchange", nil)
+	o, err = Get("TestExchange")
+	assert.Nil(t, err)
+	assert.Equal(t, "http://127.0.0.1:3128", o.Proxy)
+
+	// Forgotten settings are read from the environment again
+	Set("TestExchange", nil)
+	o, err = Get("TestExchange")
+	assert.Nil(t, err)
+	assert.Equal(t, "socks5://127.0.0.1:1080", o.Proxy)
+}
+
+func TestNewDialer(t *testing.T) {
+	tables := []struct {
+		options *Options
+		socks   bool
+		err     string
+	}{
+		{&Options{}, false, ""},
+		{&Options{Proxy: "http://127.0.0.1:3128"}, false, ""},
+		{&Options{Proxy: "socks5://127.0.0.1:1080"}, true, ""},
+		{&Options{Proxy: "ftp://127.0.0.1"}, false, "notSupported"},
+		{&Options{RootCAs: "/does/not/exist.pem"}, false, "notFound"},
+	}
+
+	for _, table := range tables {
+		Set("Dialer", table.options)
+		dialer, err := NewDialer("Dialer")
+
+		switch table.err {
+		case "notSupported":
+			assert.True(t, errors.IsNotSupported(err))
+			continue
+		case "notFound":
+			assert.NotNil(t, err)
+			continue
+		}
+
+		assert.Nil(t, err)
+		assert.Equal(t, defaultTimeout, dialer.HandshakeTimeout)
+		assert.Equal(t, table.socks, dialer.NetDial != nil)
+		assert.Equal(t, table.socks, dialer.Proxy == nil)
+	}
+}
+
+func TestNewHTTPClient(t *testing.T) {
+	file, err := ioutil.TempFile("", "roots")
+	assert.Nil(t, err)
+	defer os.Remove(file.Name())
+	file.WriteString("not a certificate")
+	file.Close()
+
+	Set("Client", &Options{RootCAs: file.Name()})
+	_, err = NewHTTPClient("Client")
+	assert.True(t, errors.IsNotValid(err))
+
+	Set("Client", &Options{Proxy: "socks5://127.0.0.1:1080", Timeout: time.Second})
+	client, err := NewHTTPClient("Client")
+	assert.Nil(t, err)
+	assert.Equal(t, time.Second, client.Timeout)
+	assert.True(t, client.Transport.(*http.Transport).ForceAttemptHTTP2)
+	assert.NotNil(t, client.Transport.(*http.Transport).DialContext)
+	assert.Equal(t, http.DefaultTransport.(*http.Transport).MaxIdleConns, client.Transport.(*http.Transport).MaxIdleConns)
+
+	request, _ := http.NewRequest("GET", "https://api.pro.coinbase.com", nil)
+	proxyURL, err := client.Transport.(*http.Transport).Proxy(request)
+	assert.Nil(t, err)
+	assert.Equal(t, "socks5://127.0.0.1:1080", proxyURL.String())
+}
@@ -0,0 +1,130 @@
+package websocket
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+// Limits applied to the messages sent to a websocket
+type Limits struct {
+	// Number of messages which can be sent per second.
+	// A rate of 0 disables the limitation.
+	Rate float64 `json:"rate"`
+
+	// Number of messages which can be sent at once
+	Burst int `json:"burst"`
+
+	// Number of messages waiting to be sent.
+	// Once the queue is full, new messages are rejected.
+	QueueSize int `json:"queue_size"`
+}
+
+// Token bucket which determines when a message can be sent
+type Limiter struct {
+	mutex  sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+const (
+	defaultQueueSize = 64
+)
+
+var (
+	// Returned when a message is sent while the outbound queue is full
+	ErrQueueFull = errors.New("outbound queue is full")
+)
+
+// Returns the limits of an exchange.
+// Each value of `defaults` can be overridden by the environment:
+//  - <LABEL>_RATE_LIMIT (messages per second)
+//  - <LABEL>_BURST
+//  - <LABEL>_QUEUE_SIZE
+func LimitsFromEnv(label string, defaults Limits) (Limits, error) {
+	limits := defaults
+	prefix := strings.ToUpper(label) + "_"
+
+	if rate := os.Getenv(prefix + "RATE_LIMIT"); rate != "" {
+		value, err := strconv.ParseFloat(rate, 64)
+
+		if err != nil || value < 0 {
+			return limits, errors.NotValidf("%sRATE_LIMIT %q", prefix, rate)
+		}
+
+		limits.Rate = value
+	}
+
+	if burst := os.Getenv(prefix + "BURST"); burst != "" {
+		value, err := strconv.Atoi(burst)
+
+		if err != nil || value < 1 {
+			return limits, errors.NotValidf("%sBURST %q", prefix, burst)
+		}
+
+		limits.Burst = value
+	}
+
+	if size := os.Getenv(prefix + "QUEUE_SIZE"); size != "" {
+		value, err := strconv.Atoi(size)
+
+		if err != nil || value < 1 {
+			return limits, errors.NotValidf("%sQUEUE_SIZE %q", prefix, size)
+		}
+
+		limits.QueueSize = value
+	}
+
+	if limits.QueueSize <= 0 {
+		limits.QueueSize = defaultQueueSize
+	}
+
+	if limits.Burst <= 0 {
+		limits.Burst = 1
+	}
+
+	return limits, nil
+}
+
+// Initializes a full token bucket
+func NewLimiter(rate float64, burst int) *Limiter {
+	return &Limiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Takes a token and returns how long to wait before using it
+func (l *Limiter) Reserve() time.Duration {
+	if l.rate <= 0 {
+		return 0
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+
+	// Refills the bucket with the tokens earned since the last reservation
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	l.tokens--
+
+	if l.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
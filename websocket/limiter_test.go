@@ -0,0 +1,80 @@
+package websocket
+
+import (
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimitsFromEnv(t *testing.T) {
+	os.Setenv("LIMITED_RATE_LIMIT", "2.5")
+	os.Setenv("LIMITED_QUEUE_SIZE", "3")
+	os.Setenv("INVALID_BURST", "-1")
+	defer os.Unsetenv("LIMITED_RATE_LIMIT")
+	defer os.Unsetenv("LIMITED_QUEUE_SIZE")
+	defer os.Unsetenv("INVALID_BURST")
+
+	tables := []struct {
+		label    string
+		defaults Limits
+		result   Limits
+		err      string
+	}{
+		{"Default", Limits{}, Limits{Rate: 0, Burst: 1, QueueSize: defaultQueueSize}, ""},
+		{"Default", Limits{Rate: 5, Burst: 10, QueueSize: 256}, Limits{Rate: 5, Burst: 10, QueueSize: 256}, ""},
+		{"Limited", Limits{Rate: 5, Burst: 10, QueueSize: 256}, Limits{Rate: 2.5, Burst: 10, QueueSize: 3}, ""},
+		{"Invalid", Limits{}, Limits{}, "notValid"},
+	}
+
+	for _, table := range tables {
+		result, err := LimitsFromEnv(table.label, table.defaults)
+
+		switch table.err {
+		case "notValid":
+			assert.True(t, errors.IsNotValid(err))
+		case "":
+			assert.Nil(t, err)
+			assert.Equal(t, table.result, result)
+		}
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	// Unlimited
+	limiter := NewLimiter(0, 1)
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, time.Duration(0), limiter.Reserve())
+	}
+
+	// The burst is available at once, then one message every 100ms
+	limiter = NewLimiter(10, 3)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, time.Duration(0), limiter.Reserve())
+	}
+
+	delay := limiter.Reserve()
+	assert.InDelta(t, 100*time.Millisecond, delay, float64(10*time.Millisecond))
+
+	delay = limiter.Reserve()
+	assert.InDelta(t, 200*time.Millisecond, delay, float64(10*time.Millisecond))
+}
+
+func TestSend(t *testing.T) {
+	SetReplay(&ReplayOptions{Path: "/does/not/exist"})
+	defer SetReplay(nil)
+
+	p := &Proxy{Label: "Queue", Limits: Limits{QueueSize: 2}}
+	assert.Nil(t, p.Initialize(url.URL{Scheme: "wss", Host: "example.com"}))
+
+	assert.Nil(t, p.Send([]byte("first")))
+	assert.Nil(t, p.Send([]byte("second")))
+	assert.Equal(t, 2, p.QueueDepth())
+
+	err := p.Send([]byte("third"))
+	assert.Equal(t, ErrQueueFull, errors.Cause(err))
+	assert.Equal(t, 2, p.Status().QueueDepth)
+}
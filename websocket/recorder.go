@@ -0,0 +1,259 @@
+package websocket
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+// Direction of a frame, seen from the aggregator
+type Direction string
+
+const (
+	// Frame received from the exchange
+	Inbound Direction = "in"
+	// Frame sent to the exchange
+	Outbound Direction = "out"
+)
+
+// Frame is one websocket message, as stored in a recording
+type Frame struct {
+	// Date and time at which the frame went through the proxy
+	Time time.Time `json:"time"`
+
+	// Label of the proxy (ex: GDAX)
+	Label string `json:"label"`
+
+	// Direction of the frame (in or out)
+	Direction Direction `json:"direction"`
+
+	// Raw content of the frame
+	Data string `json:"data"`
+}
+
+// Recorder appends every frame it receives to a gzip compressed file.
+// Each frame is written as a JSON line.
+type Recorder struct {
+	mutex   sync.Mutex
+	file    *os.File
+	writer  *gzip.Writer
+	encoder *json.Encoder
+	closed  bool
+}
+
+// Player reads back the frames written by a Recorder
+type Player struct {
+	file    *os.File
+	reader  *gzip.Reader
+	decoder *json.Decoder
+}
+
+// Opens (or creates) the recording file located at `path`.
+// A new gzip member is appended at the end of the file,
+// so several sessions can be recorded in the same file.
+// The member of a session which has not been closed (ex: after a crash)
+// is incomplete: it is replaced by a complete one with the frames it contains,
+// so that the sessions recorded after it can be read back.
+func NewRecorder(path string) (*Recorder, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+
+	if err != nil {
+		return nil, errors.Annotatef(err, "tried to open the recording file %s", path)
+	}
+
+	size, frames, err := readMembers(file)
+
+	if err == nil {
+		err = file.Truncate(size)
+	}
+
+	if err == nil {
+		_, err = file.Seek(size, io.SeekStart)
+	}
+
+	if err != nil {
+		file.Close()
+		return nil, errors.Annotatef(err, "tried to repair the recording file %s", path)
+	}
+
+	writer := gzip.NewWriter(file)
+	recorder := &Recorder{
+		file:    file,
+		writer:  writer,
+		encoder: json.NewEncoder(writer),
+	}
+
+	if len(frames) > 0 {
+		if err := recorder.write(frames...); err != nil {
+			recorder.Close()
+			return nil, errors.Annotatef(err, "tried to repair the recording file %s", path)
+		}
+	}
+
+	return recorder, nil
+}
+
+// Reads the gzip members of a recording. Returns the size of the complete
+// members, and the frames of the incomplete member which follows them, if any.
+func readMembers(file *os.File) (int64, []*Frame, error) {
+	counter := &countingReader{reader: bufio.NewReader(file)}
+	size := int64(0)
+	var reader *gzip.Reader
+
+	for {
+		var err error
+
+		if reader == nil {
+			reader, err = gzip.NewReader(counter)
+		} else {
+			err = reader.Reset(counter)
+		}
+
+		// Either every member has been read,
+		// or the header of the last one has not been entirely written
+		if err != nil {
+			return size, nil, nil
+		}
+
+		reader.Multistream(false)
+		decoder := json.NewDecoder(reader)
+		frames := []*Frame{}
+
+		for {
+			frame := &Frame{}
+
+			if err := decoder.Decode(frame); err == io.EOF {
+				break
+			} else if err != nil {
+				// The frames flushed before the end of the session are kept
+				return size, frames, nil
+			}
+
+			frames = append(frames, frame)
+		}
+
+		size = counter.count
+	}
+}
+
+// Counts the bytes read from a file. As it reads one byte at a time
+// when it is asked to, the gzip reader does not read beyond the end of a member.
+type countingReader struct {
+	reader *bufio.Reader
+	count  int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.count += int64(n)
+
+	return n, err
+}
+
+func (c *countingReader) ReadByte() (byte, error) {
+	b, err := c.reader.ReadByte()
+
+	if err == nil {
+		c.count++
+	}
+
+	return b, err
+}
+
+// Appends a new frame to the recording.
+// The compressed stream is flushed after each frame
+// so a crash does not lose what has already been received.
+func (r *Recorder) Record(label string, direction Direction, data []byte) error {
+	frame := &Frame{
+		Time:      time.Now(),
+		Label:     label,
+		Direction: direction,
+		Data:      string(data),
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// Frames received after closing are ignored
+	if r.closed {
+		return nil
+	}
+
+	return r.write(frame)
+}
+
+// Writes frames and flushes the compressed stream.
+// The mutex must be locked once the recorder is shared.
+func (r *Recorder) write(frames ...*Frame) error {
+	for _, frame := range frames {
+		if err := r.encoder.Encode(frame); err != nil {
+			return errors.Annotatef(err, "tried to record frame %v", frame)
+		}
+	}
+
+	return r.writer.Flush()
+}
+
+// Closes the compressed stream and the file
+func (r *Recorder) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.closed = true
+
+	if err := r.writer.Close(); err != nil {
+		return err
+	}
+
+	return r.file.Close()
+}
+
+// Opens the recording located at `path`
+func OpenRecording(path string) (*Player, error) {
+	file, err := os.Open(path)
+
+	if err != nil {
+		return nil, errors.Annotatef(err, "tried to open the recording file %s", path)
+	}
+
+	reader, err := gzip.NewReader(file)
+
+	if err != nil {
+		file.Close()
+		return nil, errors.Annotatef(err, "tried to read the recording file %s", path)
+	}
+
+	return &Player{
+		file:    file,
+		reader:  reader,
+		decoder: json.NewDecoder(reader),
+	}, nil
+}
+
+// Returns the next frame of the recording.
+// Returns io.EOF when every frame has been read.
+func (p *Player) Next() (*Frame, error) {
+	frame := &Frame{}
+
+	if err := p.decoder.Decode(frame); err != nil {
+		if err == io.EOF {
+			return nil, err
+		}
+
+		return nil, errors.Annotate(err, "tried to read a frame from the recording")
+	}
+
+	return frame, nil
+}
+
+// Closes the recording file
+func (p *Player) Close() error {
+	p.reader.Close()
+	return p.file.Close()
+}
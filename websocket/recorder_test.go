@@ -0,0 +1,173 @@
+package websocket
+
+import (
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecorder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "recorder")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "frames.gz")
+
+	// Two sessions are recorded in the same file
+	sessions := [][]struct {
+		label     string
+		direction Direction
+		data      string
+	}{
+		{
+			{"GDAX", Outbound, `{"type":"subscribe"}`},
+			{"GDAX", Inbound, `{"type":"subscriptions"}`},
+		},
+		{
+			{"Bitfinex", Inbound, `[1,"hb"]`},
+		},
+	}
+
+	for _, session := range sessions {
+		recorder, err := NewRecorder(path)
+		assert.Nil(t, err)
+
+		for _, frame := range session {
+			assert.Nil(t, recorder.Record(frame.label, frame.direction, []byte(frame.data)))
+		}
+
+		assert.Nil(t, recorder.Close())
+		// Frames recorded after closing are ignored
+		assert.Nil(t, recorder.Record("GDAX", Inbound, []byte("ignored")))
+	}
+
+	player, err := OpenRecording(path)
+	assert.Nil(t, err)
+	defer player.Close()
+
+	for _, session := range sessions {
+		for _, expected := range session {
+			frame, err := player.Next()
+			assert.Nil(t, err)
+			assert.Equal(t, expected.label, frame.Label)
+			assert.Equal(t, expected.direction, frame.Direction)
+			assert.Equal(t, expected.data, frame.Data)
+		}
+	}
+
+	_, err = player.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestRecorderCrash(t *testing.T) {
+	dir, err := ioutil.TempDir("", "recorder")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "frames.gz")
+	record := func(data ...string) *Recorder {
+		recorder, err := NewRecorder(path)
+		assert.Nil(t, err)
+
+		for _, d := range data {
+			assert.Nil(t, recorder.Record("GDAX", Inbound, []byte(d)))
+		}
+
+		return recorder
+	}
+
+	assert.Nil(t, record("1", "2").Close())
+
+	// The session stops without closing its gzip member
+	assert.Nil(t, record("3", "4").file.Close())
+
+	// Then a session stops while the header of its member is written
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	assert.Nil(t, err)
+	_, err = file.Write([]byte{0x1f, 0x8b})
+	assert.Nil(t, err)
+	assert.Nil(t, file.Close())
+
+	assert.Nil(t, record("5").Close())
+
+	player, err := OpenRecording(path)
+	assert.Nil(t, err)
+	defer player.Close()
+
+	for _, expected := range []string{"1", "2", "3", "4", "5"} {
+		frame, err := player.Next()
+
+		if !assert.Nil(t, err, expected) {
+			return
+		}
+
+		assert.Equal(t, expected, frame.Data)
+	}
+
+	_, err = player.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replay")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "frames.gz")
+	recorder, err := NewRecorder(path)
+	assert.Nil(t, err)
+	recorder.Record("GDAX", Inbound, []byte("first"))
+	recorder.Record("Bitfinex", Inbound, []byte("other exchange"))
+	recorder.Record("GDAX", Outbound, []byte("outbound"))
+	recorder.Record("GDAX", Inbound, []byte("second"))
+	assert.Nil(t, recorder.Close())
+
+	SetReplay(&ReplayOptions{Path: path, Speed: 0})
+	defer SetReplay(nil)
+
+	p := &Proxy{Label: "GDAX"}
+	assert.Nil(t, p.Initialize(url.URL{Scheme: "wss", Host: "example.com"}))
+	assert.Nil(t, p.IsClean())
+
+	go p.Start()
+
+	for _, expected := range []string{"first", "second"} {
+		select {
+		case response := <-p.ResponseChannel:
+			assert.Equal(t, expected, string(response))
+		case <-time.After(time.Second):
+			t.Fatalf("frame %q not replayed", expected)
+		}
+	}
+
+	p.Interrupt()
+}
+
+func TestReplayClock(t *testing.T) {
+	start := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	clock := &replayClock{}
+
+	tables := []struct {
+		frame    time.Time
+		expected time.Duration
+	}{
+		{start, 0},
+		{start.Add(time.Second), time.Second},
+		{start.Add(3 * time.Second), 3 * time.Second},
+		// Next session, appended the day after
+		{start.Add(24 * time.Hour), 3*time.Second + MaxReplayGap},
+		{start.Add(24*time.Hour + time.Second), 4*time.Second + MaxReplayGap},
+		// Clock moved back
+		{start, 4*time.Second + MaxReplayGap},
+	}
+
+	for _, table := range tables {
+		assert.Equal(t, table.expected, clock.advance(table.frame), "%s", table.frame)
+	}
+}
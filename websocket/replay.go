@@ -0,0 +1,176 @@
+package websocket
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Options of the replay mode
+type ReplayOptions struct {
+	// Path of the recording to replay
+	Path string
+
+	// Speed factor applied to the recorded delays between frames
+	// (ex: 1 = recorded speed, 10 = ten times faster).
+	// A speed of 0 replays the frames as fast as they can be processed.
+	Speed float64
+}
+
+const (
+	// Longest delay replayed between two consecutive frames. The recordings of
+	// several sessions are appended to the same file, and the gap between them
+	// is shortened to this delay.
+	MaxReplayGap time.Duration = 5 * time.Second
+)
+
+var (
+	// Recorder shared by every proxy, nil when the recording is disabled
+	recorder *Recorder
+
+	// Replay options shared by every proxy, nil when the replay mode is disabled
+	replay *ReplayOptions
+
+	// Protects the replay options, read by the drivers while they run
+	replayMutex sync.RWMutex
+)
+
+// Sets the recorder used by every proxy.
+// A nil recorder disables the recording.
+func SetRecorder(r *Recorder) {
+	recorder = r
+}
+
+// Enables the replay mode: proxies do not open any connection
+// and the inbound frames are read from the recording instead.
+// A nil value disables the replay mode.
+func SetReplay(options *ReplayOptions) {
+	replayMutex.Lock()
+	defer replayMutex.Unlock()
+
+	replay = options
+}
+
+// Returns true if the replay mode is enabled.
+// Nothing must then be fetched from the network.
+func Replaying() bool {
+	return replayOptions() != nil
+}
+
+// Returns the replay options, nil when the replay mode is disabled
+func replayOptions() *ReplayOptions {
+	replayMutex.RLock()
+	defer replayMutex.RUnlock()
+
+	return replay
+}
+
+// Records a frame if a recorder has been set
+func (p *Proxy) record(direction Direction, data []byte) {
+	if recorder == nil {
+		return
+	}
+
+	if err := recorder.Record(p.Label, direction, data); err != nil {
+		p.log.WithFields(logrus.Fields{"error": err}).Errorf("Error occured while recording a frame")
+	}
+}
+
+// Recorded time elapsed since the first frame of a recording,
+// where each gap between consecutive frames is capped to MaxReplayGap
+type replayClock struct {
+	last    time.Time
+	elapsed time.Duration
+}
+
+// Returns the recorded time elapsed until the frame recorded at `t`
+func (c *replayClock) advance(t time.Time) time.Duration {
+	if !c.last.IsZero() {
+		gap := t.Sub(c.last)
+
+		switch {
+		case gap > MaxReplayGap:
+			gap = MaxReplayGap
+		case gap < 0:
+			// The clock went back between two sessions
+			gap = 0
+		}
+
+		c.elapsed += gap
+	}
+
+	c.last = t
+
+	return c.elapsed
+}
+
+// Reads the recording and sends each inbound frame of the proxy
+// to the response channel, respecting the recorded delays divided by the speed.
+// Delays are computed from the first frame of the recording (whatever its label),
+// so several proxies replaying the same file stay synchronized.
+// The gaps longer than MaxReplayGap, such as the ones between two sessions, are shortened.
+func (p *Proxy) ReplayRecording() {
+	options := replayOptions()
+
+	if options == nil {
+		return
+	}
+
+	p.log.WithFields(logrus.Fields{"path": options.Path, "speed": options.Speed}).Infof("Replaying recording")
+
+	player, err := OpenRecording(options.Path)
+
+	if err != nil {
+		p.log.WithFields(logrus.Fields{"error": err}).Errorf("Cannot replay the recording")
+		return
+	}
+
+	defer player.Close()
+
+	clock := &replayClock{}
+	start := time.Now()
+	count := 0
+
+	for {
+		frame, err := player.Next()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			p.log.WithFields(logrus.Fields{"error": err}).Errorf("Replay stopped")
+			return
+		}
+
+		elapsed := clock.advance(frame.Time)
+
+		if frame.Label != p.Label || frame.Direction != Inbound {
+			continue
+		}
+
+		if options.Speed > 0 {
+			delay := time.Duration(float64(elapsed)/options.Speed) - time.Since(start)
+
+			if delay > 0 {
+				select {
+				case <-time.After(delay):
+				case <-p.done:
+					return
+				}
+			}
+		}
+
+		select {
+		case p.ResponseChannel <- []byte(frame.Data):
+			p.received()
+			count++
+		case <-p.done:
+			return
+		}
+	}
+
+	p.log.WithField("frames", count).Infof("Replay finished")
+}
@@ -0,0 +1,85 @@
+package websocket
+
+import (
+	"net/url"
+	"sync"
+)
+
+// Settings of the websocket of an exchange which replace the defaults of its driver.
+// The zero values keep the defaults.
+type Settings struct {
+	// URL of the websocket
+	Endpoint *url.URL
+
+	// Number of messages which can be sent per second, 0 disables the limitation
+	Rate *float64
+
+	// Number of messages which can be sent at once
+	Burst int
+
+	// Number of messages waiting to be sent
+	QueueSize int
+}
+
+var (
+	settingsMutex sync.RWMutex
+	settings      = map[string]Settings{}
+)
+
+// Sets the websocket settings of an exchange.
+// Once they are set, the <LABEL>_RATE_LIMIT, <LABEL>_BURST
+// and <LABEL>_QUEUE_SIZE variables are ignored.
+func Configure(label string, s Settings) {
+	settingsMutex.Lock()
+	defer settingsMutex.Unlock()
+
+	settings[label] = s
+}
+
+// Returns the websocket settings of an exchange, false if they have not been set
+func configured(label string) (Settings, bool) {
+	settingsMutex.RLock()
+	defer settingsMutex.RUnlock()
+
+	s, ok := settings[label]
+	return s, ok
+}
+
+// Returns the endpoint and the limits of the proxy,
+// the settings of the exchange replacing the defaults of its driver
+func (p *Proxy) settings(uri url.URL) (url.URL, Limits, error) {
+	s, ok := configured(p.Label)
+
+	if !ok {
+		limits, err := LimitsFromEnv(p.Label, p.Limits)
+		return uri, limits, err
+	}
+
+	limits := p.Limits
+
+	if s.Endpoint != nil {
+		uri = *s.Endpoint
+	}
+
+	if s.Rate != nil {
+		limits.Rate = *s.Rate
+	}
+
+	if s.Burst > 0 {
+		limits.Burst = s.Burst
+	}
+
+	if s.QueueSize > 0 {
+		limits.QueueSize = s.QueueSize
+	}
+
+	if limits.QueueSize <= 0 {
+		limits.QueueSize = defaultQueueSize
+	}
+
+	if limits.Burst <= 0 {
+		limits.Burst = 1
+	}
+
+	return uri, limits, nil
+}
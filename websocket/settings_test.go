@@ -0,0 +1,37 @@
+package websocket
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSettings(t *testing.T) {
+	defaults := Limits{Rate: 8, Burst: 20, QueueSize: 64}
+	uri := url.URL{Scheme: "wss", Host: "ws-feed.pro.coinbase.com", Path: "/"}
+	sandbox := &url.URL{Scheme: "wss", Host: "ws-feed-public.sandbox.pro.coinbase.com", Path: "/"}
+	unlimited := 0.0
+
+	Configure("Sandbox", Settings{Endpoint: sandbox, Rate: &unlimited, QueueSize: 128})
+	Configure("Empty", Settings{})
+
+	tables := []struct {
+		label    string
+		endpoint string
+		limits   Limits
+	}{
+		{"NotConfigured", uri.String(), defaults},
+		{"Empty", uri.String(), defaults},
+		{"Sandbox", sandbox.String(), Limits{Rate: 0, Burst: 20, QueueSize: 128}},
+	}
+
+	for _, table := range tables {
+		p := &Proxy{Label: table.label, Limits: defaults}
+		endpoint, limits, err := p.settings(uri)
+
+		assert.Nil(t, err)
+		assert.Equal(t, table.endpoint, endpoint.String(), table.label)
+		assert.Equal(t, table.limits, limits, table.label)
+	}
+}
@@ -3,18 +3,54 @@ package websocket
 import (
 	"net/url"
 	"reflect"
+	"sync"
+	"time"
 
+	"github.com/fberrez/romantic-aggregator/metrics"
+	"github.com/fberrez/romantic-aggregator/transport"
 	"github.com/gorilla/websocket"
 	"github.com/juju/errors"
 	"github.com/sirupsen/logrus"
 )
 
+const (
+	// Duration given to the host to answer the Close frame
+	// before the connection is closed anyway
+	CloseTimeout time.Duration = time.Second
+)
+
+// Current state of a proxy
+type Status struct {
+	// Label of the proxy (ex: GDAX)
+	Label string `json:"label"`
+
+	// True when the inbound frames are read from a recording
+	Replaying bool `json:"replaying"`
+
+	// Number of messages waiting to be sent
+	QueueDepth int `json:"queue_depth"`
+
+	// Limits applied to the messages sent to the websocket
+	Limits Limits `json:"limits"`
+
+	// True while the connection to the websocket is open
+	// (or while the recording is replayed)
+	Connected bool `json:"connected"`
+
+	// Date and time of the last frame received
+	LastMessage *time.Time `json:"last_message,omitempty"`
+
+	// Number of times the connection has been reopened
+	Reconnects int `json:"reconnects"`
+}
+
 type Proxy struct {
 	Label  string
 	WssUrl url.URL         `json:"wss_url"`
 	Conn   *websocket.Conn `json:"conn"`
 
-	// Channel which receive messages (sent by exchange) to the websocket
+	// Channel which receive messages (sent by exchange) to the websocket.
+	// It is the outbound queue, its capacity is Limits.QueueSize.
 	MessageChannel chan []byte `json:"message_channel"`
 
 	// Channel which send responses (sent by the websocket) to the exchange
@@ -27,6 +63,34 @@ type Proxy struct {
 	// (usefull when the websocket has been closed by the host)
 	Subscriptions [][]byte `json:"subscriptions"`
 
+	// True when the inbound frames are read from a recording
+	// instead of the websocket (see SetReplay)
+	Replaying bool `json:"replaying"`
+
+	// Closed when the proxy stops
+	done chan struct{}
+
+	// Closed when ListenWebsocket returns
+	listened chan struct{}
+
+	// Limits applied to the messages sent to the websocket,
+	// set by the exchange before initializing the proxy
+	Limits Limits `json:"limits"`
+
+	// Dialer which honours the connection settings of the exchange
+	// (see transport.Get)
+	dialer *websocket.Dialer
+
+	// Determines when the next message can be sent
+	limiter *Limiter
+
+	// Protects the connection state below,
+	// which is read by the health checks
+	mutex       sync.Mutex
+	connected   bool
+	lastMessage time.Time
+	reconnects  int
+
 	log *logrus.Entry
 }
 
@@ -36,18 +100,44 @@ func (p *Proxy) Initialize(uri url.URL) error {
 
 	p.log.Infof("Initializing proxy")
 
-	c, _, err := websocket.DefaultDialer.Dial(uri.String(), nil)
+	uri, limits, err := p.settings(uri)
 
 	if err != nil {
 		return err
 	}
 
+	p.Limits = limits
+	p.limiter = NewLimiter(limits.Rate, limits.Burst)
 	p.WssUrl = uri
-	p.Conn = c
-	p.MessageChannel = make(chan []byte)
+	p.MessageChannel = make(chan []byte, limits.QueueSize)
 	p.ResponseChannel = make(chan []byte)
-	p.InterruptChannel = make(chan bool)
+	p.InterruptChannel = make(chan bool, 1)
 	p.Subscriptions = [][]byte{}
+	p.done = make(chan struct{})
+	p.listened = make(chan struct{})
+
+	// In replay mode, no connection is opened
+	if Replaying() {
+		p.Replaying = true
+		p.setConnected(true)
+		return nil
+	}
+
+	dialer, err := transport.NewDialer(p.Label)
+
+	if err != nil {
+		return err
+	}
+
+	c, _, err := dialer.Dial(uri.String(), nil)
+
+	if err != nil {
+		return err
+	}
+
+	p.dialer = dialer
+	p.Conn = c
+	p.setConnected(true)
 
 	return nil
 }
@@ -57,14 +147,20 @@ func (p *Proxy) Initialize(uri url.URL) error {
 //  - Receiving message in the MessageChannel
 //  - Receiving a SIGINT
 //  - Done chan closed by the listening go routine
+// Messages are sent at the rate determined by the proxy limits.
 func (p *Proxy) Start() {
-	p.log.WithFields(logrus.Fields{"url": p.WssUrl.String()}).Infof("Connecting...")
-
-	// Listen and process every datas
-	// received by the connection to the websocket.
-	// If the connection is closed by the websocket,
-	// the go routine is restarted with a new connection
-	go p.ListenWebsocket()
+	if p.Replaying {
+		// Inbound frames are read from the recording
+		go p.ReplayRecording()
+	} else {
+		p.log.WithFields(logrus.Fields{"url": p.WssUrl.String()}).Infof("Connecting...")
+
+		// Listen and process every datas
+		// received by the connection to the websocket.
+		// If the connection is closed by the websocket,
+		// the go routine is restarted with a new connection
+		go p.ListenWebsocket()
+	}
 
 	for {
 		select {
@@ -72,8 +168,31 @@ func (p *Proxy) Start() {
 		// If a new message arrives in the MessageChannel,
 		// it is sent to the websocket
 		case msg := <-p.MessageChannel:
+			metrics.OutboundQueueDepth.WithLabelValues(p.Label).Set(float64(p.QueueDepth()))
+
+			// Waits until the limiter allows to send the message
+			if delay := p.limiter.Reserve(); delay > 0 {
+				p.log.WithFields(logrus.Fields{"delay": delay, "queue_depth": p.QueueDepth()}).Debugf("Rate limit reached, waiting")
+
+				select {
+				case <-time.After(delay):
+				case interrupt := <-p.InterruptChannel:
+					if interrupt {
+						p.stop()
+						return
+					}
+				}
+			}
+
+			p.record(Outbound, msg)
+
+			if p.Replaying {
+				p.log.WithFields(logrus.Fields{"message": string(msg)}).Debugf("Replay mode: message not sent")
+				continue
+			}
+
 			p.log.WithFields(logrus.Fields{"message": string(msg)}).Debugf("Sending message to Websocket")
-			if err := p.Conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+			if err := p.conn().WriteMessage(websocket.TextMessage, msg); err != nil {
 				p.log.WithFields(logrus.Fields{"error": err}).Errorf("Error occured while sending message to Websocket")
 				continue
 			}
@@ -81,54 +200,205 @@ func (p *Proxy) Start() {
 			// If a SIGINT is received, it closes the connection
 		case interrupt := <-p.InterruptChannel:
 			if interrupt {
-				p.log.Debugf("Closing Websocket")
-				err := p.Conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
-				if err != nil {
-					p.log.WithFields(logrus.Fields{"error": err}).Errorf("Error occured while closing the Websocket")
-					continue
-				}
+				p.stop()
 				return
 			}
 		}
 	}
 }
 
+// Closes the connection to the websocket.
+// The host has CloseTimeout to answer the Close frame,
+// then the connection is closed anyway.
+func (p *Proxy) stop() {
+	close(p.done)
+	p.setConnected(false)
+
+	if p.Replaying {
+		return
+	}
+
+	p.log.Debugf("Closing Websocket")
+	conn := p.conn()
+	err := conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+
+	if err != nil {
+		p.log.WithFields(logrus.Fields{"error": err}).Errorf("Error occured while closing the Websocket")
+	} else {
+		// The answer of the host stops ListenWebsocket
+		select {
+		case <-p.listened:
+		case <-time.After(CloseTimeout):
+			p.log.Warnf("The host has not answered the Close frame")
+		}
+	}
+
+	if err := conn.Close(); err != nil {
+		p.log.WithFields(logrus.Fields{"error": err}).Errorf("Error occured while closing the connection")
+	}
+}
+
+// Adds a message to the outbound queue.
+// Returns ErrQueueFull if the queue has reached its capacity.
+func (p *Proxy) Send(message []byte) error {
+	select {
+	case p.MessageChannel <- message:
+		metrics.OutboundQueueDepth.WithLabelValues(p.Label).Set(float64(p.QueueDepth()))
+		return nil
+	default:
+		metrics.OutboundDropped.WithLabelValues(p.Label).Inc()
+		p.log.WithFields(logrus.Fields{"queue_size": p.Limits.QueueSize}).Warnf("Outbound queue is full, message dropped")
+		return errors.Trace(ErrQueueFull)
+	}
+}
+
+// Returns the number of messages waiting to be sent
+func (p *Proxy) QueueDepth() int {
+	return len(p.MessageChannel)
+}
+
+// Returns the current state of the proxy
+func (p *Proxy) Status() Status {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	status := Status{
+		Label:      p.Label,
+		Replaying:  p.Replaying,
+		QueueDepth: p.QueueDepth(),
+		Limits:     p.Limits,
+		Connected:  p.connected,
+		Reconnects: p.reconnects,
+	}
+
+	if !p.lastMessage.IsZero() {
+		lastMessage := p.lastMessage
+		status.LastMessage = &lastMessage
+	}
+
+	return status
+}
+
+// Updates the state of the connection
+func (p *Proxy) setConnected(connected bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.connected = connected
+}
+
+// Records the reception of a frame
+func (p *Proxy) received() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.lastMessage = time.Now()
+	metrics.FramesReceived.WithLabelValues(p.Label).Inc()
+}
+
+// Records a new connection to the websocket
+func (p *Proxy) reconnected() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.connected = true
+	p.reconnects++
+	metrics.Reconnects.WithLabelValues(p.Label).Inc()
+}
+
 // Listens the websocket and processes datas it receives
-// before to send it to the response channel
+// before to send it to the response channel.
+// If the host closes the connection, a new one is opened
+// and the subscriptions are sent again.
 func (p *Proxy) ListenWebsocket() {
+	defer close(p.listened)
 	p.log.Infof("Listening to %s", p.WssUrl.String())
 
-ListeningLoop:
 	for {
-		_, message, err := p.Conn.ReadMessage()
+		_, message, err := p.conn().ReadMessage()
 
 		if err != nil {
+			p.setConnected(false)
+
 			if !websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseNoStatusReceived) {
 				p.log.WithFields(logrus.Fields{"message": err}).Debugf("Websocket closed by client")
-				break ListeningLoop
+				return
 			}
 
-			c, _, err := websocket.DefaultDialer.Dial(p.WssUrl.String(), nil)
-
-			if err != nil {
-				p.log.Error("Cannot open a new connection with %s. Closing %s.", p.WssUrl.String(), p.Label)
-				break ListeningLoop
+			if err := p.reconnect(); err != nil {
+				p.log.WithFields(logrus.Fields{"error": err}).Errorf("Cannot open a new connection with %s. Closing %s.", p.WssUrl.String(), p.Label)
+				return
 			}
 
-			p.Conn = c
-			for _, messageToSend := range p.Subscriptions {
-				p.MessageChannel <- messageToSend
-			}
+			continue
+		}
 
-			p.log.Warnf("Restarting websocket client")
-			p.ListenWebsocket()
+		p.received()
+		p.record(Inbound, message)
 
+		select {
+		case p.ResponseChannel <- message:
+		case <-p.done:
+			return
 		}
+	}
+}
+
+// Opens a new connection to the websocket,
+// then sends the subscriptions again without blocking the reading of the frames
+func (p *Proxy) reconnect() error {
+	c, _, err := p.dialer.Dial(p.WssUrl.String(), nil)
+
+	if err != nil {
+		return err
+	}
+
+	p.mutex.Lock()
+
+	// The proxy has stopped while the connection was opened
+	select {
+	case <-p.done:
+		p.mutex.Unlock()
+		c.Close()
+		return errors.New("proxy stopped")
+	default:
+	}
+
+	p.Conn = c
+	p.mutex.Unlock()
+
+	p.reconnected()
+	p.log.Warnf("Restarting websocket client")
+
+	go p.resubscribe(append([][]byte{}, p.Subscriptions...))
 
-		p.ResponseChannel <- message
+	return nil
+}
+
+// Adds the subscriptions to the outbound queue.
+// The ones which do not fit in the queue are dropped and counted.
+func (p *Proxy) resubscribe(subscriptions [][]byte) {
+	dropped := 0
+
+	for _, message := range subscriptions {
+		if err := p.Send(message); err != nil {
+			dropped++
+		}
+	}
+
+	if dropped > 0 {
+		p.log.WithFields(logrus.Fields{"dropped": dropped, "subscriptions": len(subscriptions)}).Errorf("Some subscriptions cannot be sent again after the reconnection")
 	}
 }
 
+// Returns the current connection to the websocket
+func (p *Proxy) conn() *websocket.Conn {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.Conn
+}
+
 // Returns false if at least one of these condition is verified:
 // 	- The Proxy struct has not been initialized
 // 	- The URL has not been initialized
@@ -142,6 +412,10 @@ func (p *Proxy) IsClean() error {
 		return errors.NotAssignedf("%v proxy: structure doesn't have a good Websocket URL", p.Label)
 	}
 
+	if p.Replaying {
+		return nil
+	}
+
 	if p.Conn == nil || reflect.DeepEqual(p.Conn, &websocket.Conn{}) {
 		return errors.NotAssignedf("%v proxy: structure doesn't have any connection etablished with the websocket", p.Label)
 	}
@@ -149,6 +423,11 @@ func (p *Proxy) IsClean() error {
 	return nil
 }
 
+// Asks the proxy to stop, without blocking if it has already stopped
+// or if an interruption is already pending
 func (p *Proxy) Interrupt() {
-	p.InterruptChannel <- true
+	select {
+	case p.InterruptChannel <- true:
+	default:
+	}
 }
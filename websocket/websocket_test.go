@@ -0,0 +1,108 @@
+package websocket
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReconnect(t *testing.T) {
+	var connections int32
+	upgrader := websocket.Upgrader{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+
+		if err != nil {
+			return
+		}
+
+		defer conn.Close()
+
+		// The first connection is closed by the host
+		if atomic.AddInt32(&connections, 1) == 1 {
+			conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "restarting"))
+			return
+		}
+
+		conn.WriteMessage(websocket.TextMessage, []byte("after reconnection"))
+		conn.ReadMessage()
+	}))
+	defer server.Close()
+
+	endpoint, _ := url.Parse(server.URL)
+	endpoint.Scheme = "ws"
+
+	// The queue cannot contain every subscription
+	p := &Proxy{Label: "Reconnect", Limits: Limits{QueueSize: 1}}
+	assert.Nil(t, p.Initialize(*endpoint))
+	defer func() { p.conn().Close() }()
+
+	p.Subscriptions = [][]byte{[]byte("first"), []byte("second")}
+
+	go p.ListenWebsocket()
+
+	select {
+	case response := <-p.ResponseChannel:
+		assert.Equal(t, "after reconnection", string(response))
+	case <-time.After(2 * time.Second):
+		t.Fatal("the frames are not read after the reconnection")
+	}
+
+	assert.Eventually(t, func() bool { return p.QueueDepth() == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "first", string(<-p.MessageChannel))
+	assert.Equal(t, 1, p.Status().Reconnects)
+}
+
+func TestStop(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	release := make(chan struct{})
+
+	// The host never answers the Close frame
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+
+		if err != nil {
+			return
+		}
+
+		defer conn.Close()
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	endpoint, _ := url.Parse(server.URL)
+	endpoint.Scheme = "ws"
+
+	p := &Proxy{Label: "Stop"}
+	assert.Nil(t, p.Initialize(*endpoint))
+
+	stopped := make(chan struct{})
+	go func() {
+		p.Start()
+		close(stopped)
+	}()
+
+	p.Interrupt()
+
+	select {
+	case <-stopped:
+	case <-time.After(CloseTimeout + time.Second):
+		t.Fatal("the proxy has not stopped")
+	}
+
+	select {
+	case <-p.listened:
+	case <-time.After(time.Second):
+		t.Fatal("the websocket is still listened after the proxy has stopped")
+	}
+
+	assert.False(t, p.Status().Connected)
+}